@@ -0,0 +1,133 @@
+// Агент — отдельный процесс-вычислитель. Он подключается к оркестратору по gRPC,
+// забирает операции через GetTask и возвращает результаты через SubmitResult.
+// Чтобы добавить вычислительных мощностей, достаточно запустить ещё агентов.
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/philipslstwoyears/calculator-go/internal/agent"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// retryDelay — пауза перед повторным запросом, если оркестратор недоступен.
+const retryDelay = time.Second
+
+func main() {
+	// на отдельной машине переменные можно задать окружением, без cmd/.env
+	if err := godotenv.Load("cmd/.env"); err != nil {
+		log.Println("cmd/.env not loaded, using environment")
+	}
+	addr := os.Getenv("ORCHESTRATOR_ADDR")
+	if addr == "" {
+		addr = "localhost:8081"
+	}
+	computingPower := settings.Int("COMPUTING_POWER", calc.DefaultComputingPower, settings.Positive)
+	// без задержек TIME_*_MS агент не может выполнить ни одной операции, поэтому проверяем их сразу
+	if err := calc.LoadDelays(); err != nil {
+		log.Fatal(err)
+	}
+	token := os.Getenv("AGENT_TOKEN")
+	if token == "" {
+		log.Fatal("AGENT_TOKEN must be set to the orchestrator's AGENT_TOKEN")
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withToken(token)),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	client := proto.NewCalcServiceClient(conn)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hostname, _ := os.Hostname()
+	log.Printf("[AGENT] connecting to orchestrator, addr: %s, computing power: %d", addr, computingPower)
+	var running sync.WaitGroup
+	for i := 0; i < computingPower; i++ {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			run(ctx, client, fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i))
+		}()
+	}
+
+	<-ctx.Done()
+	stop()
+	grace := settings.ShutdownTimeout()
+	log.Printf("[AGENT] shutting down, grace period %s", grace)
+	finished := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		log.Println("[AGENT] stopped")
+	case <-time.After(grace):
+		// аренда невыполненных операций истечёт, и оркестратор отдаст их другим вычислителям
+		log.Println("[AGENT] grace period is over, unfinished tasks are left to other agents")
+	}
+}
+
+// withToken добавляет AGENT_TOKEN в метаданные каждого вызова оркестратора.
+func withToken(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, agent.AgentTokenKey, token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// run забирает задачи у оркестратора и выполняет их по одной, пока не отменён ctx.
+// Начатая операция при отмене выполняется до конца, и её результат отправляется оркестратору.
+func run(ctx context.Context, client proto.CalcServiceClient, agentID string) {
+	for ctx.Err() == nil {
+		task, err := client.GetTask(ctx, &proto.TaskRequest{AgentId: agentID})
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			log.Println(err)
+			time.Sleep(retryDelay)
+			continue
+		}
+		value, err := calc.ExecuteTask(context.Background(), calc.Task{
+			Operation: task.GetOperation(),
+			Arg1:      task.GetArg1(),
+			Arg2:      task.GetArg2(),
+			Mode:      task.GetMode(),
+		})
+		result := &proto.TaskResult{
+			Id:      task.GetId(),
+			Lease:   task.GetLease(),
+			Value:   value,
+			AgentId: agentID,
+		}
+		if err != nil {
+			result.ErrorCode = calc.ErrorCode(err)
+			result.ErrorMessage = err.Error()
+		}
+		if _, err := client.SubmitResult(context.Background(), result); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/philipslstwoyears/calculator-go/internal/agent"
 	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
-	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
 	"github.com/philipslstwoyears/calculator-go/internal/server"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
 	"github.com/philipslstwoyears/calculator-go/internal/storage"
+	"github.com/philipslstwoyears/calculator-go/internal/webhook"
 	"log"
 	_ "modernc.org/sqlite"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -19,15 +24,39 @@ func main() {
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
+	if err := calc.LoadConstants(os.Getenv("CONSTANTS")); err != nil {
+		log.Fatal(err)
+	}
+	if err := calc.LoadDelays(); err != nil {
+		log.Fatal(err)
+	}
+	autoscale, err := calc.AutoscaleFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 	db, err := ConnectToDB()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 	data := storage.New(db)
-	ch := make(chan dto.Expression)
-	workers := calc.New(data, ch)
-	agent := agent.New(data, ch)
+	data.PriorityAging = calc.PriorityAgingFromEnv()
+	queued := make(chan struct{}, 1)
+	events := calc.NewEvents(calc.DefaultEventHistory)
+	workers := calc.New(data, queued)
+	workers.SetAutoscale(autoscale)
+	workers.Events = events
+	agent := agent.New(data, queued)
+	agent.Tasks = workers.Tasks()
+	agent.Workers = workers
+	agent.Events = events
+	hooksConfig, err := webhook.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	hooks := webhook.New(data, hooksConfig)
+	hooks.Events = events
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	go func() {
 		if err := agent.RunServer(); err != nil {
 			log.Fatal(err)
@@ -41,16 +70,43 @@ func main() {
 	if err := workers.Start(); err != nil {
 		log.Fatal(err)
 	}
-	if err := serv.RunServer(); err != nil {
-		log.Fatal(err)
+	hooks.Start()
+	go func() {
+		if err := serv.RunServer(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	grace := settings.ShutdownTimeout()
+	log.Printf("shutting down, grace period %s", grace)
+	// Все этапы укладываются в один срок grace: этап, до которого срок уже истёк,
+	// сразу прерывает начатую работу.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	// Порядок важен: HTTP-запросы обращаются к агенту, а выражения дописывают результаты
+	// через удалённых агентов, поэтому gRPC останавливается только после вычислителей.
+	if err := serv.Shutdown(shutdownCtx); err != nil {
+		log.Println("http shutdown:", err)
 	}
+	workers.Shutdown(shutdownCtx)
+	hooks.Shutdown(shutdownCtx)
+	agent.Shutdown(shutdownCtx)
+	if err := db.Close(); err != nil {
+		log.Println("database close:", err)
+	}
+	log.Println("stopped")
 }
 
 func ConnectToDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite", "store.db")
+	// busy_timeout и одно соединение: воркеры пишут результаты параллельно с приёмом
+	// новых выражений, и без этого SQLite отвечает "database is locked"
+	db, err := sql.Open("sqlite", "store.db?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(1)
 
 	err = db.PingContext(context.Background())
 	if err != nil {
@@ -84,6 +140,93 @@ func CreateTables(ctx context.Context, db *sql.DB) error {
 		result FLOAT,
 		FOREIGN KEY (user_id) REFERENCES users(user_id)
 	);`
+
+		variablesTable = `
+	CREATE TABLE IF NOT EXISTS variables (
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		value FLOAT NOT NULL,
+		PRIMARY KEY (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
+		// jobs — очередь выражений, которые ещё не вычислены. Она переживает перезапуск:
+		// при старте прерванные выражения возвращаются в очередь.
+		jobsTable = `
+	CREATE TABLE IF NOT EXISTS jobs (
+		expression_id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		state TEXT NOT NULL,
+		lease_until INTEGER NOT NULL DEFAULT 0,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		priority INTEGER NOT NULL DEFAULT 1,
+		queued_at INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (expression_id) REFERENCES expressions(id)
+	);`
+
+		backfillQueuedAt = `
+		UPDATE jobs SET queued_at = COALESCE(
+			(SELECT NULLIF(created_at, 0) FROM expressions WHERE expressions.id = jobs.expression_id), ?
+		) WHERE queued_at = 0;`
+
+		createJobsStateIndex = `
+		CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs(state, expression_id);`
+
+		// webhooks — адреса, на которые отправляются вычисленные выражения пользователя
+		webhooksTable = `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		UNIQUE (user_id, url),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
+		// deliveries — очередь и журнал отправок вычисленных выражений на webhooks и callback_url.
+		// Доставки, которые ещё не удались, переживают перезапуск.
+		deliveriesTable = `
+	CREATE TABLE IF NOT EXISTS deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		expression_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
+		updated_at INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (expression_id) REFERENCES expressions(id)
+	);`
+
+		createDeliveriesDueIndex = `
+		CREATE INDEX IF NOT EXISTS idx_deliveries_due ON deliveries(status, next_attempt_at);`
+
+		createDeliveriesUserIndex = `
+		CREATE INDEX IF NOT EXISTS idx_deliveries_user ON deliveries(user_id, expression_id);`
+
+		// dependencies — ссылки выражений на результаты других выражений того же пользователя:
+		// name — ссылка так, как она записана в выражении (#42 или $prev), depends_on — номер выражения.
+		// Пока у зависимости есть задание в jobs, зависимое выражение не забирается из очереди.
+		dependenciesTable = `
+	CREATE TABLE IF NOT EXISTS dependencies (
+		expression_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		depends_on INTEGER NOT NULL,
+		PRIMARY KEY (expression_id, name),
+		FOREIGN KEY (expression_id) REFERENCES expressions(id),
+		FOREIGN KEY (depends_on) REFERENCES expressions(id)
+	);`
+
+		functionsTable = `
+	CREATE TABLE IF NOT EXISTS functions (
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		params TEXT NOT NULL,
+		body TEXT NOT NULL,
+		PRIMARY KEY (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
 	)
 
 	// Создаём таблицы
@@ -93,9 +236,122 @@ func CreateTables(ctx context.Context, db *sql.DB) error {
 	if _, err := db.ExecContext(ctx, createUniqueLoginIndex); err != nil {
 		return err
 	}
+	for _, column := range usersColumns {
+		if err := addColumn(ctx, db, "users", column); err != nil {
+			return err
+		}
+	}
 	if _, err := db.ExecContext(ctx, expressionsTable); err != nil {
 		return err
 	}
+	for _, column := range expressionsColumns {
+		if err := addColumn(ctx, db, "expressions", column); err != nil {
+			return err
+		}
+	}
+	if _, err := db.ExecContext(ctx, jobsTable); err != nil {
+		return err
+	}
+	for _, column := range jobsColumns {
+		if err := addColumn(ctx, db, "jobs", column); err != nil {
+			return err
+		}
+	}
+	// Задания, поставленные в очередь до появления queued_at, считаются ожидающими с создания
+	// выражения, а если и оно неизвестно — с запуска: с queued_at = 0 они бы сразу поднялись
+	// на высший уровень приоритета.
+	if _, err := db.ExecContext(ctx, backfillQueuedAt, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, createJobsStateIndex); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, dependenciesTable); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, variablesTable); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, functionsTable); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, webhooksTable); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, deliveriesTable); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, createDeliveriesDueIndex); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, createDeliveriesUserIndex); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// usersColumns — колонки, добавленные в users после первой версии схемы.
+var usersColumns = []string{
+	// webhook_secret — ключ HMAC для подписи доставок, создаётся при первом обращении
+	"webhook_secret TEXT NOT NULL DEFAULT ''",
+	// webhook_secret_shown — ключ уже показан пользователю; ключ может появиться раньше,
+	// при доставке на callback_url, и тогда его покажет первый AddWebhook
+	"webhook_secret_shown INTEGER NOT NULL DEFAULT 0",
+}
+
+// expressionsColumns — колонки, добавленные в expressions после первой версии схемы.
+var expressionsColumns = []string{
+	"mode TEXT NOT NULL DEFAULT ''",
+	"precision INTEGER NOT NULL DEFAULT 0",
+	"value TEXT NOT NULL DEFAULT ''",
+	// inexact — 1, если часть точного вычисления выполнена в float64
+	"inexact INTEGER NOT NULL DEFAULT 0",
+	"error_code TEXT NOT NULL DEFAULT ''",
+	"error_message TEXT NOT NULL DEFAULT ''",
+	// deadline — срок вычисления в миллисекундах Unix, 0 — без срока
+	"deadline INTEGER NOT NULL DEFAULT 0",
+	// priority — уровень приоритета, как в jobs.priority
+	"priority INTEGER NOT NULL DEFAULT 1",
+	"callback_url TEXT NOT NULL DEFAULT ''",
+	// created_at — время создания в миллисекундах Unix, 0 у выражений, созданных до появления колонки
+	"created_at INTEGER NOT NULL DEFAULT 0",
+}
+
+// jobsColumns — колонки, добавленные в jobs после первой версии схемы.
+var jobsColumns = []string{
+	// priority — уровень приоритета: 0 — interactive, 1 — normal, 2 — batch, см. dto.PriorityRank
+	"priority INTEGER NOT NULL DEFAULT 1",
+	// queued_at — время постановки в очередь в миллисекундах Unix, от него считается повышение приоритета
+	"queued_at INTEGER NOT NULL DEFAULT 0",
+}
+
+// addColumn добавляет колонку в существующую таблицу, если её там ещё нет.
+// column — определение колонки, которое начинается с её имени.
+func addColumn(ctx context.Context, db *sql.DB, table, column string) error {
+	exists, err := hasColumn(ctx, db, table, strings.Fields(column)[0])
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "ALTER TABLE "+table+" ADD COLUMN "+column)
+	return err
+}
+
+// hasColumn проверяет по PRAGMA table_info, есть ли в таблице колонка name.
+func hasColumn(ctx context.Context, db *sql.DB, table, name string) (bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(column, name) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -4,22 +4,30 @@ import (
 	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
 	"github.com/philipslstwoyears/calculator-go/internal/mocks"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/storage"
 	"github.com/philipslstwoyears/calculator-go/proto"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAgent_Calc(t *testing.T) {
 	tests := []struct {
-		name          string
-		input         *proto.Request
-		expected      *proto.Id
-		mockBehavior  func(r *mocks.MockStorage)
-		expectedError error
+		name           string
+		input          *proto.Request
+		expected       *proto.Id
+		mockBehavior   func(r *mocks.MockStorage)
+		expectedError  error
+		expectedSyntax *proto.SyntaxError
 	}{
 		{
 			name:     "Success",
@@ -30,21 +38,166 @@ func TestAgent_Calc(t *testing.T) {
 					UserID:     1,
 					Expression: "5+5",
 					Status:     "Выражение принято для вычисления",
-				}).Return(1, nil)
+					Priority:   "normal",
+				}, DefaultMaxQueueDepth).Return(1, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Rational",
+			input:    &proto.Request{UserId: 1, Expression: "1/3", Mode: "rational"},
+			expected: &proto.Id{Id: 2},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().AddExpression(dto.Expression{
+					UserID:     1,
+					Expression: "1/3",
+					Status:     "Выражение принято для вычисления",
+					Mode:       "rational",
+					Priority:   "normal",
+				}, DefaultMaxQueueDepth).Return(2, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "UnknownMode",
+			input:         &proto.Request{UserId: 1, Expression: "1/3", Mode: "complex"},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, "unknown mode: complex"),
+		},
+		{
+			name:          "SyntaxError",
+			input:         &proto.Request{UserId: 1, Expression: ")1("},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, `unexpected ")", expected number, name, "-" or "("`),
+			expectedSyntax: &proto.SyntaxError{
+				Code:     "unbalanced_parens",
+				Line:     1,
+				Column:   1,
+				Token:    ")",
+				Expected: `number, name, "-" or "("`,
+				Message:  `unexpected ")", expected number, name, "-" or "("`,
+			},
+		},
+		{
+			name:          "TooLong",
+			input:         &proto.Request{UserId: 1, Expression: strings.Repeat("1+", 5000) + "1"},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, "expression is too long: 10001 characters, limit is 10000"),
+		},
+		{
+			name:          "DeadlinePassed",
+			input:         &proto.Request{UserId: 1, Expression: "5+5", Deadline: time.Now().Add(-time.Second).UnixMilli()},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, "deadline has already passed"),
+		},
+		{
+			name:     "Deadline",
+			input:    &proto.Request{UserId: 1, Expression: "5+5", Deadline: 4102444800000},
+			expected: &proto.Id{Id: 3},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().AddExpression(dto.Expression{
+					UserID:     1,
+					Expression: "5+5",
+					Status:     "Выражение принято для вычисления",
+					Deadline:   4102444800000,
+					Priority:   "normal",
+				}, DefaultMaxQueueDepth).Return(3, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Batch",
+			input:    &proto.Request{UserId: 1, Expression: "5+5", Priority: "batch"},
+			expected: &proto.Id{Id: 4},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().AddExpression(dto.Expression{
+					UserID:     1,
+					Expression: "5+5",
+					Status:     "Выражение принято для вычисления",
+					Priority:   "batch",
+				}, DefaultMaxQueueDepth).Return(4, nil)
 			},
 			expectedError: nil,
 		},
+		{
+			name:          "UnknownPriority",
+			input:         &proto.Request{UserId: 1, Expression: "5+5", Priority: "urgent"},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, "unknown priority: urgent"),
+		},
 		{
 			name:     "Fail",
 			input:    &proto.Request{},
 			expected: nil,
 			mockBehavior: func(r *mocks.MockStorage) {
 				r.EXPECT().AddExpression(dto.Expression{
-					Status: "Выражение принято для вычисления",
-				}).Return(0, errors.New("error"))
+					Status:   "Выражение принято для вычисления",
+					Priority: "normal",
+				}, DefaultMaxQueueDepth).Return(0, errors.New("error"))
 			},
 			expectedError: status.Error(codes.Internal, "error"),
 		},
+		{
+			name:     "References",
+			input:    &proto.Request{UserId: 1, Expression: "#3 * 1.2 + $prev"},
+			expected: &proto.Id{Id: 6},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(3).Return(dto.Expression{ID: 3, UserID: 1}, true, nil)
+				r.EXPECT().LastExpressionID(1).Return(5, nil)
+				r.EXPECT().AddExpression(dto.Expression{
+					UserID:     1,
+					Expression: "#3 * 1.2 + $prev",
+					Status:     "Выражение принято для вычисления",
+					Priority:   "normal",
+					// номер $prev находит storage при сохранении
+					References: map[string]int{"#3": 3, "$prev": 0},
+				}, DefaultMaxQueueDepth).Return(6, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "ForeignReference",
+			input: &proto.Request{UserId: 1, Expression: "#3 + 1"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(3).Return(dto.Expression{ID: 3, UserID: 2}, true, nil)
+			},
+			expectedError: status.Error(codes.InvalidArgument, "#3: expression not found"),
+		},
+		{
+			name:  "MissingReference",
+			input: &proto.Request{UserId: 1, Expression: "#99 + 1"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(99).Return(dto.Expression{}, false, nil)
+			},
+			expectedError: status.Error(codes.InvalidArgument, "#99: expression not found"),
+		},
+		{
+			name:  "NoPrevious",
+			input: &proto.Request{UserId: 1, Expression: "$prev * 2"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().LastExpressionID(1).Return(0, nil)
+			},
+			expectedError: status.Error(codes.InvalidArgument, "$prev: there is no previous expression"),
+		},
+		{
+			name:          "UnknownReference",
+			input:         &proto.Request{UserId: 1, Expression: "$next * 2"},
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, `unknown reference "$next"`),
+			expectedSyntax: &proto.SyntaxError{
+				Code:     "unknown_token",
+				Line:     1,
+				Column:   1,
+				Token:    "$next",
+				Expected: "$prev",
+				Message:  `unknown reference "$next"`,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -53,21 +206,184 @@ func TestAgent_Calc(t *testing.T) {
 			defer c.Finish()
 			storage := mocks.NewMockStorage(c)
 			test.mockBehavior(storage)
-			ch := make(chan dto.Expression, 1)
+			ch := make(chan struct{}, 1)
 			agent := New(storage, ch)
 			id, err := agent.Calc(context.Background(), test.input)
 			assert.Equal(t, test.expected, id)
-			assert.Equal(t, test.expectedError, err)
+			assertStatus(t, test.expectedError, test.expectedSyntax, err)
 			if err == nil {
-				expression := <-ch
-				assert.Equal(t, test.input.Expression, expression.Expression)
-				assert.Equal(t, int(test.input.UserId), expression.UserID)
-				assert.Equal(t, int(test.expected.Id), expression.ID)
+				// выражение уже в очереди в базе, вычислители только получают уведомление
+				assert.Len(t, ch, 1)
 			}
 		})
 	}
 }
 
+func TestAgent_CalcQueueFull(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	r := mocks.NewMockStorage(c)
+	r.EXPECT().AddExpression(gomock.Any(), 2).Return(0, storage.ErrQueueFull)
+	agent := New(r, make(chan struct{}, 1))
+	agent.config.MaxQueueDepth = 2
+	agent.config.RetryAfter = 3 * time.Second
+
+	id, err := agent.Calc(context.Background(), &proto.Request{UserId: 1, Expression: "5+5"})
+	assert.Nil(t, id)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "queue is full: 2 expressions are waiting", status.Convert(err).Message())
+	retryAfter, ok := convert.RetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, retryAfter)
+}
+
+func TestAgent_CalcBatch(t *testing.T) {
+	accepted := func(expression string) dto.Expression {
+		return dto.Expression{UserID: 1, Expression: expression, Status: "Выражение принято для вычисления", Priority: "normal"}
+	}
+
+	t.Run("PartialErrors", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().AddExpressions([]dto.Expression{accepted("1+1"), accepted("2*3")}, DefaultMaxQueueDepth).Return([]int{7, 8}, nil)
+		ch := make(chan struct{}, 1)
+		agent := New(storage, ch)
+
+		result, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{
+			{Expression: "1+1"},
+			{Expression: ")1("},
+			{Expression: "2*3", UserId: 5}, // пользователь берётся из пакета
+			{Expression: "1/3", Mode: "complex"},
+		}})
+		assert.NoError(t, err)
+		assert.Len(t, result.Items, 4)
+		assert.Equal(t, &proto.BatchItem{Id: 7}, result.Items[0])
+		assert.Equal(t, `unexpected ")", expected number, name, "-" or "("`, result.Items[1].Error)
+		assert.Equal(t, "unbalanced_parens", result.Items[1].Syntax.GetCode())
+		assert.Equal(t, &proto.BatchItem{Id: 8}, result.Items[2])
+		assert.Equal(t, "InvalidArgument", result.Items[1].Code)
+		assert.Equal(t, &proto.BatchItem{Error: "unknown mode: complex", Code: "InvalidArgument"}, result.Items[3])
+		assert.Len(t, ch, 1)
+	})
+
+	t.Run("NothingValid", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		agent := New(storage, make(chan struct{}, 1))
+
+		result, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{
+			{Expression: "2+2", Priority: "urgent"},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, []*proto.BatchItem{{Error: "unknown priority: urgent", Code: "InvalidArgument"}}, result.Items)
+	})
+
+	t.Run("QueueOverflow", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().AddExpressions([]dto.Expression{accepted("1+1"), accepted("2+2")}, 2).Return([]int{3}, nil)
+		agent := New(storage, make(chan struct{}, 1))
+		agent.config.MaxQueueDepth = 2
+		agent.config.RetryAfter = 3 * time.Second
+
+		result, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{
+			{Expression: "1+1"},
+			{Expression: "2+2"},
+		}})
+		assert.NoError(t, err)
+		// не поместившееся выражение получает тот же код и срок повтора, что и отклонённый Calc
+		assert.Equal(t, []*proto.BatchItem{{Id: 3}, {Error: "queue is full: 2 expressions are waiting", Code: "ResourceExhausted", RetryAfterMs: 3000}}, result.Items)
+	})
+
+	t.Run("Prev", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		// у пользователя ещё нет выражений: $prev первого выражения пакета некуда указывать,
+		// а у следующих он указывает на предыдущее выражение пакета
+		storage.EXPECT().LastExpressionID(1).Return(0, nil)
+		next := accepted("$prev * 2")
+		next.References = map[string]int{"$prev": 0}
+		storage.EXPECT().AddExpressions([]dto.Expression{accepted("1+1"), next}, DefaultMaxQueueDepth).Return([]int{1, 2}, nil)
+		agent := New(storage, make(chan struct{}, 1))
+
+		result, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{
+			{Expression: "$prev + 1"},
+			{Expression: "1+1"},
+			{Expression: "$prev * 2"},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, []*proto.BatchItem{{Error: "$prev: there is no previous expression", Code: "InvalidArgument"}, {Id: 1}, {Id: 2}}, result.Items)
+	})
+
+	t.Run("QueueFull", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().AddExpressions([]dto.Expression{accepted("1+1")}, 2).Return(nil, nil)
+		agent := New(storage, make(chan struct{}, 1))
+		agent.config.MaxQueueDepth = 2
+
+		_, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{{Expression: "1+1"}}})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		agent := New(nil, nil)
+		_, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: make([]*proto.Request, MaxBatch+1)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("StorageError", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().AddExpressions(gomock.Any(), DefaultMaxQueueDepth).Return(nil, errors.New("error"))
+		agent := New(storage, make(chan struct{}, 1))
+
+		_, err := agent.CalcBatch(context.Background(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{{Expression: "1+1"}}})
+		assert.Equal(t, status.Error(codes.Internal, "error"), err)
+	})
+}
+
+func TestAgent_GetQueue(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	storage.EXPECT().QueueDepth().Return(7, nil)
+	agent := New(storage, nil)
+	agent.config.MaxQueueDepth = 100
+	agent.Tasks = calc.NewDispatcher(calc.DefaultLease)
+
+	queue, err := agent.GetQueue(context.Background(), &proto.Empty{})
+	assert.NoError(t, err)
+	assert.Equal(t, &proto.Queue{Depth: 7, Capacity: 100}, queue)
+}
+
+func TestAgent_ResizePool(t *testing.T) {
+	agent := New(nil, nil)
+	agent.config.AdminToken = "admin"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AdminTokenKey, "admin"))
+	_, err := agent.GetPool(ctx, &proto.Empty{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	agent.Workers = calc.New(nil, nil)
+	pool, err := agent.ResizePool(ctx, &proto.PoolSize{Size: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), pool.Size)
+
+	_, err = agent.ResizePool(ctx, &proto.PoolSize{Size: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	pool, err = agent.ResizePool(ctx, &proto.PoolSize{Size: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), pool.Size)
+	agent.Workers.Pool().Wait()
+}
+
 func TestAgent_GetExpressions(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -100,6 +416,23 @@ func TestAgent_GetExpressions(t *testing.T) {
 						UserID:     1,
 					},
 				}, nil)
+				r.EXPECT().QueuePositions(1).Return(map[int]int{}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "Queued",
+			input: &proto.Id{Id: 1},
+			expected: &proto.Expressions{
+				Expressions: []*proto.Expression{
+					{Id: 2, Expression: "2+2", Status: calc.StatusAccepted, UserId: 1, Position: 1},
+				},
+			},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpressions(1).Return([]dto.Expression{
+					{ID: 2, Expression: "2+2", Status: calc.StatusAccepted, UserID: 1},
+				}, nil)
+				r.EXPECT().QueuePositions(1).Return(map[int]int{2: 1}, nil)
 			},
 			expectedError: nil,
 		},
@@ -120,7 +453,7 @@ func TestAgent_GetExpressions(t *testing.T) {
 			defer c.Finish()
 			storage := mocks.NewMockStorage(c)
 			test.mockBehavior(storage)
-			ch := make(chan dto.Expression, 1)
+			ch := make(chan struct{}, 1)
 			agent := New(storage, ch)
 			result, err := agent.GetExpressions(context.Background(), test.input)
 			assert.Equal(t, test.expected, result)
@@ -154,7 +487,57 @@ func TestAgent_GetExpression(t *testing.T) {
 					Status:     "completed",
 					Result:     10.0,
 					UserID:     1,
-				}, true)
+				}, true, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "Lossless",
+			input: &proto.Id{Id: 2},
+			expected: &proto.Expression{
+				Id:         2,
+				Expression: "123456789+1/3",
+				Status:     "Ok",
+				Result:     123456789.33333333,
+				UserId:     1,
+				Mode:       "rational",
+				Value:      "370370368/3",
+			},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(2).Return(dto.Expression{
+					ID:         2,
+					Expression: "123456789+1/3",
+					Status:     "Ok",
+					Result:     123456789.33333333,
+					UserID:     1,
+					Mode:       "rational",
+					Value:      "370370368/3",
+				}, true, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "Failed",
+			input: &proto.Id{Id: 3},
+			expected: &proto.Expression{
+				Id:           3,
+				Expression:   "1/0",
+				Status:       "Ошибка: Division by zero",
+				Result:       -1,
+				UserId:       1,
+				ErrorCode:    "division_by_zero",
+				ErrorMessage: "Division by zero",
+			},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(3).Return(dto.Expression{
+					ID:           3,
+					Expression:   "1/0",
+					Status:       "Ошибка: Division by zero",
+					Result:       -1,
+					UserID:       1,
+					ErrorCode:    "division_by_zero",
+					ErrorMessage: "Division by zero",
+				}, true, nil)
 			},
 			expectedError: nil,
 		},
@@ -163,10 +546,35 @@ func TestAgent_GetExpression(t *testing.T) {
 			input:    &proto.Id{Id: 999},
 			expected: nil,
 			mockBehavior: func(r *mocks.MockStorage) {
-				r.EXPECT().GetExpression(999).Return(dto.Expression{}, false)
+				r.EXPECT().GetExpression(999).Return(dto.Expression{}, false, nil)
 			},
 			expectedError: status.Error(codes.NotFound, "expression not found"),
 		},
+		{
+			name:     "StorageError",
+			input:    &proto.Id{Id: 4},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(4).Return(dto.Expression{}, false, errors.New("database error"))
+			},
+			expectedError: status.Error(codes.Internal, "database error"),
+		},
+		{
+			name:  "Queued",
+			input: &proto.Id{Id: 5},
+			expected: &proto.Expression{
+				Id:         5,
+				Expression: "2+2",
+				Status:     calc.StatusAccepted,
+				UserId:     1,
+				Position:   3,
+			},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(5).Return(dto.Expression{ID: 5, Expression: "2+2", Status: calc.StatusAccepted, UserID: 1}, true, nil)
+				r.EXPECT().QueuePositions(1).Return(map[int]int{5: 3}, nil)
+			},
+			expectedError: nil,
+		},
 	}
 
 	for _, test := range tests {
@@ -175,7 +583,7 @@ func TestAgent_GetExpression(t *testing.T) {
 			defer c.Finish()
 			storage := mocks.NewMockStorage(c)
 			test.mockBehavior(storage)
-			ch := make(chan dto.Expression, 1)
+			ch := make(chan struct{}, 1)
 			agent := New(storage, ch)
 			result, err := agent.GetExpression(context.Background(), test.input)
 			assert.Equal(t, test.expected, result)
@@ -184,6 +592,354 @@ func TestAgent_GetExpression(t *testing.T) {
 	}
 }
 
+func TestAgent_CancelExpression(t *testing.T) {
+	accepted := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: "Выражение принято для вычисления"}
+	tests := []struct {
+		name          string
+		input         *proto.Expression
+		expected      *proto.Expression
+		mockBehavior  func(r *mocks.MockStorage)
+		expectedError error
+	}{
+		{
+			name:     "Queued",
+			input:    &proto.Expression{Id: 1, UserId: 1},
+			expected: &proto.Expression{Id: 1, UserId: 1, Expression: "2+2", Status: "Cancelled", Result: -1, ErrorCode: "cancelled", ErrorMessage: "context canceled"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				cancelled := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: "Cancelled", Result: -1, ErrorCode: "cancelled", ErrorMessage: "context canceled"}
+				r.EXPECT().GetExpression(1).Return(accepted, true, nil)
+				r.EXPECT().CancelJob(cancelled).Return(true, nil)
+				r.EXPECT().GetExpression(1).Return(cancelled, true, nil)
+			},
+		},
+		{
+			name:  "AlreadyFinished",
+			input: &proto.Expression{Id: 1, UserId: 1},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(1).Return(dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: "Ok", Result: 4}, true, nil)
+				r.EXPECT().CancelJob(gomock.Any()).Return(false, nil)
+			},
+			expectedError: status.Error(codes.FailedPrecondition, "expression is already finished"),
+		},
+		{
+			name:  "NotYours",
+			input: &proto.Expression{Id: 1, UserId: 2},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(1).Return(accepted, true, nil)
+			},
+			expectedError: status.Error(codes.PermissionDenied, "It is not your expression"),
+		},
+		{
+			name:  "NotFound",
+			input: &proto.Expression{Id: 5, UserId: 1},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetExpression(5).Return(dto.Expression{}, false, nil)
+			},
+			expectedError: status.Error(codes.NotFound, "expression not found"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			test.mockBehavior(storage)
+			agent := New(storage, nil)
+			agent.Workers = calc.New(storage, nil)
+			result, err := agent.CancelExpression(context.Background(), test.input)
+			assert.True(t, protobuf.Equal(test.expected, result), "expression: %v", result)
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestAgent_WatchExpression(t *testing.T) {
+	accepted := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: calc.StatusAccepted}
+	done := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: "Ok", Result: 4, Value: "4"}
+
+	t.Run("Transitions", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil)
+		stream := mocks.NewMockCalcService_WatchExpressionServer(c)
+		stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		var types []string
+		stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(event *proto.ExpressionEvent) error {
+			types = append(types, event.Type)
+			if len(types) == 1 {
+				// изменения других выражений в поток не попадают
+				agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: dto.Expression{ID: 2}})
+				agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: accepted})
+				agent.Events.Publish(calc.Event{Type: calc.EventProgress, Expression: accepted, Done: 1, Total: 2})
+				agent.Events.Publish(calc.Event{Type: calc.EventDone, Expression: done})
+			}
+			return nil
+		}).Times(4)
+
+		err := agent.WatchExpression(&proto.WatchExpressionRequest{Id: 1, UserId: 1}, stream)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{calc.EventQueued, calc.EventRunning, calc.EventProgress, calc.EventDone}, types)
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(done, true, nil)
+		stream := mocks.NewMockCalcService_WatchExpressionServer(c)
+		stream.EXPECT().Send(&proto.ExpressionEvent{Type: calc.EventDone, Expression: convert.ExpressionToProto(done)}).Return(nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		assert.NoError(t, agent.WatchExpression(&proto.WatchExpressionRequest{Id: 1, UserId: 1}, stream))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(5).Return(dto.Expression{}, false, nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		err := agent.WatchExpression(&proto.WatchExpressionRequest{Id: 5, UserId: 1}, mocks.NewMockCalcService_WatchExpressionServer(c))
+		assert.Equal(t, status.Error(codes.NotFound, "expression not found"), err)
+	})
+
+	t.Run("NotOwned", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		err := agent.WatchExpression(&proto.WatchExpressionRequest{Id: 1, UserId: 2}, mocks.NewMockCalcService_WatchExpressionServer(c))
+		assert.Equal(t, status.Error(codes.PermissionDenied, "It is not your expression"), err)
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil)
+		stream := mocks.NewMockCalcService_WatchExpressionServer(c)
+		stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+		stream.EXPECT().Send(gomock.Any()).Return(nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+		agent.Shutdown(context.Background())
+
+		err := agent.WatchExpression(&proto.WatchExpressionRequest{Id: 1, UserId: 1}, stream)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestAgent_WaitExpression(t *testing.T) {
+	accepted := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: calc.StatusAccepted}
+	done := dto.Expression{ID: 1, UserID: 1, Expression: "2+2", Status: "Ok", Result: 4, Value: "4"}
+
+	t.Run("Finished", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(done, true, nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		expression, err := agent.WaitExpression(context.Background(), &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 30000})
+		assert.NoError(t, err)
+		assert.Equal(t, convert.ExpressionToProto(done), expression)
+	})
+
+	t.Run("Done", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+		gomock.InOrder(
+			storage.EXPECT().GetExpression(1).DoAndReturn(func(int) (dto.Expression, bool, error) {
+				// события других выражений ожидание не прерывают
+				agent.Events.Publish(calc.Event{Type: calc.EventDone, Expression: dto.Expression{ID: 2}})
+				agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: accepted})
+				agent.Events.Publish(calc.Event{Type: calc.EventDone, Expression: done})
+				return accepted, true, nil
+			}),
+			storage.EXPECT().GetExpression(1).Return(done, true, nil),
+		)
+
+		expression, err := agent.WaitExpression(context.Background(), &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 30000})
+		assert.NoError(t, err)
+		assert.Equal(t, convert.ExpressionToProto(done), expression)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil).Times(2)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		expression, err := agent.WaitExpression(context.Background(), &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, convert.ExpressionToProto(accepted), expression)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+
+		_, err := agent.WaitExpression(context.Background(), &proto.WaitRequest{Id: 1, UserId: 2, WaitMs: 30000})
+		assert.Equal(t, status.Error(codes.PermissionDenied, "It is not your expression"), err)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+		storage := mocks.NewMockStorage(c)
+		storage.EXPECT().GetExpression(1).Return(accepted, true, nil)
+		agent := New(storage, nil)
+		agent.Events = calc.NewEvents(calc.DefaultEventHistory)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := agent.WaitExpression(ctx, &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 30000})
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+}
+
+func TestAgent_WatchExpressions(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	agent := New(nil, nil)
+	agent.Events = calc.NewEvents(2)
+	mine := dto.Expression{ID: 1, UserID: 1}
+	agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: dto.Expression{ID: 3, UserID: 1}})
+	agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: dto.Expression{ID: 2, UserID: 2}})
+	agent.Events.Publish(calc.Event{Type: calc.EventRunning, Expression: mine})
+	// в истории остались два последних события, первое вытеснено, поэтому клиент получает reset
+	replay, stop := agent.Events.Subscribe(func(calc.Event) bool { return true }, 0)
+	kept, running := <-replay, <-replay
+	stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := mocks.NewMockCalcService_WatchExpressionsServer(c)
+	stream.EXPECT().Context().Return(ctx).AnyTimes()
+	var received []*proto.ExpressionEvent
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(event *proto.ExpressionEvent) error {
+		received = append(received, event)
+		if event.Type == calc.EventRunning {
+			agent.Events.Publish(calc.Event{Type: calc.EventDone, Expression: dto.Expression{ID: 2, UserID: 2}})
+			agent.Events.Publish(calc.Event{Type: calc.EventDone, Expression: mine})
+		}
+		if event.Type == calc.EventDone {
+			cancel()
+		}
+		return nil
+	}).Times(3)
+
+	err := agent.WatchExpressions(&proto.WatchRequest{UserId: 1, After: kept.ID - 2}, stream)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	if assert.Len(t, received, 3) {
+		assert.Equal(t, calc.EventReset, received[0].Type)
+		assert.Equal(t, running.ID, received[1].Id)
+		assert.Equal(t, calc.EventDone, received[2].Type)
+		assert.Equal(t, int32(1), received[2].Expression.Id)
+	}
+}
+
+func TestAgent_AddWebhook(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	storage.EXPECT().AddWebhook(dto.Webhook{UserID: 1, URL: "https://example.com/hook"}).Return(3, nil)
+	storage.EXPECT().AddWebhook(dto.Webhook{UserID: 1, URL: "https://example.com/other"}).Return(4, nil)
+	gomock.InOrder(
+		storage.EXPECT().RevealWebhookSecret(1).Return("key", true, nil),
+		storage.EXPECT().RevealWebhookSecret(1).Return("key", false, nil),
+	)
+	agent := New(storage, nil)
+
+	// ключ показывается только тому webhook, который его создал
+	webhook, err := agent.AddWebhook(context.Background(), &proto.Webhook{UserId: 1, Url: "https://example.com/hook"})
+	assert.NoError(t, err)
+	assert.True(t, protobuf.Equal(&proto.Webhook{Id: 3, UserId: 1, Url: "https://example.com/hook", Secret: "key"}, webhook), "webhook: %v", webhook)
+	webhook, err = agent.AddWebhook(context.Background(), &proto.Webhook{UserId: 1, Url: "https://example.com/other"})
+	assert.NoError(t, err)
+	assert.True(t, protobuf.Equal(&proto.Webhook{Id: 4, UserId: 1, Url: "https://example.com/other"}, webhook), "webhook: %v", webhook)
+
+	_, err = agent.AddWebhook(context.Background(), &proto.Webhook{UserId: 1, Url: "example.com/hook"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = agent.Calc(context.Background(), &proto.Request{Expression: "2+2", UserId: 1, CallbackUrl: "file:///etc/passwd"})
+	assert.Equal(t, status.Error(codes.InvalidArgument, "callback_url: url must start with http:// or https://"), err)
+}
+
+func TestAgent_AddWebhookAfterCallback(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	// хранилище помнит отдельно, создан ли ключ и показан ли он
+	var secret string
+	shown := false
+	create := func(int) (string, error) {
+		if secret == "" {
+			secret = "key"
+		}
+		return secret, nil
+	}
+	storage.EXPECT().WebhookSecret(1).DoAndReturn(create)
+	storage.EXPECT().RevealWebhookSecret(1).DoAndReturn(func(userID int) (string, bool, error) {
+		key, _ := create(userID)
+		first := !shown
+		shown = true
+		return key, first, nil
+	}).Times(2)
+	storage.EXPECT().AddWebhook(gomock.Any()).Return(3, nil).Times(2)
+	agent := New(storage, nil)
+
+	// доставка на callback_url подписывается ключом и создаёт его, но никому не показывает
+	signing, err := storage.WebhookSecret(1)
+	assert.NoError(t, err)
+
+	// поэтому первый webhook всё равно получает этот ключ, а следующий — уже нет
+	webhook, err := agent.AddWebhook(context.Background(), &proto.Webhook{UserId: 1, Url: "https://example.com/hook"})
+	assert.NoError(t, err)
+	assert.Equal(t, signing, webhook.GetSecret())
+	webhook, err = agent.AddWebhook(context.Background(), &proto.Webhook{UserId: 1, Url: "https://example.com/hook"})
+	assert.NoError(t, err)
+	assert.Empty(t, webhook.GetSecret())
+}
+
+func TestAgent_GetWebhooks(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	// ключ подписи не читается и не возвращается
+	storage.EXPECT().GetWebhooks(1).Return([]dto.Webhook{{ID: 3, UserID: 1, URL: "https://example.com/hook"}}, nil)
+	storage.EXPECT().RotateWebhookSecret(1).Return("new", nil)
+	agent := New(storage, nil)
+
+	webhooks, err := agent.GetWebhooks(context.Background(), &proto.Id{Id: 1})
+	assert.NoError(t, err)
+	assert.True(t, protobuf.Equal(&proto.Webhooks{Webhooks: []*proto.Webhook{{Id: 3, UserId: 1, Url: "https://example.com/hook"}}}, webhooks), "webhooks: %v", webhooks)
+
+	secret, err := agent.RotateWebhookSecret(context.Background(), &proto.Id{Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", secret.GetSecret())
+}
+
 func TestAgent_Login(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -237,7 +993,7 @@ func TestAgent_Login(t *testing.T) {
 			defer c.Finish()
 			storage := mocks.NewMockStorage(c)
 			test.mockBehavior(storage)
-			ch := make(chan dto.Expression, 1)
+			ch := make(chan struct{}, 1)
 			agent := New(storage, ch)
 			result, err := agent.Login(context.Background(), test.input)
 			assert.Equal(t, test.expected, result)
@@ -303,7 +1059,7 @@ func TestAgent_Register(t *testing.T) {
 			defer c.Finish()
 			storage := mocks.NewMockStorage(c)
 			test.mockBehavior(storage)
-			ch := make(chan dto.Expression, 1)
+			ch := make(chan struct{}, 1)
 			agent := New(storage, ch)
 			result, err := agent.Register(context.Background(), test.input)
 			assert.Equal(t, test.expected, result)
@@ -311,3 +1067,324 @@ func TestAgent_Register(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_GetVariables(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *proto.Id
+		expected      *proto.Variables
+		mockBehavior  func(r *mocks.MockStorage)
+		expectedError error
+	}{
+		{
+			name:  "Success",
+			input: &proto.Id{Id: 1},
+			expected: &proto.Variables{
+				Variables: []*proto.Variable{{UserId: 1, Name: "x", Value: 12}},
+			},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return([]dto.Variable{{UserID: 1, Name: "x", Value: 12}}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Fail",
+			input:    &proto.Id{Id: 1},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return(nil, errors.New("database error"))
+			},
+			expectedError: status.Error(codes.Internal, "database error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			test.mockBehavior(storage)
+			agent := New(storage, make(chan struct{}, 1))
+			result, err := agent.GetVariables(context.Background(), test.input)
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestAgent_SetVariable(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *proto.Variable
+		expected      *proto.Variable
+		mockBehavior  func(r *mocks.MockStorage)
+		expectedError error
+	}{
+		{
+			name:     "Success",
+			input:    &proto.Variable{UserId: 1, Name: "x", Value: 12},
+			expected: &proto.Variable{UserId: 1, Name: "x", Value: 12},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().SetVariable(dto.Variable{UserID: 1, Name: "x", Value: 12}).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "InvalidName",
+			input:         &proto.Variable{UserId: 1, Name: "pi", Value: 3},
+			expected:      nil,
+			mockBehavior:  func(r *mocks.MockStorage) {},
+			expectedError: status.Error(codes.InvalidArgument, "cannot assign to constant pi"),
+		},
+		{
+			name:     "StorageError",
+			input:    &proto.Variable{UserId: 1, Name: "x", Value: 12},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().SetVariable(dto.Variable{UserID: 1, Name: "x", Value: 12}).Return(errors.New("database error"))
+			},
+			expectedError: status.Error(codes.Internal, "database error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			test.mockBehavior(storage)
+			agent := New(storage, make(chan struct{}, 1))
+			result, err := agent.SetVariable(context.Background(), test.input)
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestAgent_DeleteVariable(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *proto.Variable
+		expected      *proto.Empty
+		mockBehavior  func(r *mocks.MockStorage)
+		expectedError error
+	}{
+		{
+			name:     "Success",
+			input:    &proto.Variable{UserId: 1, Name: "x"},
+			expected: &proto.Empty{},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().DeleteVariable(1, "x").Return(true, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "NotFound",
+			input:    &proto.Variable{UserId: 1, Name: "x"},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().DeleteVariable(1, "x").Return(false, nil)
+			},
+			expectedError: status.Error(codes.NotFound, "variable not found"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			test.mockBehavior(storage)
+			agent := New(storage, make(chan struct{}, 1))
+			result, err := agent.DeleteVariable(context.Background(), test.input)
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestAgent_SetFunction(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          *proto.Function
+		expected       *proto.Function
+		mockBehavior   func(r *mocks.MockStorage)
+		expectedError  error
+		expectedSyntax *proto.SyntaxError
+	}{
+		{
+			name:     "Success",
+			input:    &proto.Function{UserId: 1, Name: "vat", Params: []string{"x"}, Body: "x*rate"},
+			expected: &proto.Function{UserId: 1, Name: "vat", Params: []string{"x"}, Body: "x*rate"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return([]dto.Variable{{UserID: 1, Name: "rate", Value: 1.2}}, nil)
+				r.EXPECT().GetFunctions(1).Return(nil, nil)
+				r.EXPECT().SetFunction(dto.Function{UserID: 1, Name: "vat", Params: []string{"x"}, Body: "x*rate"}).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "UnknownIdentifier",
+			input:    &proto.Function{UserId: 1, Name: "vat", Params: []string{"x"}, Body: "x*rate"},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return(nil, nil)
+				r.EXPECT().GetFunctions(1).Return(nil, nil)
+			},
+			expectedError:  status.Error(codes.InvalidArgument, "unknown identifier: rate"),
+			expectedSyntax: &proto.SyntaxError{Code: "unknown_token", Line: 1, Column: 3, Token: "rate", Expected: "variable or constant", Message: "unknown identifier: rate"},
+		},
+		{
+			name:     "SyntaxError",
+			input:    &proto.Function{UserId: 1, Name: "vat", Params: []string{"x"}, Body: "x*(1.2"},
+			expected: nil,
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return(nil, nil)
+				r.EXPECT().GetFunctions(1).Return(nil, nil)
+			},
+			expectedError:  status.Error(codes.InvalidArgument, `unexpected end of expression, expected operator or ")"`),
+			expectedSyntax: &proto.SyntaxError{Code: "unbalanced_parens", Line: 1, Column: 7, Expected: `operator or ")"`, Message: `unexpected end of expression, expected operator or ")"`},
+		},
+		{
+			name:     "UsesOtherFunction",
+			input:    &proto.Function{UserId: 1, Name: "total", Params: []string{"x"}, Body: "vat(x)+1"},
+			expected: &proto.Function{UserId: 1, Name: "total", Params: []string{"x"}, Body: "vat(x)+1"},
+			mockBehavior: func(r *mocks.MockStorage) {
+				r.EXPECT().GetVariables(1).Return(nil, nil)
+				r.EXPECT().GetFunctions(1).Return([]dto.Function{{UserID: 1, Name: "vat", Params: []string{"x"}, Body: "x*1.2"}}, nil)
+				r.EXPECT().SetFunction(dto.Function{UserID: 1, Name: "total", Params: []string{"x"}, Body: "vat(x)+1"}).Return(nil)
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			test.mockBehavior(storage)
+			agent := New(storage, make(chan struct{}, 1))
+			result, err := agent.SetFunction(context.Background(), test.input)
+			assert.Equal(t, test.expected, result)
+			assertStatus(t, test.expectedError, test.expectedSyntax, err)
+		})
+	}
+}
+
+func TestAgent_GetFunction(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	storage.EXPECT().GetFunction(1, "vat").Return(dto.Function{UserID: 1, Name: "vat", Params: []string{"x"}, Body: "x*1.2"}, true)
+	storage.EXPECT().GetFunction(1, "nope").Return(dto.Function{}, false)
+	agent := New(storage, make(chan struct{}, 1))
+
+	result, err := agent.GetFunction(context.Background(), &proto.Function{UserId: 1, Name: "vat"})
+	assert.NoError(t, err)
+	assert.Equal(t, &proto.Function{UserId: 1, Name: "vat", Params: []string{"x"}, Body: "x*1.2"}, result)
+
+	result, err = agent.GetFunction(context.Background(), &proto.Function{UserId: 1, Name: "nope"})
+	assert.Nil(t, result)
+	assert.Equal(t, status.Error(codes.NotFound, "function not found"), err)
+}
+
+func TestAgent_RemoteTask(t *testing.T) {
+	t.Setenv("COMPUTING_POWER", "0")
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	updated := make(chan dto.Expression, 1)
+	storage.EXPECT().RecoverJobs(gomock.Any()).Return(0, nil, nil)
+	storage.EXPECT().ClaimJob(gomock.Any(), gomock.Any()).Return(dto.Expression{ID: 1, UserID: 1, Expression: "2*3"}, true, nil)
+	storage.EXPECT().ClaimJob(gomock.Any(), gomock.Any()).Return(dto.Expression{}, false, nil).AnyTimes()
+	storage.EXPECT().RenewJob(1, gomock.Any()).Return(nil).AnyTimes()
+	storage.EXPECT().GetVariables(1).Return(nil, nil)
+	storage.EXPECT().GetFunctions(1).Return(nil, nil)
+	storage.EXPECT().FinishJob(gomock.Any()).DoAndReturn(func(expression dto.Expression) error {
+		updated <- expression
+		return nil
+	})
+	queued := make(chan struct{}, 1)
+	workers := calc.New(storage, queued)
+	assert.NoError(t, workers.Start())
+	t.Setenv("AGENT_TOKEN", "secret")
+	agent := New(storage, queued)
+	agent.Tasks = workers.Tasks()
+
+	_, err := agent.GetTask(context.Background(), &proto.TaskRequest{AgentId: "test"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = agent.GetTask(agentContext("wrong"), &proto.TaskRequest{AgentId: "test"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = agent.SubmitResult(context.Background(), &proto.TaskResult{Id: 1, Lease: 1, Value: "6"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := agentContext("secret")
+	task, err := agent.GetTask(ctx, &proto.TaskRequest{AgentId: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "*", task.GetOperation())
+	assert.Equal(t, "2", task.GetArg1())
+	assert.Equal(t, "3", task.GetArg2())
+
+	_, err = agent.SubmitResult(ctx, &proto.TaskResult{Id: task.GetId(), Lease: task.GetLease() + 1, Value: "7"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = agent.SubmitResult(ctx, &proto.TaskResult{Id: task.GetId(), Lease: task.GetLease(), Value: "6"})
+	assert.NoError(t, err)
+
+	expression := <-updated
+	assert.Equal(t, "Ok", expression.Status)
+	assert.Equal(t, 6.0, expression.Result)
+}
+
+func TestAgent_GetTaskUnavailable(t *testing.T) {
+	t.Setenv("AGENT_TOKEN", "secret")
+	agent := New(nil, nil)
+	_, err := agent.GetTask(agentContext("secret"), &proto.TaskRequest{AgentId: "test"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestAgent_GetTaskWithoutToken(t *testing.T) {
+	t.Setenv("AGENT_TOKEN", "")
+	agent := New(nil, nil)
+	_, err := agent.GetTask(agentContext(""), &proto.TaskRequest{AgentId: "test"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// agentContext возвращает контекст входящего вызова удалённого агента с токеном token.
+func TestAgent_PoolRequiresAdminToken(t *testing.T) {
+	agent := New(nil, nil)
+	agent.Workers = calc.New(nil, nil)
+
+	// без ADMIN_TOKEN на сервере вызовы администратора выключены
+	agent.config.AdminToken = ""
+	_, err := agent.GetPool(context.Background(), &proto.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	agent.config.AdminToken = "admin"
+	_, err = agent.GetPool(context.Background(), &proto.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	// токен агента не открывает вызовы администратора
+	agent.config.AgentToken = "admin"
+	_, err = agent.ResizePool(agentContext("admin"), &proto.PoolSize{Size: 3})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, 0, agent.Workers.Pool().Size())
+}
+
+func agentContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(AgentTokenKey, token))
+}
+
+// assertStatus сравнивает код и текст статуса, а также позицию синтаксической ошибки в его деталях.
+func assertStatus(t *testing.T, expected error, expectedSyntax *proto.SyntaxError, err error) {
+	t.Helper()
+	assert.Equal(t, status.Code(expected), status.Code(err))
+	assert.Equal(t, status.Convert(expected).Message(), status.Convert(err).Message())
+	details := status.Convert(err).Details()
+	if expectedSyntax == nil {
+		assert.Empty(t, details)
+		return
+	}
+	if assert.Len(t, details, 1) {
+		assert.True(t, protobuf.Equal(expectedSyntax, details[0].(*proto.SyntaxError)), "syntax error details: %v", details[0])
+	}
+}
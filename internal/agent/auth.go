@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AgentTokenKey — ключ метаданных gRPC, в котором удалённый агент передаёт AGENT_TOKEN.
+const AgentTokenKey = "x-agent-token"
+
+// AdminTokenKey — ключ метаданных gRPC, в котором HTTP-сервер передаёт ADMIN_TOKEN
+// для вызовов администратора.
+const AdminTokenKey = "x-admin-token"
+
+// checkToken сверяет токен из метаданных вызова с ожидаемым за постоянное время.
+// Пустой ожидаемый токен означает, что вызовы с этим токеном отключены.
+func checkToken(ctx context.Context, key, expected, variable string) error {
+	if expected == "" {
+		return status.Errorf(codes.PermissionDenied, "%s is not set on the server", variable)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(key)
+	if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(expected)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+// checkAgent пропускает только удалённых агентов, знающих AGENT_TOKEN.
+func (a *Application) checkAgent(ctx context.Context) error {
+	return checkToken(ctx, AgentTokenKey, a.config.AgentToken, "AGENT_TOKEN")
+}
+
+// checkAdmin пропускает только вызовы с ADMIN_TOKEN: GetPool и ResizePool.
+func (a *Application) checkAdmin(ctx context.Context) error {
+	return checkToken(ctx, AdminTokenKey, a.config.AdminToken, "ADMIN_TOKEN")
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"errors"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time"
+)
+
+// queueFull возвращает статус ResourceExhausted с подсказкой, когда повторить запрос.
+func queueFull(depth int, retryAfter time.Duration) error {
+	st := status.Newf(codes.ResourceExhausted, "queue is full: %d expressions are waiting", depth)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// invalidArgument возвращает статус InvalidArgument; для синтаксической ошибки
+// в детали статуса добавляется её позиция.
+func invalidArgument(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	var syntaxErr *calc.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return st.Err()
+	}
+	detailed, detailsErr := st.WithDetails(&proto.SyntaxError{
+		Code:     calc.ErrorCode(err),
+		Line:     int32(syntaxErr.Line),
+		Column:   int32(syntaxErr.Column),
+		Token:    syntaxErr.Token,
+		Expected: syntaxErr.Expected,
+		Message:  syntaxErr.Message,
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// batchError переводит ошибку одного запроса пакета в элемент ответа CalcBatch.
+func batchError(err error) *proto.BatchItem {
+	st := status.Convert(err)
+	item := &proto.BatchItem{Error: st.Message(), Code: st.Code().String()}
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *proto.SyntaxError:
+			item.Syntax = detail
+		case *errdetails.RetryInfo:
+			item.RetryAfterMs = detail.GetRetryDelay().AsDuration().Milliseconds()
+		}
+	}
+	return item
+}
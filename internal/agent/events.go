@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"time"
+)
+
+// WatchExpression отправляет текущее состояние выражения, затем каждое его изменение,
+// и закрывает поток после события, которым выражение закончилось. Чужое выражение — PermissionDenied.
+func (a *Application) WatchExpression(in *proto.WatchExpressionRequest, stream proto.CalcService_WatchExpressionServer) error {
+	if a.Events == nil {
+		return status.Error(codes.Unavailable, "expression events are not available")
+	}
+	id := int(in.GetId())
+	// подписка раньше чтения состояния: изменение между ними придёт событием, а не потеряется
+	events, unsubscribe := a.Events.Subscribe(func(event calc.Event) bool {
+		return event.Expression.ID == id
+	}, math.MaxInt64)
+	defer unsubscribe()
+	expression, err := a.expression(id)
+	if err != nil {
+		return err
+	}
+	if expression.UserID != int(in.GetUserId()) {
+		return status.Error(codes.PermissionDenied, "It is not your expression")
+	}
+	snapshot := calc.Event{Type: calc.EventType(expression), Expression: expression}
+	if snapshot.Type == calc.EventQueued && a.Workers != nil && a.Workers.Running(id) {
+		snapshot.Type = calc.EventRunning
+	}
+	if err := stream.Send(eventToProto(snapshot)); err != nil {
+		return err
+	}
+	if snapshot.Final() {
+		return nil
+	}
+	return a.forward(events, stream, calc.Event.Final)
+}
+
+// MaxWait ограничивает, сколько WaitExpression ждёт завершения выражения; по нему же
+// HTTP-сервер проверяет параметр wait в GET /api/v1/expressions/{id}.
+const MaxWait = time.Minute
+
+// WaitExpression ждёт, пока выражение завершится, но не дольше waitMs, и возвращает его
+// текущее состояние. Завершение приходит событием от вычислителей, поэтому база не опрашивается.
+func (a *Application) WaitExpression(ctx context.Context, in *proto.WaitRequest) (*proto.Expression, error) {
+	if a.Events == nil {
+		return nil, status.Error(codes.Unavailable, "expression events are not available")
+	}
+	id := int(in.GetId())
+	events, unsubscribe := a.Events.Subscribe(func(event calc.Event) bool {
+		return event.Expression.ID == id
+	}, math.MaxInt64)
+	defer unsubscribe()
+	expression, err := a.expression(id)
+	if err != nil {
+		return nil, err
+	}
+	if expression.UserID != int(in.GetUserId()) {
+		return nil, status.Error(codes.PermissionDenied, "It is not your expression")
+	}
+	if calc.EventType(expression) != calc.EventQueued {
+		return convert.ExpressionToProto(expression), nil
+	}
+
+	timer := time.NewTimer(min(time.Duration(in.GetWaitMs())*time.Millisecond, MaxWait))
+	defer timer.Stop()
+wait:
+	for {
+		select {
+		case event, ok := <-events:
+			// отставшая подписка закрывается; тогда состояние просто перечитывается
+			if !ok || event.Final() {
+				break wait
+			}
+		case <-timer.C:
+			break wait
+		case <-a.closing:
+			break wait
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	expression, err = a.expression(id)
+	if err != nil {
+		return nil, err
+	}
+	return convert.ExpressionToProto(expression), nil
+}
+
+// WatchExpressions отправляет изменения всех выражений пользователя. Если after не ноль,
+// сначала отправляются события после after, а если часть из них уже не сохранилась —
+// событие reset, после которого клиенту стоит перечитать свои выражения.
+func (a *Application) WatchExpressions(in *proto.WatchRequest, stream proto.CalcService_WatchExpressionsServer) error {
+	if a.Events == nil {
+		return status.Error(codes.Unavailable, "expression events are not available")
+	}
+	userID := int(in.GetUserId())
+	after := in.GetAfter()
+	if after == 0 {
+		after = math.MaxInt64
+	}
+	events, unsubscribe := a.Events.Subscribe(func(event calc.Event) bool {
+		return event.Expression.UserID == userID
+	}, after)
+	defer unsubscribe()
+	if after != math.MaxInt64 && !a.Events.Covers(after) {
+		if err := stream.Send(&proto.ExpressionEvent{Type: calc.EventReset}); err != nil {
+			return err
+		}
+	}
+	return a.forward(events, stream, func(calc.Event) bool { return false })
+}
+
+// eventStream — поток, в который отправляются события выражений.
+type eventStream interface {
+	Send(*proto.ExpressionEvent) error
+	Context() context.Context
+}
+
+// forward отправляет события в поток, пока last не вернёт true, клиент не отключится
+// или сервер не начнёт останавливаться.
+func (a *Application) forward(events <-chan calc.Event, stream eventStream, last func(calc.Event) bool) error {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Aborted, "watcher fell behind, watch again")
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+			if last(event) {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-a.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+func eventToProto(event calc.Event) *proto.ExpressionEvent {
+	return &proto.ExpressionEvent{
+		Id:         event.ID,
+		Type:       event.Type,
+		Expression: convert.ExpressionToProto(event.Expression),
+		Done:       int32(event.Done),
+		Total:      int32(event.Total),
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (a *Application) GetFunctions(ctx context.Context, id *proto.Id) (*proto.Functions, error) {
+	functions, err := a.Storage.GetFunctions(int(id.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := make([]*proto.Function, len(functions))
+	for i, f := range functions {
+		result[i] = &proto.Function{
+			UserId: int32(f.UserID),
+			Name:   f.Name,
+			Params: f.Params,
+			Body:   f.Body,
+		}
+	}
+	return &proto.Functions{
+		Functions: result,
+	}, nil
+}
+
+func (a *Application) GetFunction(ctx context.Context, in *proto.Function) (*proto.Function, error) {
+	f, ok := a.Storage.GetFunction(int(in.GetUserId()), in.GetName())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "function not found")
+	}
+	return &proto.Function{
+		UserId: int32(f.UserID),
+		Name:   f.Name,
+		Params: f.Params,
+		Body:   f.Body,
+	}, nil
+}
+
+func (a *Application) SetFunction(ctx context.Context, in *proto.Function) (*proto.Function, error) {
+	variables, err := a.Storage.GetVariables(int(in.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	functions, err := a.Storage.GetFunctions(int(in.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	f := calc.UserFunction{Params: in.GetParams(), Body: in.GetBody()}
+	if err := calc.ValidateFunction(in.GetName(), f, calc.NewEnv(variables, functions)); err != nil {
+		return nil, invalidArgument(err)
+	}
+	err = a.Storage.SetFunction(dto.Function{
+		UserID: int(in.GetUserId()),
+		Name:   in.GetName(),
+		Params: in.GetParams(),
+		Body:   in.GetBody(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return in, nil
+}
+
+func (a *Application) DeleteFunction(ctx context.Context, in *proto.Function) (*proto.Empty, error) {
+	ok, err := a.Storage.DeleteFunction(int(in.GetUserId()), in.GetName())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "function not found")
+	}
+	return &proto.Empty{}, nil
+}
@@ -2,43 +2,206 @@ package agent
 
 import (
 	"context"
+	"errors"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/storage"
+	"github.com/philipslstwoyears/calculator-go/internal/webhook"
 	"github.com/philipslstwoyears/calculator-go/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"time"
 )
 
 func (a *Application) Calc(ctx context.Context, r *proto.Request) (*proto.Id, error) {
-	expression := dto.Expression{
-		Expression: r.Expression,
-		UserID:     int(r.UserId),
-		Status:     "Выражение принято для вычисления",
+	expression, err := a.newExpression(r, r.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := expression.References[calc.PrevReference]; ok {
+		if err := a.checkPrevious(expression.UserID); err != nil {
+			return nil, err
+		}
+	}
+	// queued публикуется раньше, чем вычислители смогут забрать выражение и опубликовать running
+	release := a.Workers.HoldClaims()
+	defer release()
+	id, err := a.Storage.AddExpression(expression, a.config.MaxQueueDepth)
+	if errors.Is(err, storage.ErrQueueFull) {
+		return nil, queueFull(a.config.MaxQueueDepth, a.config.RetryAfter)
 	}
-	id, err := a.Storage.AddExpression(expression)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	expression.ID = id
-	a.input <- expression
+	a.enqueued(expression)
 	return &proto.Id{
 		Id: int32(id),
 	}, nil
 }
 
+// MaxBatch — сколько выражений можно передать в одном CalcBatch.
+const MaxBatch = 10000
+
+// CalcBatch принимает пакет выражений. Выражения, прошедшие проверку, сохраняются одной транзакцией;
+// для остальных в ответе на их месте ошибка. Если в очереди не хватает места на все выражения,
+// не поместившиеся тоже получают ошибку, а пакет целиком отклоняется, только когда не поместилось ни одно.
+func (a *Application) CalcBatch(ctx context.Context, in *proto.BatchRequest) (*proto.BatchResult, error) {
+	if len(in.GetRequests()) > MaxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "batch has %d expressions, at most %d are allowed", len(in.GetRequests()), MaxBatch)
+	}
+	items := make([]*proto.BatchItem, len(in.GetRequests()))
+	var expressions []dto.Expression
+	var positions []int
+	for i, r := range in.GetRequests() {
+		expression, err := a.newExpression(r, in.GetUserId())
+		if _, ok := expression.References[calc.PrevReference]; ok && len(expressions) == 0 {
+			// $prev первого принятого выражения пакета указывает на последнее сохранённое выражение,
+			// а следующих — на предыдущее выражение пакета
+			err = a.checkPrevious(expression.UserID)
+		}
+		if err != nil {
+			items[i] = batchError(err)
+			continue
+		}
+		expressions = append(expressions, expression)
+		positions = append(positions, i)
+	}
+	if len(expressions) == 0 {
+		return &proto.BatchResult{Items: items}, nil
+	}
+
+	release := a.Workers.HoldClaims()
+	defer release()
+	ids, err := a.Storage.AddExpressions(expressions, a.config.MaxQueueDepth)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(ids) == 0 {
+		return nil, queueFull(a.config.MaxQueueDepth, a.config.RetryAfter)
+	}
+	for j, id := range ids {
+		expressions[j].ID = id
+		items[positions[j]] = &proto.BatchItem{Id: int32(id)}
+	}
+	for _, position := range positions[len(ids):] {
+		items[position] = batchError(queueFull(a.config.MaxQueueDepth, a.config.RetryAfter))
+	}
+	a.enqueued(expressions[:len(ids)]...)
+	return &proto.BatchResult{Items: items}, nil
+}
+
+// newExpression проверяет запрос пользователя userID и собирает из него выражение для очереди.
+func (a *Application) newExpression(r *proto.Request, userID int32) (dto.Expression, error) {
+	if err := calc.ValidateMode(r.Mode, int(r.Precision)); err != nil {
+		return dto.Expression{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := calc.ValidatePriority(r.Priority); err != nil {
+		return dto.Expression{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	priority := r.Priority
+	if priority == "" {
+		priority = dto.PriorityNormal
+	}
+	// синтаксис и размер проверяются сразу, чтобы клиент получил ошибку в ответе;
+	// пустое выражение и ошибки вычисления по-прежнему видны в статусе выражения
+	var syntaxErr *calc.SyntaxError
+	if err := a.config.Limits.Check(r.Expression); errors.As(err, &syntaxErr) || errors.Is(err, calc.ErrBudget) {
+		return dto.Expression{}, invalidArgument(err)
+	}
+	if r.Deadline != 0 && time.UnixMilli(r.Deadline).Before(time.Now()) {
+		return dto.Expression{}, status.Error(codes.InvalidArgument, "deadline has already passed")
+	}
+	if r.CallbackUrl != "" {
+		if err := webhook.ValidateURL(r.CallbackUrl); err != nil {
+			return dto.Expression{}, status.Error(codes.InvalidArgument, "callback_url: "+err.Error())
+		}
+	}
+	references, err := a.references(r.Expression, int(userID))
+	if err != nil {
+		return dto.Expression{}, err
+	}
+	return dto.Expression{
+		Expression:  r.Expression,
+		UserID:      int(userID),
+		Status:      calc.StatusAccepted,
+		Mode:        r.Mode,
+		Precision:   int(r.Precision),
+		Deadline:    r.Deadline,
+		Priority:    priority,
+		CallbackURL: r.CallbackUrl,
+		References:  references,
+	}, nil
+}
+
+// checkPrevious проверяет, что у пользователя есть выражение, на которое укажет $prev.
+func (a *Application) checkPrevious(userID int) error {
+	previous, err := a.Storage.LastExpressionID(userID)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if previous == 0 {
+		return status.Error(codes.InvalidArgument, calc.PrevReference+": there is no previous expression")
+	}
+	return nil
+}
+
+// references находит выражения, на которые ссылается expression пользователя userID.
+// Ссылаться можно только на уже принятые выражения этого пользователя; чужое выражение
+// неотличимо от несуществующего. Новое выражение получает номер больше всех существующих,
+// поэтому ни на себя, ни на более поздние выражения оно сослаться не может и цикл зависимостей
+// не образуется. $prev получает номер 0: предыдущее выражение находит storage при сохранении.
+func (a *Application) references(expression string, userID int) (map[string]int, error) {
+	names, err := calc.References(expression)
+	if err != nil || len(names) == 0 {
+		// синтаксические ошибки уже проверены; пустое выражение получит ошибку при вычислении
+		return nil, nil
+	}
+	references := make(map[string]int, len(names))
+	for _, name := range names {
+		id, ok := calc.ReferenceID(name)
+		if !ok {
+			references[name] = 0
+			continue
+		}
+		dependency, ok, err := a.Storage.GetExpression(id)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if !ok || dependency.UserID != userID {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: expression not found", name)
+		}
+		references[name] = id
+	}
+	return references, nil
+}
+
+// enqueued сообщает о новых выражениях подписчикам и будит вычислители.
+func (a *Application) enqueued(expressions ...dto.Expression) {
+	for _, expression := range expressions {
+		a.Events.Publish(calc.Event{Type: calc.EventQueued, Expression: expression})
+	}
+	select {
+	case a.queued <- struct{}{}:
+	default:
+		// вычислители уже разбужены и заберут это выражение вместе с остальными
+	}
+}
+
 func (a *Application) GetExpressions(ctx context.Context, id *proto.Id) (*proto.Expressions, error) {
 	exp, err := a.Storage.GetExpressions(int(id.GetId()))
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	positions, err := a.Storage.QueuePositions(int(id.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	result := make([]*proto.Expression, len(exp))
 	for i, expression := range exp {
-		result[i] = &proto.Expression{
-			Expression: expression.Expression,
-			Status:     expression.Status,
-			Id:         int32(expression.ID),
-			Result:     float32(expression.Result),
-			UserId:     int32(expression.UserID),
-		}
+		expression.Position = positions[expression.ID]
+		result[i] = convert.ExpressionToProto(expression)
 	}
 	return &proto.Expressions{
 		Expressions: result,
@@ -46,19 +209,61 @@ func (a *Application) GetExpressions(ctx context.Context, id *proto.Id) (*proto.
 }
 
 func (a *Application) GetExpression(ctx context.Context, id *proto.Id) (*proto.Expression, error) {
-	i, ok := a.Storage.GetExpression(int(id.GetId()))
-	if !ok {
-		return nil, status.Error(codes.NotFound, "expression not found")
+	expression, err := a.expression(int(id.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	if calc.EventType(expression) == calc.EventQueued {
+		positions, err := a.Storage.QueuePositions(expression.UserID)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		expression.Position = positions[expression.ID]
 	}
-	expression := &proto.Expression{
-		Expression: i.Expression,
-		Status:     i.Status,
-		Result:     float32(i.Result),
-		Id:         int32(i.ID),
-		UserId:     int32(i.UserID),
+	return convert.ExpressionToProto(expression), nil
+}
+
+// expression читает выражение id: NotFound, если его нет, и Internal при ошибке базы.
+func (a *Application) expression(id int) (dto.Expression, error) {
+	expression, ok, err := a.Storage.GetExpression(id)
+	if err != nil {
+		return dto.Expression{}, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return dto.Expression{}, status.Error(codes.NotFound, "expression not found")
 	}
 	return expression, nil
 }
+
+// CancelExpression убирает выражение из очереди или прерывает его вычисление.
+func (a *Application) CancelExpression(ctx context.Context, in *proto.Expression) (*proto.Expression, error) {
+	if a.Workers == nil {
+		return nil, status.Error(codes.Unavailable, "workers are not available")
+	}
+	expression, err := a.expression(int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	if expression.UserID != int(in.GetUserId()) {
+		return nil, status.Error(codes.PermissionDenied, "It is not your expression")
+	}
+	cancelled, err := a.Workers.Cancel(expression)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if cancelled {
+		expression, err = a.expression(expression.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// выражение могло успеть вычислиться, пока его отменяли
+	if expression.Status != calc.StatusCancelled {
+		return nil, status.Error(codes.FailedPrecondition, "expression is already finished")
+	}
+	return convert.ExpressionToProto(expression), nil
+}
+
 func (a *Application) Login(ctx context.Context, in *proto.User) (*proto.Id, error) {
 	user, ok := a.Storage.GetUser(in.Login)
 	if !ok {
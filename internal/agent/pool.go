@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetPool показывает размер пула локальных вычислителей. Доступен только с ADMIN_TOKEN.
+func (a *Application) GetPool(ctx context.Context, in *proto.Empty) (*proto.Pool, error) {
+	if err := a.checkAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if a.Workers == nil {
+		return nil, status.Error(codes.Unavailable, "worker pool is not available")
+	}
+	return a.pool(), nil
+}
+
+// ResizePool меняет число локальных вычислителей. Лишние вычислители останавливаются
+// после операции, которую уже выполняют. Доступен только с ADMIN_TOKEN.
+func (a *Application) ResizePool(ctx context.Context, in *proto.PoolSize) (*proto.Pool, error) {
+	if err := a.checkAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if a.Workers == nil {
+		return nil, status.Error(codes.Unavailable, "worker pool is not available")
+	}
+	if in.GetSize() < 0 || in.GetSize() > calc.MaxPoolSize {
+		return nil, status.Errorf(codes.InvalidArgument, "pool size must be from 0 to %d", calc.MaxPoolSize)
+	}
+	a.Workers.Pool().Resize(int(in.GetSize()))
+	return a.pool(), nil
+}
+
+func (a *Application) pool() *proto.Pool {
+	autoscale := a.Workers.Autoscale()
+	return &proto.Pool{
+		Size:      int32(a.Workers.Pool().Size()),
+		Autoscale: autoscale.Enabled(),
+		Min:       int32(autoscale.Min),
+		Max:       int32(autoscale.Max),
+	}
+}
@@ -1,17 +1,36 @@
 package agent
 
 import (
-	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"context"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
 	"github.com/philipslstwoyears/calculator-go/internal/storage"
 	"github.com/philipslstwoyears/calculator-go/proto"
 	"google.golang.org/grpc"
 	"log"
 	"net"
 	"os"
+	"time"
+)
+
+// Ограничения очереди по умолчанию.
+const (
+	DefaultMaxQueueDepth = 1000
+	DefaultRetryAfter    = 5 * time.Second
 )
 
 type Config struct {
 	Addr string
+	// MaxQueueDepth — сколько выражений может ждать вычисления; новые сверх этого отклоняются
+	MaxQueueDepth int
+	// RetryAfter — через сколько клиенту стоит повторить отклонённый запрос
+	RetryAfter time.Duration
+	// Limits — ограничения на размер принимаемых выражений
+	Limits calc.Limits
+	// AgentToken — общий секрет удалённых агентов для GetTask и SubmitResult; пустой отключает удалённых агентов
+	AgentToken string
+	// AdminToken — токен администратора для GetPool и ResizePool; пустой отключает эти вызовы
+	AdminToken string
 }
 
 func ConfigFromEnv() *Config {
@@ -20,6 +39,11 @@ func ConfigFromEnv() *Config {
 	if config.Addr == "" {
 		config.Addr = "8081"
 	}
+	config.MaxQueueDepth = settings.Int("MAX_QUEUE_DEPTH", DefaultMaxQueueDepth, settings.Positive)
+	config.RetryAfter = time.Duration(settings.Int("RETRY_AFTER_SECONDS", int(DefaultRetryAfter.Seconds()), settings.Positive)) * time.Second
+	config.Limits = calc.LimitsFromEnv()
+	config.AgentToken = os.Getenv("AGENT_TOKEN")
+	config.AdminToken = os.Getenv("ADMIN_TOKEN")
 	return config
 }
 
@@ -27,15 +51,29 @@ type Application struct {
 	proto.UnimplementedCalcServiceServer
 	config  *Config
 	Storage storage.Storage
-	input   chan dto.Expression
+	// Tasks — очередь операций для удалённых агентов; без неё GetTask отвечает Unavailable
+	Tasks *calc.Dispatcher
+	// Workers отменяют выражения; без них CancelExpression отвечает Unavailable
+	Workers *calc.Worker
+	// Events — изменения выражений для WatchExpression; без них WatchExpression отвечает Unavailable
+	Events *calc.Events
+	// queued будит вычислители, когда в очереди появляется новое выражение
+	queued chan<- struct{}
+	server *grpc.Server
+	// closing закрывается при остановке и завершает бесконечные потоки событий
+	closing chan struct{}
 }
 
-func New(s storage.Storage, input chan dto.Expression) *Application {
-	return &Application{
+func New(s storage.Storage, queued chan<- struct{}) *Application {
+	a := &Application{
 		config:  ConfigFromEnv(),
 		Storage: s,
-		input:   input,
+		queued:  queued,
+		server:  grpc.NewServer(),
+		closing: make(chan struct{}),
 	}
+	proto.RegisterCalcServiceServer(a.server, a)
+	return a
 }
 func (a *Application) RunServer() error {
 	lis, err := net.Listen("tcp", "0.0.0.0:"+a.config.Addr)
@@ -43,8 +81,23 @@ func (a *Application) RunServer() error {
 		return err
 	}
 	log.Printf("[AGENT SERVER] listening for gRPC, addr: %s", a.config.Addr)
-	privateGRPC := grpc.NewServer()
-	proto.RegisterCalcServiceServer(privateGRPC, a)
+	return a.server.Serve(lis)
+}
 
-	return privateGRPC.Serve(lis)
+// Shutdown перестаёт принимать вызовы и ждёт, пока завершатся начатые.
+// Если ctx отменён раньше, оставшиеся вызовы прерываются.
+// Потоки WatchExpression закрываются сразу, иначе остановка ждала бы их до конца ctx.
+func (a *Application) Shutdown(ctx context.Context) {
+	close(a.closing)
+	stopped := make(chan struct{})
+	go func() {
+		a.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.server.Stop()
+		<-stopped
+	}
 }
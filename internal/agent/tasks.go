@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// taskWait — сколько GetTask ждёт задачу, прежде чем ответить NotFound.
+// Агент после этого просто повторяет запрос.
+const taskWait = 10 * time.Second
+
+// GetQueue показывает операторам, насколько загружены очереди выражений и операций.
+func (a *Application) GetQueue(ctx context.Context, in *proto.Empty) (*proto.Queue, error) {
+	depth, err := a.Storage.QueueDepth()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	queue := &proto.Queue{
+		Depth:    int32(depth),
+		Capacity: int32(a.config.MaxQueueDepth),
+	}
+	if a.Tasks != nil {
+		queue.Tasks = int32(a.Tasks.Queued())
+	}
+	return queue, nil
+}
+
+// GetTask выдаёт удалённому агенту операцию в аренду. Агент передаёт AGENT_TOKEN в метаданных AgentTokenKey.
+func (a *Application) GetTask(ctx context.Context, in *proto.TaskRequest) (*proto.Task, error) {
+	if err := a.checkAgent(ctx); err != nil {
+		return nil, err
+	}
+	if a.Tasks == nil {
+		return nil, status.Error(codes.Unavailable, "task queue is not available")
+	}
+	ctx, cancel := context.WithTimeout(ctx, taskWait)
+	defer cancel()
+	leased, err := a.Tasks.Acquire(ctx)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "no task available")
+	}
+	return &proto.Task{
+		Id:        leased.ID,
+		Lease:     leased.Lease,
+		Operation: leased.Task.Operation,
+		Arg1:      leased.Task.Arg1,
+		Arg2:      leased.Task.Arg2,
+		Mode:      leased.Task.Mode,
+		LeaseMs:   a.Tasks.Lease().Milliseconds(),
+	}, nil
+}
+
+// SubmitResult принимает результат операции от удалённого агента.
+func (a *Application) SubmitResult(ctx context.Context, in *proto.TaskResult) (*proto.Empty, error) {
+	if err := a.checkAgent(ctx); err != nil {
+		return nil, err
+	}
+	if a.Tasks == nil {
+		return nil, status.Error(codes.Unavailable, "task queue is not available")
+	}
+	err := a.Tasks.Complete(in.GetId(), in.GetLease(), in.GetValue(), calc.ErrorFromCode(in.GetErrorCode(), in.GetErrorMessage()))
+	if errors.Is(err, calc.ErrLeaseExpired) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &proto.Empty{}, nil
+}
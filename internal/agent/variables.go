@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (a *Application) GetVariables(ctx context.Context, id *proto.Id) (*proto.Variables, error) {
+	variables, err := a.Storage.GetVariables(int(id.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := make([]*proto.Variable, len(variables))
+	for i, variable := range variables {
+		result[i] = &proto.Variable{
+			UserId: int32(variable.UserID),
+			Name:   variable.Name,
+			Value:  variable.Value,
+		}
+	}
+	return &proto.Variables{
+		Variables: result,
+	}, nil
+}
+
+func (a *Application) SetVariable(ctx context.Context, in *proto.Variable) (*proto.Variable, error) {
+	if err := calc.ValidateVariableName(in.GetName()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err := a.Storage.SetVariable(dto.Variable{
+		UserID: int(in.GetUserId()),
+		Name:   in.GetName(),
+		Value:  in.GetValue(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return in, nil
+}
+
+func (a *Application) DeleteVariable(ctx context.Context, in *proto.Variable) (*proto.Empty, error) {
+	ok, err := a.Storage.DeleteVariable(int(in.GetUserId()), in.GetName())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "variable not found")
+	}
+	return &proto.Empty{}, nil
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/webhook"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AddWebhook регистрирует адрес пользователя. Первый webhook пользователя возвращает ключ подписи,
+// даже если ключ уже создан доставкой на callback_url, — больше ключ не показывается,
+// новый выдаёт RotateWebhookSecret.
+func (a *Application) AddWebhook(ctx context.Context, in *proto.Webhook) (*proto.Webhook, error) {
+	if err := webhook.ValidateURL(in.GetUrl()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	w := dto.Webhook{UserID: int(in.GetUserId()), URL: in.GetUrl()}
+	id, err := a.Storage.AddWebhook(w)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	w.ID = id
+	secret, first, err := a.Storage.RevealWebhookSecret(w.UserID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if first {
+		w.Secret = secret
+	}
+	return convert.WebhookToProto(w), nil
+}
+
+// GetWebhooks возвращает webhooks пользователя без ключа подписи.
+func (a *Application) GetWebhooks(ctx context.Context, id *proto.Id) (*proto.Webhooks, error) {
+	webhooks, err := a.Storage.GetWebhooks(int(id.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := make([]*proto.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		result[i] = convert.WebhookToProto(w)
+	}
+	return &proto.Webhooks{
+		Webhooks: result,
+	}, nil
+}
+
+// RotateWebhookSecret заменяет ключ подписи пользователя и возвращает новый.
+func (a *Application) RotateWebhookSecret(ctx context.Context, id *proto.Id) (*proto.WebhookSecret, error) {
+	secret, err := a.Storage.RotateWebhookSecret(int(id.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &proto.WebhookSecret{Secret: secret}, nil
+}
+
+func (a *Application) DeleteWebhook(ctx context.Context, in *proto.Webhook) (*proto.Empty, error) {
+	ok, err := a.Storage.DeleteWebhook(int(in.GetUserId()), int(in.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	return &proto.Empty{}, nil
+}
+
+func (a *Application) GetDeliveries(ctx context.Context, in *proto.DeliveryRequest) (*proto.Deliveries, error) {
+	deliveries, err := a.Storage.GetDeliveries(int(in.GetUserId()), int(in.GetExpressionId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := make([]*proto.Delivery, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = convert.DeliveryToProto(d)
+	}
+	return &proto.Deliveries{
+		Deliveries: result,
+	}, nil
+}
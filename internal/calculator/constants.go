@@ -0,0 +1,75 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	constantsMu sync.RWMutex
+	constants   = map[string]float64{
+		"pi":  math.Pi,
+		"e":   math.E,
+		"tau": 2 * math.Pi,
+		"phi": math.Phi,
+	}
+)
+
+// RegisterConstant добавляет именованную константу, доступную во всех выражениях.
+func RegisterConstant(name string, value float64) error {
+	if !isValidName(name) {
+		return fmt.Errorf("invalid constant name: %q", name)
+	}
+	if _, ok := functions[name]; ok {
+		return fmt.Errorf("constant %s conflicts with function %s", name, name)
+	}
+	constantsMu.Lock()
+	defer constantsMu.Unlock()
+	constants[name] = value
+	return nil
+}
+
+// LoadConstants регистрирует константы из строки вида "r_earth=6371000,vat=0.2".
+func LoadConstants(config string) error {
+	for _, pair := range strings.Split(config, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, valueStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid constant definition: %q", pair)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+		if err != nil {
+			return fmt.Errorf("invalid value of constant %s: %w", name, err)
+		}
+		if err := RegisterConstant(strings.TrimSpace(name), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupConstant(name string) (float64, bool) {
+	constantsMu.RLock()
+	defer constantsMu.RUnlock()
+	value, ok := constants[name]
+	return value, ok
+}
+
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, character := range name {
+		if !unicode.IsLetter(character) && character != '_' && (i == 0 || !unicode.IsDigit(character)) {
+			return false
+		}
+	}
+	return true
+}
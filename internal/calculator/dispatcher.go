@@ -0,0 +1,291 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
+	"sync"
+	"time"
+)
+
+// DefaultLease — время, за которое вычислитель должен вернуть результат задачи,
+// если TASK_LEASE_MS не задана.
+const DefaultLease = 30 * time.Second
+
+// ErrLeaseExpired возвращается, когда результат приходит по задаче, аренда которой
+// уже истекла и задача передана другому вычислителю.
+var ErrLeaseExpired = errors.New("task lease expired")
+
+// LeasedTask — задача, выданная вычислителю в аренду.
+// Результат принимается только с тем же ID и Lease.
+type LeasedTask struct {
+	ID    int64
+	Lease int64
+	Task  Task
+}
+
+type leasedTask struct {
+	LeasedTask
+	deadline time.Time
+}
+
+// Dispatcher раздаёт задачи вычислителям: горутинам этого процесса и удалённым агентам.
+// Задача выдаётся в аренду; если результат не пришёл до окончания аренды,
+// задача возвращается в очередь и достаётся следующему вычислителю.
+// Задачи более высокого приоритета выдаются первыми, а каждые aging ожидания поднимают
+// задачу на уровень выше. Внутри уровня у каждого пользователя своя очередь задач,
+// а очереди обслуживаются по кругу: длинное выражение одного пользователя
+// не задерживает короткие выражения других.
+type Dispatcher struct {
+	lease time.Duration
+	aging time.Duration
+
+	mu sync.Mutex
+	// levels — очереди уровней приоритета, levels[0] — самый высокий
+	levels    [dto.PriorityLevels]roundRobin
+	leased    map[int64]*leasedTask
+	nextID    int64
+	nextLease int64
+	// ready закрывается и пересоздаётся при каждой новой задаче, чтобы разбудить ждущих
+	ready chan struct{}
+	// waited и acquired — суммарное ожидание и число выданных задач с прошлого вызова load
+	waited   time.Duration
+	acquired int
+}
+
+func NewDispatcher(lease time.Duration) *Dispatcher {
+	return &Dispatcher{
+		lease:  lease,
+		aging:  dto.DefaultPriorityAging,
+		leased: make(map[int64]*leasedTask),
+		ready:  make(chan struct{}),
+	}
+}
+
+// LeaseFromEnv читает длительность аренды задачи из TASK_LEASE_MS.
+func LeaseFromEnv() time.Duration {
+	return settings.Duration("TASK_LEASE_MS", DefaultLease)
+}
+
+// PriorityAgingFromEnv читает из PRIORITY_AGING_MS, за сколько ожидания выражение или задача
+// поднимается на один уровень приоритета.
+func PriorityAgingFromEnv() time.Duration {
+	return settings.Duration("PRIORITY_AGING_MS", dto.DefaultPriorityAging)
+}
+
+// ValidatePriority проверяет приоритет выражения. Пустой приоритет означает dto.PriorityNormal.
+func ValidatePriority(priority string) error {
+	switch priority {
+	case "", dto.PriorityInteractive, dto.PriorityNormal, dto.PriorityBatch:
+		return nil
+	}
+	return fmt.Errorf("unknown priority: %s", priority)
+}
+
+// Lease возвращает длительность аренды задачи.
+func (d *Dispatcher) Lease() time.Duration {
+	return d.lease
+}
+
+// Submit ставит задачу в очередь.
+func (d *Dispatcher) Submit(task Task) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	task.queued = time.Now()
+	d.push(d.nextID, task)
+}
+
+// Queued возвращает число операций, ожидающих вычислителя.
+func (d *Dispatcher) Queued() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queued := 0
+	for _, level := range d.levels {
+		queued += level.len()
+	}
+	return queued
+}
+
+func (d *Dispatcher) push(id int64, task Task) {
+	d.levels[task.priority].push(LeasedTask{ID: id, Task: task})
+	close(d.ready)
+	d.ready = make(chan struct{})
+}
+
+// load возвращает число ожидающих задач и время ожидания: среднее по задачам, выданным
+// с прошлого вызова, или время ожидания самой давней задачи, если оно больше.
+func (d *Dispatcher) load(now time.Time) (int, time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var wait time.Duration
+	if d.acquired > 0 {
+		wait = d.waited / time.Duration(d.acquired)
+	}
+	d.waited, d.acquired = 0, 0
+	queued := 0
+	for i := range d.levels {
+		queued += d.levels[i].len()
+		if oldest, ok := d.levels[i].oldest(); ok {
+			wait = max(wait, now.Sub(oldest))
+		}
+	}
+	return queued, wait
+}
+
+// next берёт задачу с уровня, который с учётом ожидания сейчас самый приоритетный.
+func (d *Dispatcher) next(now time.Time) (LeasedTask, bool) {
+	for {
+		best := -1
+		bestRank := 0
+		for i := range d.levels {
+			oldest, ok := d.levels[i].oldest()
+			if !ok {
+				continue
+			}
+			rank := max(0, i-int(now.Sub(oldest)/d.aging))
+			if best < 0 || rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			return LeasedTask{}, false
+		}
+		// уровень мог состоять только из задач отменённых выражений, тогда он опустел
+		if task, ok := d.levels[best].next(); ok {
+			return task, true
+		}
+	}
+}
+
+// roundRobin — очередь задач одного уровня приоритета с отдельной очередью для каждого пользователя.
+type roundRobin struct {
+	queues map[int][]LeasedTask
+	// users — круг пользователей с непустыми очередями; следующая задача берётся у первого
+	users []int
+}
+
+func (r *roundRobin) push(task LeasedTask) {
+	if r.queues == nil {
+		r.queues = make(map[int][]LeasedTask)
+	}
+	user := task.Task.user
+	if len(r.queues[user]) == 0 {
+		r.users = append(r.users, user)
+	}
+	r.queues[user] = append(r.queues[user], task)
+}
+
+func (r *roundRobin) len() int {
+	count := 0
+	for _, queue := range r.queues {
+		count += len(queue)
+	}
+	return count
+}
+
+// oldest возвращает время постановки самой давней задачи уровня.
+func (r *roundRobin) oldest() (time.Time, bool) {
+	var oldest time.Time
+	for _, user := range r.users {
+		if queued := r.queues[user][0].Task.queued; oldest.IsZero() || queued.Before(oldest) {
+			oldest = queued
+		}
+	}
+	return oldest, len(r.users) > 0
+}
+
+// next берёт задачу у первого пользователя в круге и переставляет его в конец круга.
+// Задачи отменённых выражений никому не нужны и отбрасываются.
+func (r *roundRobin) next() (LeasedTask, bool) {
+	for len(r.users) > 0 {
+		user := r.users[0]
+		r.users = r.users[1:]
+		queue := r.queues[user]
+		for len(queue) > 0 && queue[0].Task.context().Err() != nil {
+			queue = queue[1:]
+		}
+		if len(queue) == 0 {
+			delete(r.queues, user)
+			continue
+		}
+		task := queue[0]
+		if len(queue) > 1 {
+			r.queues[user] = queue[1:]
+			r.users = append(r.users, user)
+		} else {
+			delete(r.queues, user)
+		}
+		return task, true
+	}
+	return LeasedTask{}, false
+}
+
+// Acquire ждёт задачу и выдаёт её в аренду. Ожидание прерывается отменой ctx.
+func (d *Dispatcher) Acquire(ctx context.Context) (LeasedTask, error) {
+	for {
+		d.mu.Lock()
+		now := time.Now()
+		d.requeueExpired(now)
+		if task, ok := d.next(now); ok {
+			leased := &leasedTask{LeasedTask: task, deadline: now.Add(d.lease)}
+			d.waited += now.Sub(task.Task.queued)
+			d.acquired++
+			d.nextLease++
+			leased.Lease = d.nextLease
+			d.leased[leased.ID] = leased
+			d.mu.Unlock()
+			return leased.LeasedTask, nil
+		}
+		ready := d.ready
+		wait := d.nextExpiry(now)
+		d.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ready:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return LeasedTask{}, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// Complete принимает результат задачи id. Если аренда lease уже истекла,
+// возвращается ErrLeaseExpired, а результат отбрасывается.
+func (d *Dispatcher) Complete(id, lease int64, value string, err error) error {
+	d.mu.Lock()
+	leased, ok := d.leased[id]
+	if !ok || leased.Lease != lease {
+		d.mu.Unlock()
+		return ErrLeaseExpired
+	}
+	delete(d.leased, id)
+	d.mu.Unlock()
+	leased.Task.done <- TaskResult{ID: leased.Task.ID, Value: value, Err: err}
+	return nil
+}
+
+func (d *Dispatcher) requeueExpired(now time.Time) {
+	for id, leased := range d.leased {
+		if now.After(leased.deadline) {
+			delete(d.leased, id)
+			d.push(id, leased.Task)
+		}
+	}
+}
+
+// nextExpiry возвращает время до окончания ближайшей аренды.
+func (d *Dispatcher) nextExpiry(now time.Time) time.Duration {
+	wait := d.lease
+	for _, leased := range d.leased {
+		if until := leased.deadline.Sub(now); until < wait {
+			wait = until
+		}
+	}
+	return max(wait, time.Millisecond)
+}
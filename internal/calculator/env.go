@@ -0,0 +1,226 @@
+package calc
+
+import (
+	"context"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// MaxCallDepth ограничивает глубину вложенных вызовов пользовательских функций,
+// чтобы рекурсивное определение не вычислялось бесконечно.
+const MaxCallDepth = 32
+
+// UserFunction — функция, определённая пользователем, например hyp(a,b) = sqrt(a*a+b*b).
+type UserFunction struct {
+	Params []string
+	Body   string
+}
+
+// Env — окружение пользователя, в котором вычисляется выражение.
+type Env struct {
+	Variables map[string]float64
+	Functions map[string]UserFunction
+	// exact — точные значения параметров пользовательской функции в точном режиме.
+	exact map[string]*big.Rat
+	depth int
+	// budget ограничивает число операций, общий с дочерними окружениями
+	budget *budget
+	// inexact отмечает, что часть точного вычисления выполнена в float64; общий с дочерними окружениями
+	inexact *atomic.Bool
+}
+
+// NewEnv собирает окружение из сохранённых переменных и функций пользователя.
+func NewEnv(variables []dto.Variable, functions []dto.Function) *Env {
+	env := &Env{
+		Variables: make(map[string]float64, len(variables)),
+		Functions: make(map[string]UserFunction, len(functions)),
+		inexact:   new(atomic.Bool),
+	}
+	for _, variable := range variables {
+		env.Variables[variable.Name] = variable.Value
+	}
+	for _, f := range functions {
+		env.Functions[f.Name] = UserFunction{Params: f.Params, Body: f.Body}
+	}
+	return env
+}
+
+func (e *Env) lookup(name string) (float64, bool) {
+	if e != nil {
+		if value, ok := e.Variables[name]; ok {
+			return value, true
+		}
+	}
+	return lookupConstant(name)
+}
+
+// literal возвращает запись значения name, которую понимает стековая машина.
+func (e *Env) literal(name string) (string, bool) {
+	if e != nil {
+		if value, ok := e.exact[name]; ok {
+			return value.RatString(), true
+		}
+	}
+	value, ok := e.lookup(name)
+	if !ok {
+		return "", false
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), true
+}
+
+func (e *Env) userFunction(name string) (UserFunction, bool) {
+	if e == nil {
+		return UserFunction{}, false
+	}
+	f, ok := e.Functions[name]
+	return f, ok
+}
+
+func (e *Env) hasFunction(name string) bool {
+	if _, ok := functions[name]; ok {
+		return true
+	}
+	_, ok := e.userFunction(name)
+	return ok
+}
+
+func (e *Env) checkArgs(name string, count int) error {
+	if _, ok := functions[name]; ok {
+		return checkArgs(name, count)
+	}
+	f, ok := e.userFunction(name)
+	if !ok {
+		return errorf(ErrUnknownToken, "unknown function: %s", name)
+	}
+	if count != len(f.Params) {
+		return errorf(ErrArity, "function %s expects %d arguments, got %d", name, len(f.Params), count)
+	}
+	return nil
+}
+
+// call вызывает встроенную или пользовательскую функцию.
+// Тело пользовательской функции вычисляется в дочернем окружении, где параметры
+// перекрывают одноимённые переменные пользователя.
+func (e *Env) call(ctx context.Context, name string, args []float64) (float64, error) {
+	f, ok := e.userFunction(name)
+	if !ok {
+		return callFunction(name, args)
+	}
+	if err := e.checkArgs(name, len(args)); err != nil {
+		return 0, err
+	}
+	if e.depth >= MaxCallDepth {
+		return 0, errorf(ErrOverflow, "function %s: maximum call depth %d exceeded", name, MaxCallDepth)
+	}
+	return Calc(ctx, f.Body, e.child(f.Params, args))
+}
+
+func (e *Env) child(params []string, args []float64) *Env {
+	child := &Env{
+		Variables: make(map[string]float64, len(e.Variables)+len(params)),
+		Functions: e.Functions,
+		depth:     e.depth + 1,
+		budget:    e.budget,
+		inexact:   e.inexact,
+	}
+	for name, value := range e.Variables {
+		child.Variables[name] = value
+	}
+	for i, param := range params {
+		child.Variables[param] = args[i]
+	}
+	return child
+}
+
+// ValidateVariableName проверяет, что имя можно использовать для пользовательской переменной.
+func ValidateVariableName(name string) error {
+	if !isValidName(name) {
+		return errorf(ErrInvalidName, "invalid variable name: %q", name)
+	}
+	if _, ok := functions[name]; ok {
+		return errorf(ErrInvalidName, "cannot assign to function %s", name)
+	}
+	if _, ok := lookupConstant(name); ok {
+		return errorf(ErrInvalidName, "cannot assign to constant %s", name)
+	}
+	return nil
+}
+
+// ParseFunction разбирает определение вида "vat(x) = x*1.2".
+func ParseFunction(definition string) (string, UserFunction, error) {
+	head, body, ok := strings.Cut(definition, "=")
+	if !ok {
+		return "", UserFunction{}, errorf(ErrSyntax, "function definition must look like f(x) = expression")
+	}
+	head = strings.TrimSpace(head)
+	name, params, ok := strings.Cut(head, "(")
+	if !ok || !strings.HasSuffix(params, ")") {
+		return "", UserFunction{}, errorf(ErrSyntax, "function definition must look like f(x) = expression")
+	}
+	f := UserFunction{Body: strings.TrimSpace(body)}
+	params = strings.TrimSpace(strings.TrimSuffix(params, ")"))
+	if params != "" {
+		for _, param := range strings.Split(params, ",") {
+			f.Params = append(f.Params, strings.TrimSpace(param))
+		}
+	}
+	return strings.TrimSpace(name), f, nil
+}
+
+// ValidateFunction проверяет имя, параметры и тело функции name.
+// Тело может ссылаться на переменные и функции из env, а также на саму функцию.
+func ValidateFunction(name string, f UserFunction, env *Env) error {
+	if !isValidName(name) {
+		return errorf(ErrInvalidName, "invalid function name: %q", name)
+	}
+	if _, ok := functions[name]; ok {
+		return errorf(ErrInvalidName, "cannot redefine built-in function %s", name)
+	}
+	if _, ok := lookupConstant(name); ok {
+		return errorf(ErrInvalidName, "cannot redefine constant %s", name)
+	}
+	seen := make(map[string]bool, len(f.Params))
+	for _, param := range f.Params {
+		if !isValidName(param) {
+			return errorf(ErrInvalidName, "invalid parameter name: %q", param)
+		}
+		// параметр с именем встроенной функции или константы перекрыл бы её в теле
+		if _, ok := functions[param]; ok {
+			return errorf(ErrInvalidName, "parameter %s shadows built-in function %s", param, param)
+		}
+		if _, ok := lookupConstant(param); ok {
+			return errorf(ErrInvalidName, "parameter %s shadows constant %s", param, param)
+		}
+		if seen[param] {
+			return errorf(ErrInvalidName, "duplicate parameter %s", param)
+		}
+		seen[param] = true
+	}
+	if strings.TrimSpace(f.Body) == "" {
+		return ErrEmptyExpression
+	}
+
+	check := &Env{Variables: map[string]float64{}, Functions: map[string]UserFunction{name: f}}
+	if env != nil {
+		for k, v := range env.Variables {
+			check.Variables[k] = v
+		}
+		for k, v := range env.Functions {
+			if k != name {
+				check.Functions[k] = v
+			}
+		}
+	}
+	for _, param := range f.Params {
+		check.Variables[param] = 0
+	}
+	root, err := parse(f.Body)
+	if err != nil {
+		return err
+	}
+	_, err = compile(root, check, []string{})
+	return err
+}
@@ -0,0 +1,113 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Виды ошибок вычисления. Конкретная ошибка сохраняет свой текст, а её вид
+// определяется через errors.Is, например errors.Is(err, ErrDivisionByZero).
+var (
+	ErrEmptyExpression  = errors.New("Expression is empty")
+	ErrSyntax           = errors.New("syntax error")
+	ErrUnbalancedParens = errors.New("unbalanced parentheses")
+	ErrUnknownToken     = errors.New("unknown token")
+	ErrInvalidName      = errors.New("invalid name")
+	ErrArity            = errors.New("wrong number of arguments")
+	ErrDivisionByZero   = errors.New("Division by zero")
+	ErrDomain           = errors.New("argument out of domain")
+	ErrOverflow         = errors.New("overflow")
+	ErrBudget           = errors.New("evaluation budget exceeded")
+	ErrDependency       = errors.New("dependency failed")
+	// ErrInterrupted — выражение слишком часто прерывалось остановкой процесса и больше не вычисляется
+	ErrInterrupted = errors.New("expression was interrupted too many times")
+	// ErrCancelled — выражение отменено пользователем
+	ErrCancelled = context.Canceled
+	// ErrTimeout — истёк срок, заданный клиентом для выражения
+	ErrTimeout = context.DeadlineExceeded
+)
+
+// Коды ошибок, которые видят клиенты. Они не меняются вместе с текстом ошибок.
+const (
+	CodeEmptyExpression  = "empty_expression"
+	CodeSyntax           = "syntax_error"
+	CodeUnbalancedParens = "unbalanced_parens"
+	CodeUnknownToken     = "unknown_token"
+	CodeInvalidName      = "invalid_name"
+	CodeArity            = "wrong_arity"
+	CodeDivisionByZero   = "division_by_zero"
+	CodeDomain           = "domain_error"
+	CodeOverflow         = "overflow"
+	CodeBudget           = "budget_exceeded"
+	CodeDependency       = "dependency_failed"
+	CodeCancelled        = "cancelled"
+	CodeTimeout          = "timeout"
+	CodeInterrupted      = "interrupted"
+	// CodeInternal — ошибка не вычисления, а окружения, например базы данных.
+	CodeInternal = "internal"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrEmptyExpression, CodeEmptyExpression},
+	{ErrSyntax, CodeSyntax},
+	{ErrUnbalancedParens, CodeUnbalancedParens},
+	{ErrUnknownToken, CodeUnknownToken},
+	{ErrInvalidName, CodeInvalidName},
+	{ErrArity, CodeArity},
+	{ErrDivisionByZero, CodeDivisionByZero},
+	{ErrDomain, CodeDomain},
+	{ErrOverflow, CodeOverflow},
+	{ErrBudget, CodeBudget},
+	{ErrDependency, CodeDependency},
+	{ErrCancelled, CodeCancelled},
+	{ErrTimeout, CodeTimeout},
+	{ErrInterrupted, CodeInterrupted},
+}
+
+// ErrorCode возвращает код вида ошибки err или пустую строку для nil.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			return errorCode.code
+		}
+	}
+	return CodeInternal
+}
+
+// ErrorFromCode восстанавливает ошибку по коду и тексту, например полученным от удалённого агента.
+func ErrorFromCode(code, message string) error {
+	if code == "" {
+		return nil
+	}
+	for _, errorCode := range errorCodes {
+		if errorCode.code == code {
+			return errorf(errorCode.err, "%s", message)
+		}
+	}
+	return errors.New(message)
+}
+
+// kindError — ошибка со своим текстом, которая относится к виду kind.
+type kindError struct {
+	kind    error
+	message string
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+func errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
@@ -1,168 +1,247 @@
 package calc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/philipslstwoyears/calculator-go/internal/stack"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-	"unicode"
 )
 
-func operate(num1 float64, num2 float64, operation string) (float64, error) {
+// delayVariables — переменные окружения с имитируемой длительностью каждой операции в миллисекундах.
+var delayVariables = map[string]string{
+	"+": "TIME_ADDITION_MS",
+	"-": "TIME_SUBTRACTION_MS",
+	"*": "TIME_MULTIPLICATIONS_MS",
+	"/": "TIME_DIVISIONS_MS",
+	"^": "TIME_POWER_MS",
+}
+
+var (
+	delaysMu sync.RWMutex
+	delays   = map[string]time.Duration{}
+)
+
+// LoadDelays читает длительности операций из TIME_*_MS. Вызывается один раз при запуске:
+// незаданная или некорректная переменная — ошибка конфигурации, а не ошибка каждой операции.
+func LoadDelays() error {
+	loaded := make(map[string]time.Duration, len(delayVariables))
+	for operation, name := range delayVariables {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return fmt.Errorf("%s is not set", name)
+		}
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms < 0 {
+			return fmt.Errorf("%s must be a non-negative number of milliseconds, got %q", name, value)
+		}
+		loaded[operation] = time.Duration(ms) * time.Millisecond
+	}
+	delaysMu.Lock()
+	defer delaysMu.Unlock()
+	delays = loaded
+	return nil
+}
+
+// delay ждёт имитируемую длительность операции. Ожидание прерывается отменой ctx.
+func delay(ctx context.Context, operation string) error {
+	delaysMu.RLock()
+	duration := delays[operation]
+	delaysMu.RUnlock()
+	if duration <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func operate(ctx context.Context, num1 float64, num2 float64, operation string) (float64, error) {
+	if err := delay(ctx, operation); err != nil {
+		return 0, err
+	}
+	var result float64
 	switch operation {
 	case "+":
-		additionMs := os.Getenv("TIME_ADDITION_MS")
-		ms, err := strconv.Atoi(additionMs)
-		if err != nil {
-			return 0, err
-		}
-		time.Sleep(time.Duration(ms))
-		return num1 + num2, nil
+		result = num1 + num2
 	case "-":
-		subMs := os.Getenv("TIME_SUBTRACTION_MS")
-		ms, err := strconv.Atoi(subMs)
-		if err != nil {
-			return 0, err
-		}
-		time.Sleep(time.Duration(ms))
-		return num1 - num2, nil
+		result = num1 - num2
 	case "*":
-		multMs := os.Getenv("TIME_MULTIPLICATIONS_MS")
-		ms, err := strconv.Atoi(multMs)
-		if err != nil {
-			return 0, err
-		}
-		time.Sleep(time.Duration(ms))
-		return num1 * num2, nil
+		result = num1 * num2
 	case "/":
-		divMs := os.Getenv("TIME_DIVISIONS_MS")
-		ms, err := strconv.Atoi(divMs)
-		if err != nil {
-			return 0, err
-		}
-		time.Sleep(time.Duration(ms))
 		if num2 == 0 {
-			return 0, errors.New("Division by zero")
+			return 0, ErrDivisionByZero
+		}
+		result = num1 / num2
+	case "^":
+		if num1 == 0 && num2 < 0 {
+			return 0, ErrDivisionByZero
 		}
-		return num1 / num2, nil
+		result = math.Pow(num1, num2)
+		if math.IsNaN(result) {
+			return 0, errorf(ErrDomain, "%v^%v is not a real number", num1, num2)
+		}
+	default:
+		return -1, nil
+	}
+	if math.IsInf(result, 0) {
+		return 0, errorf(ErrOverflow, "result of %v %s %v is out of range", num1, operation, num2)
+	}
+	return result, nil
+}
+
+// arithmetic — числа, с которыми работает стековая машина: float64 или точные дроби.
+type arithmetic[T any] interface {
+	parse(literal string) (T, error)
+	// format записывает число так, чтобы parse восстановил его без потерь.
+	format(num T) string
+	operate(ctx context.Context, num1, num2 T, operation string) (T, error)
+	// approximate сообщает, что точная арифметика выполнит операцию через float64.
+	approximate(num1, num2 T, operation string) bool
+	negate(num T) T
+	call(ctx context.Context, env *Env, name string, args []T) (T, error)
+}
+
+type floatArithmetic struct{}
+
+func (floatArithmetic) parse(literal string) (float64, error) {
+	num, err := strconv.ParseFloat(literal, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, errorf(ErrOverflow, "number %s is out of range", literal)
 	}
-	return -1, nil
+	if err != nil {
+		return 0, errorf(ErrUnknownToken, "invalid number: %s", literal)
+	}
+	return num, nil
 }
 
-func calcPolishNotation(polishNotation []string) (float64, error) {
-	numStack := stack.New[float64]()
+func (floatArithmetic) format(num float64) string {
+	return strconv.FormatFloat(num, 'g', -1, 64)
+}
+
+func (floatArithmetic) operate(ctx context.Context, num1, num2 float64, operation string) (float64, error) {
+	return operate(ctx, num1, num2, operation)
+}
+
+func (floatArithmetic) approximate(num1, num2 float64, operation string) bool {
+	return false
+}
+
+func (floatArithmetic) negate(num float64) float64 {
+	return -num
+}
+
+func (floatArithmetic) call(ctx context.Context, env *Env, name string, args []float64) (float64, error) {
+	return env.call(ctx, name, args)
+}
+
+func calcPolishNotation[T any](ctx context.Context, polishNotation []string, env *Env, arith arithmetic[T]) (T, error) {
+	var zero T
+	numStack := stack.New[T]()
 	for _, elem := range polishNotation {
 		switch elem {
-		case "+", "-", "*", "/":
+		case "+", "-", "*", "/", "^":
+			if err := env.spend(); err != nil {
+				return zero, err
+			}
 			if numStack.Size() < 2 {
-				return -1, fmt.Errorf("Wrong expression")
+				return zero, errorf(ErrSyntax, "Wrong expression")
 			}
 			num2 := numStack.Pop()
 			num1 := numStack.Pop()
-			result, err := operate(num1, num2, elem)
+			if arith.approximate(num1, num2, elem) {
+				env.markInexact()
+			}
+			result, err := arith.operate(ctx, num1, num2, elem)
 			if err != nil {
-				return -1, err
+				return zero, err
 			}
 			numStack.Push(result)
 		case "~":
+			if err := env.spend(); err != nil {
+				return zero, err
+			}
 			if numStack.IsEmpty() {
-				return -1, fmt.Errorf("Wrong expression")
+				return zero, errorf(ErrSyntax, "Wrong expression")
 			}
-			numStack.Push(-numStack.Pop())
+			numStack.Push(arith.negate(numStack.Pop()))
 		default:
-			num, err := strconv.ParseFloat(elem, 64)
+			if name, count, ok := parseFunctionCall(elem); ok {
+				if err := env.spend(); err != nil {
+					return zero, err
+				}
+				if numStack.Size() < count {
+					return zero, errorf(ErrSyntax, "Wrong expression")
+				}
+				args := make([]T, count)
+				for i := count - 1; i >= 0; i-- {
+					args[i] = numStack.Pop()
+				}
+				result, err := arith.call(ctx, env, name, args)
+				if err != nil {
+					return zero, err
+				}
+				numStack.Push(result)
+				continue
+			}
+			num, err := arith.parse(elem)
 			if err != nil {
-				return -1, err
+				return zero, err
 			}
 			numStack.Push(num)
 		}
 	}
+	if numStack.Size() != 1 {
+		return zero, errorf(ErrSyntax, "Wrong expression")
+	}
 	return numStack.Pop(), nil
 }
 
-func convertToPolishNotation(expression string) ([]string, error) {
-	polishNotation := []string{}
-	operationStack := stack.New[string]()
-	num := ""
-	countBrackets := 0
-
-	for i, character := range expression {
-		switch character {
-		case '+', '-', '*', '/', '(':
-			if num != "" {
-				polishNotation = append(polishNotation, num)
-				num = ""
-			}
-			if character == '-' && (i == 0 || (expression[i-1] != ')' && !unicode.IsDigit(rune(expression[i-1])))) {
-				operationStack.Push("~")
-				continue
-			}
-			if character == '(' {
-				countBrackets++
-			}
-			for !operationStack.IsEmpty() {
-				operationFromStack := operationStack.Peek()
-				if (character == '+' || character == '-') && (operationFromStack != "(" && operationFromStack != ")") {
-					polishNotation = append(polishNotation, operationStack.Pop())
-				} else if (character == '*' || character == '/') && (operationFromStack == "*" || operationFromStack == "/") {
-					polishNotation = append(polishNotation, operationStack.Pop())
-				} else {
-					break
-				}
-			}
-			operationStack.Push(string(character))
-
-		case ')':
-			if num != "" {
-				polishNotation = append(polishNotation, num)
-				num = ""
-			}
-			countBrackets--
-			if countBrackets < 0 {
-				return []string{}, fmt.Errorf("Wrong expression: check brackets")
-			}
-			for operationFromStack := operationStack.Pop(); operationFromStack != "("; operationFromStack = operationStack.Pop() {
-				polishNotation = append(polishNotation, operationFromStack)
-			}
-
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
-			num += string(character)
-
-		default:
-			return []string{}, fmt.Errorf("inccorect simbol: %s", string(character))
-		}
+// functionCall кодирует вызов функции в польской записи как "имя:количество_аргументов".
+func functionCall(name string, count int) string {
+	return name + ":" + strconv.Itoa(count)
+}
 
+func parseFunctionCall(elem string) (string, int, bool) {
+	name, countStr, ok := strings.Cut(elem, ":")
+	if !ok {
+		return "", 0, false
 	}
-
-	if num != "" {
-		polishNotation = append(polishNotation, num)
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return "", 0, false
 	}
+	return name, count, true
+}
 
-	for !operationStack.IsEmpty() {
-		operationFromStack := operationStack.Pop()
-		if operationFromStack == "(" {
-			return []string{}, fmt.Errorf("Wrong expression: check brackets")
-		}
-		polishNotation = append(polishNotation, operationFromStack)
+// calcTree переводит дерево в польскую запись и вычисляет её в арифметике arith.
+func calcTree[T any](ctx context.Context, root node, env *Env, arith arithmetic[T]) (T, error) {
+	polishNotation, err := compile(root, env, []string{})
+	if err != nil {
+		var zero T
+		return zero, err
 	}
-
-	return polishNotation, nil
+	return calcPolishNotation[T](ctx, polishNotation, env, arith)
 }
 
-func Calc(expression string) (float64, error) {
-	if expression == "" {
-		return 0, fmt.Errorf("Expression is empty")
-	}
-	expression = strings.ReplaceAll(expression, " ", "")
-	expression = strings.ReplaceAll(expression, "\n", "")
-	polishNotation, err := convertToPolishNotation(expression)
+// Calc вычисляет выражение в окружении env; env может быть nil.
+// При отмене ctx вычисление прерывается между операциями и возвращает ctx.Err().
+func Calc(ctx context.Context, expression string, env *Env) (float64, error) {
+	root, err := parse(expression)
 	if err != nil {
 		return -1, err
 	}
-	result, err := calcPolishNotation(polishNotation)
+	result, err := calcTree[float64](ctx, root, env, floatArithmetic{})
 	if err != nil {
 		return -1, err
 	}
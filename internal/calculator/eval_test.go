@@ -1,9 +1,19 @@
 package calc
 
 import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
 	"github.com/joho/godotenv"
+	"github.com/philipslstwoyears/calculator-go/internal/mocks"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
 	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCalc(t *testing.T) {
@@ -11,6 +21,21 @@ func TestCalc(t *testing.T) {
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
+	if err := LoadDelays(); err != nil {
+		log.Fatal(err)
+	}
+	if err := LoadConstants("r_test=1.5"); err != nil {
+		t.Fatalf("LoadConstants returns error: %v", err)
+	}
+	env := &Env{
+		Variables: map[string]float64{"x": 12, "rate": 0.5},
+		Functions: map[string]UserFunction{
+			"vat":  {Params: []string{"x"}, Body: "x*1.2"},
+			"hyp":  {Params: []string{"a", "b"}, Body: "sqrt(a*a+b*b)"},
+			"half": {Params: []string{"y"}, Body: "y*rate"},
+			"loop": {Params: []string{"n"}, Body: "loop(n+1)"},
+		},
+	}
 	testCasesSuccess := []struct {
 		name           string
 		expression     string
@@ -51,11 +76,86 @@ func TestCalc(t *testing.T) {
 			expression:     "-11-1*20/001",
 			expectedResult: -31,
 		},
+		{
+			name:           "power",
+			expression:     "2^10",
+			expectedResult: 1024,
+		},
+		{
+			name:           "power right associativity",
+			expression:     "2^3^2",
+			expectedResult: 512,
+		},
+		{
+			name:           "power before unary minus",
+			expression:     "-2^2",
+			expectedResult: -4,
+		},
+		{
+			name:           "power before multiplication",
+			expression:     "3*2^2/4",
+			expectedResult: 3,
+		},
+		{
+			name:           "power with negative exponent",
+			expression:     "(-2)^-1",
+			expectedResult: -0.5,
+		},
+		{
+			name:           "function",
+			expression:     "sqrt(16)+abs(-2)",
+			expectedResult: 6,
+		},
+		{
+			name:           "function with several arguments",
+			expression:     "pow(2, 3)*2",
+			expectedResult: 16,
+		},
+		{
+			name:           "variadic function",
+			expression:     "max(1, 7, 3) - min(4, -2*3)",
+			expectedResult: 13,
+		},
+		{
+			name:           "nested functions",
+			expression:     "round(cos(0)*floor(2.7)+ceil(0.2))",
+			expectedResult: 3,
+		},
+		{
+			name:           "unary minus before function",
+			expression:     "-abs(-3)^2",
+			expectedResult: -9,
+		},
+		{
+			name:           "constants",
+			expression:     "tau/pi*e/e",
+			expectedResult: 2,
+		},
+		{
+			name:           "registered constant",
+			expression:     "2*r_test",
+			expectedResult: 3,
+		},
+		{
+			name:           "variables",
+			expression:     "x*rate + sqrt(x+4)",
+			expectedResult: 10,
+		},
+		{
+			name:           "user functions",
+			expression:     "hyp(3, 4) + vat(10) - half(x)",
+			expectedResult: 11,
+		},
+		{
+			name:           "user function parameter shadows variable",
+			expression:     "vat(5)+x",
+			expectedResult: 18,
+		},
 	}
 
 	for _, testCase := range testCasesSuccess {
 		t.Run(testCase.name, func(t *testing.T) {
-			val, err := Calc(testCase.expression)
+			val, err := Calc(context.Background(), testCase.expression, env)
 			if err != nil {
 				t.Fatalf("successful case %s returns error: %v", testCase.expression, err)
 			}
@@ -64,4 +164,1163 @@ func TestCalc(t *testing.T) {
 			}
 		})
 	}
+
+	testCasesFail := []struct {
+		name          string
+		expression    string
+		expectedError string
+	}{
+		{
+			name:          "unknown function",
+			expression:    "foo(1)",
+			expectedError: "unknown function: foo",
+		},
+		{
+			name:          "too many arguments",
+			expression:    "sqrt(1, 2)",
+			expectedError: "function sqrt expects 1 arguments, got 2",
+		},
+		{
+			name:          "not enough arguments",
+			expression:    "min()",
+			expectedError: "function min expects at least 1 arguments, got 0",
+		},
+		{
+			name:          "arguments out of range",
+			expression:    "log(1, 2, 3)",
+			expectedError: "function log expects from 1 to 2 arguments, got 3",
+		},
+		{
+			name:          "domain",
+			expression:    "sqrt(-1)",
+			expectedError: "function sqrt: argument out of domain",
+		},
+		{
+			name:          "unknown constant",
+			expression:    "2*r_unknown",
+			expectedError: "unknown identifier: r_unknown",
+		},
+		{
+			name:          "user function arguments",
+			expression:    "hyp(1)",
+			expectedError: "function hyp expects 2 arguments, got 1",
+		},
+		{
+			name:          "recursion depth",
+			expression:    "loop(1)",
+			expectedError: "function loop: maximum call depth 32 exceeded",
+		},
+	}
+
+	for _, testCase := range testCasesFail {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Calc(context.Background(), testCase.expression, env)
+			if err == nil {
+				t.Fatalf("fail case %s returns no error", testCase.expression)
+			}
+			if err.Error() != testCase.expectedError {
+				t.Fatalf("%q should be equal %q", err.Error(), testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestLoadConstants(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "empty", config: ""},
+		{name: "several", config: "r_earth=6371000, g = 9.80665"},
+		{name: "no value", config: "r_earth", wantErr: true},
+		{name: "bad value", config: "r_earth=abc", wantErr: true},
+		{name: "bad name", config: "1r=2", wantErr: true},
+		{name: "function name", config: "sqrt=2", wantErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := LoadConstants(testCase.config)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("LoadConstants(%q) error = %v, wantErr %v", testCase.config, err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	testCases := []struct {
+		name         string
+		expression   string
+		expectedName string
+		wantErr      bool
+	}{
+		{name: "expression", expression: "3*4"},
+		{name: "assignment", expression: "x = 3*4", expectedName: "x"},
+		{name: "invalid name", expression: "x+1 = 3", wantErr: true},
+		{name: "constant", expression: "pi = 3", wantErr: true},
+		{name: "function", expression: "sqrt = 3", wantErr: true},
+		{name: "empty body", expression: "x = ", wantErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			name, _, err := parseStatement(testCase.expression)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("parseStatement(%q) error = %v, wantErr %v", testCase.expression, err, testCase.wantErr)
+			}
+			if name != testCase.expectedName {
+				t.Fatalf("parseStatement(%q) name = %q; want %q", testCase.expression, name, testCase.expectedName)
+			}
+		})
+	}
+}
+
+func TestParseFunction(t *testing.T) {
+	testCases := []struct {
+		name       string
+		definition string
+		expected   UserFunction
+		wantErr    bool
+	}{
+		{name: "one parameter", definition: "vat(x) = x*1.2", expected: UserFunction{Params: []string{"x"}, Body: "x*1.2"}},
+		{name: "several parameters", definition: "hyp(a, b) = sqrt(a*a+b*b)", expected: UserFunction{Params: []string{"a", "b"}, Body: "sqrt(a*a+b*b)"}},
+		{name: "recursive", definition: "f(n) = f(n-1)", expected: UserFunction{Params: []string{"n"}, Body: "f(n-1)"}},
+		{name: "no parameters", definition: "answer() = 42", expected: UserFunction{Body: "42"}},
+		{name: "not a definition", definition: "vat(x)", wantErr: true},
+		{name: "built-in", definition: "sqrt(x) = x", wantErr: true},
+		{name: "duplicate parameter", definition: "f(x, x) = x", wantErr: true},
+		{name: "parameter shadows function", definition: "f(sqrt) = sqrt*2", wantErr: true},
+		{name: "parameter shadows constant", definition: "area(pi, r) = pi*r^2", wantErr: true},
+		{name: "unknown identifier", definition: "f(x) = x*y", wantErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			name, f, err := ParseFunction(testCase.definition)
+			if err == nil {
+				err = ValidateFunction(name, f, nil)
+			}
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("definition %q error = %v, wantErr %v", testCase.definition, err, testCase.wantErr)
+			}
+			if !testCase.wantErr && !reflect.DeepEqual(f, testCase.expected) {
+				t.Fatalf("definition %q parsed as %+v, want %+v", testCase.definition, f, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCalcExact(t *testing.T) {
+	err := godotenv.Load("../../cmd/.env")
+	if err != nil {
+		t.Fatal("Error loading .env file")
+	}
+	if err := LoadDelays(); err != nil {
+		t.Fatal(err)
+	}
+	env := &Env{
+		Variables: map[string]float64{"price": 0.1},
+		Functions: map[string]UserFunction{
+			"third": {Params: []string{"x"}, Body: "x/3"},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		mode       string
+		precision  int
+		expected   string
+		inexact    bool
+		wantErr    bool
+	}{
+		{name: "decimal sum", expression: "0.1+0.2", mode: ModeRational, expected: "3/10"},
+		{name: "third times three", expression: "1/3*3", mode: ModeRational, expected: "1"},
+		{name: "fraction", expression: "1/3", mode: ModeRational, expected: "1/3"},
+		{name: "decimal default precision", expression: "1/3", mode: ModeDecimal, expected: "0.33333333333333333333"},
+		{name: "decimal precision", expression: "2/3", mode: ModeDecimal, precision: 4, expected: "0.6667"},
+		{name: "integer power", expression: "(2/3)^3", mode: ModeRational, expected: "8/27"},
+		{name: "negative power", expression: "2^-2", mode: ModeRational, expected: "1/4"},
+		{name: "big power", expression: "2^100", mode: ModeRational, expected: "1267650600228229401496703205376"},
+		{name: "fractional power", expression: "4^0.5", mode: ModeRational, expected: "2", inexact: true},
+		{name: "exact functions", expression: "round(-5/2)+floor(7/2)+ceil(1/3)+abs(-1/4)", mode: ModeRational, expected: "5/4"},
+		{name: "min and max", expression: "max(1/3, 0.3)-min(1/3, 0.3)", mode: ModeRational, expected: "1/30"},
+		{name: "variable", expression: "price*3", mode: ModeRational, expected: "3/10"},
+		{name: "user function keeps fraction", expression: "third(1)*3", mode: ModeRational, expected: "1"},
+		{name: "float fallback", expression: "sqrt(4)", mode: ModeRational, expected: "2", inexact: true},
+		{name: "division by zero", expression: "1/(1/3-1/3)", mode: ModeRational, wantErr: true},
+		{name: "zero to negative power", expression: "0^-1", mode: ModeRational, wantErr: true},
+		{name: "domain", expression: "sqrt(-1)", mode: ModeRational, wantErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			env := *env
+			env.inexact = new(atomic.Bool)
+			val, err := CalcExact(context.Background(), testCase.expression, &env)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("expression %s error = %v, wantErr %v", testCase.expression, err, testCase.wantErr)
+			}
+			if testCase.wantErr {
+				return
+			}
+			if got := FormatExact(val, testCase.mode, testCase.precision); got != testCase.expected {
+				t.Fatalf("%s should be equal %s, got %s", testCase.expression, testCase.expected, got)
+			}
+			if env.Inexact() != testCase.inexact {
+				t.Fatalf("%s inexact = %v, want %v", testCase.expression, env.Inexact(), testCase.inexact)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		expected   SyntaxError
+	}{
+		{
+			name:       "closing bracket first",
+			expression: ")1(",
+			expected:   SyntaxError{Err: ErrUnbalancedParens, Line: 1, Column: 1, Token: ")", Expected: `number, name, "-" or "("`, Message: `unexpected ")", expected number, name, "-" or "("`},
+		},
+		{
+			name:       "two operators",
+			expression: "2 + * 3",
+			expected:   SyntaxError{Err: ErrSyntax, Line: 1, Column: 5, Token: "*", Expected: `number, name, "-" or "("`, Message: `unexpected "*", expected number, name, "-" or "("`},
+		},
+		{
+			name:       "unclosed bracket",
+			expression: "(1+2",
+			expected:   SyntaxError{Err: ErrUnbalancedParens, Line: 1, Column: 5, Expected: `operator or ")"`, Message: `unexpected end of expression, expected operator or ")"`},
+		},
+		{
+			name:       "extra closing bracket",
+			expression: "(1+2))",
+			expected:   SyntaxError{Err: ErrUnbalancedParens, Line: 1, Column: 6, Token: ")", Expected: "operator or end of expression", Message: `unexpected ")", expected operator or end of expression`},
+		},
+		{
+			name:       "missing operator",
+			expression: "1 2",
+			expected:   SyntaxError{Err: ErrSyntax, Line: 1, Column: 3, Token: "2", Expected: "operator or end of expression", Message: `unexpected "2", expected operator or end of expression`},
+		},
+		{
+			name:       "second line",
+			expression: "1 +\n  2 $ 3",
+			expected:   SyntaxError{Err: ErrUnknownToken, Line: 2, Column: 5, Token: "$", Expected: "number, name, operator or bracket", Message: `unexpected symbol "$"`},
+		},
+		{
+			name:       "malformed number",
+			expression: "1+2..3",
+			expected:   SyntaxError{Err: ErrUnknownToken, Line: 1, Column: 3, Token: "2..3", Expected: "number", Message: `malformed number "2..3"`},
+		},
+		{
+			name:       "unfinished arguments",
+			expression: "max(1,",
+			expected:   SyntaxError{Err: ErrUnbalancedParens, Line: 1, Column: 7, Expected: `number, name, "-" or "("`, Message: `unexpected end of expression, expected number, name, "-" or "("`},
+		},
+		{
+			name:       "missing comma",
+			expression: "max(1 2)",
+			expected:   SyntaxError{Err: ErrSyntax, Line: 1, Column: 7, Token: "2", Expected: `operator, "," or ")"`, Message: `unexpected "2", expected operator, "," or ")"`},
+		},
+		{
+			name:       "unknown identifier",
+			expression: "2 * r_unknown",
+			expected:   SyntaxError{Err: ErrUnknownToken, Line: 1, Column: 5, Token: "r_unknown", Expected: "variable or constant", Message: "unknown identifier: r_unknown"},
+		},
+		{
+			name:       "assignment inside expression",
+			expression: "1 + x = 2",
+			expected:   SyntaxError{Err: ErrSyntax, Line: 1, Column: 7, Token: "=", Expected: "operator or end of expression", Message: `unexpected "=", expected operator or end of expression`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Calc(context.Background(), testCase.expression, nil)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expression %q returns %v, want SyntaxError", testCase.expression, err)
+			}
+			if *syntaxErr != testCase.expected {
+				t.Fatalf("expression %q returns %+v, want %+v", testCase.expression, *syntaxErr, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{name: "expression", expression: "2+2*2"},
+		{name: "assignment", expression: "x = (1+2)*3"},
+		{name: "unknown names are checked later", expression: "foo(y)"},
+		{name: "assignment to constant", expression: "pi = 3", wantErr: true},
+		{name: "broken body", expression: "x = 1 +", wantErr: true},
+		{name: "brackets", expression: ")1(", wantErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Check(testCase.expression)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Check(%q) error = %v, wantErr %v", testCase.expression, err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxNesting(t *testing.T) {
+	deep := MaxNesting + 1
+	testCases := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{name: "brackets at the limit", expression: strings.Repeat("(", MaxNesting) + "1" + strings.Repeat(")", MaxNesting)},
+		{name: "brackets", expression: strings.Repeat("(", deep) + "1" + strings.Repeat(")", deep), wantErr: true},
+		{name: "calls", expression: strings.Repeat("abs(", deep) + "1" + strings.Repeat(")", deep), wantErr: true},
+		{name: "unary minus", expression: strings.Repeat("-", deep) + "1", wantErr: true},
+		{name: "powers", expression: strings.Repeat("2^", deep) + "1", wantErr: true},
+		{name: "long chain", expression: "1" + strings.Repeat("+1", deep), wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Check(testCase.expression)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Check error = %v, wantErr %v", err, testCase.wantErr)
+			}
+			var syntaxErr *SyntaxError
+			if testCase.wantErr && (!errors.As(err, &syntaxErr) || ErrorCode(err) != CodeBudget) {
+				t.Fatalf("Check error = %v (%s); want positioned %s", err, ErrorCode(err), CodeBudget)
+			}
+		})
+	}
+	// тело функции разбирается тем же парсером, поэтому глубокое определение отклоняется, а не роняет процесс
+	body := strings.Repeat("(", 10*MaxNesting) + "x" + strings.Repeat(")", 10*MaxNesting)
+	if err := ValidateFunction("f", UserFunction{Params: []string{"x"}, Body: body}, nil); ErrorCode(err) != CodeBudget {
+		t.Fatalf("ValidateFunction = %v; want %s", err, CodeBudget)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	err := godotenv.Load("../../cmd/.env")
+	if err != nil {
+		t.Fatal("Error loading .env file")
+	}
+	if err := LoadDelays(); err != nil {
+		t.Fatal(err)
+	}
+	env := &Env{Functions: map[string]UserFunction{"loop": {Params: []string{"n"}, Body: "loop(n)"}}}
+
+	testCases := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{name: "division by zero", expression: "1/(2-2)", expected: CodeDivisionByZero},
+		{name: "zero to negative power", expression: "0^-1", expected: CodeDivisionByZero},
+		{name: "unbalanced", expression: "(1+2", expected: CodeUnbalancedParens},
+		{name: "extra bracket", expression: ")1(", expected: CodeUnbalancedParens},
+		{name: "unknown symbol", expression: "1 $ 2", expected: CodeUnknownToken},
+		{name: "unknown identifier", expression: "nope+1", expected: CodeUnknownToken},
+		{name: "unknown function", expression: "nope(1)", expected: CodeUnknownToken},
+		{name: "syntax", expression: "1 2", expected: CodeSyntax},
+		{name: "arity", expression: "sqrt(1, 2)", expected: CodeArity},
+		{name: "domain", expression: "ln(-1)", expected: CodeDomain},
+		{name: "negative root", expression: "(-8)^0.5", expected: CodeDomain},
+		{name: "overflow", expression: "10^300*10^300", expected: CodeOverflow},
+		{name: "function overflow", expression: "exp(1000)", expected: CodeOverflow},
+		{name: "recursion", expression: "loop(1)", expected: CodeOverflow},
+		{name: "empty", expression: "  ", expected: CodeEmptyExpression},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Calc(context.Background(), testCase.expression, env)
+			if code := ErrorCode(err); code != testCase.expected {
+				t.Fatalf("expression %q returns %v with code %q, want %q", testCase.expression, err, code, testCase.expected)
+			}
+		})
+	}
+
+	if code := ErrorCode(nil); code != "" {
+		t.Fatalf("ErrorCode(nil) = %q, want empty", code)
+	}
+	if code := ErrorCode(errors.New("database is locked")); code != CodeInternal {
+		t.Fatalf("ErrorCode of unknown error = %q, want %q", code, CodeInternal)
+	}
+	if _, err := CalcExact(context.Background(), "1/(1/3-1/3)", nil); !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("exact division by zero returns %v, want ErrDivisionByZero", err)
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	err := godotenv.Load("../../cmd/.env")
+	if err != nil {
+		t.Fatal("Error loading .env file")
+	}
+	if err := LoadDelays(); err != nil {
+		t.Fatal(err)
+	}
+	env := &Env{
+		Variables: map[string]float64{"x": 12},
+		Functions: map[string]UserFunction{
+			"hyp":    {Params: []string{"a", "b"}, Body: "sqrt(a*a+b*b)"},
+			"answer": {Body: "42"},
+		},
+	}
+	w := New(nil, nil)
+	w.pool.Resize(4)
+	defer w.pool.Resize(0)
+
+	testCases := []struct {
+		name       string
+		expression string
+		mode       string
+		expected   string
+		inexact    bool
+		wantErr    error
+	}{
+		{name: "number", expression: "7", expected: "7"},
+		{name: "variable", expression: "x", expected: "12"},
+		{name: "independent operations", expression: "(2*3)+(4*5)", expected: "26"},
+		{name: "chain", expression: "2+2*2-6/3", expected: "4"},
+		{name: "unary minus", expression: "-(2^3)*-x", expected: "96"},
+		{name: "functions", expression: "max(1, hyp(3, 4), 2)+answer()", expected: "47"},
+		{name: "rational", expression: "1/3+1/6", mode: ModeRational, expected: "1/2"},
+		{name: "rational fractional power", expression: "4^(1/2)+1/3", mode: ModeRational, expected: "7/3", inexact: true},
+		{name: "rational float function", expression: "hyp(3, 4)/3", mode: ModeRational, expected: "5/3", inexact: true},
+		{name: "division by zero", expression: "1+1/(2-2)", wantErr: ErrDivisionByZero},
+		{name: "unknown identifier", expression: "1+nope", wantErr: ErrUnknownToken},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, err := parse(testCase.expression)
+			if err != nil {
+				t.Fatalf("parse(%q) returns error: %v", testCase.expression, err)
+			}
+			env := *env
+			env.inexact = new(atomic.Bool)
+			nodes, err := buildGraph(root, &env)
+			var value string
+			if err == nil {
+				value, err = runGraph(context.Background(), nodes, &env, testCase.mode, w.tasks.Submit, nil)
+			}
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("expression %q returns error %v, want %v", testCase.expression, err, testCase.wantErr)
+			}
+			if value != testCase.expected {
+				t.Fatalf("expression %q = %q, want %q", testCase.expression, value, testCase.expected)
+			}
+			if env.Inexact() != testCase.inexact {
+				t.Fatalf("expression %q inexact = %v, want %v", testCase.expression, env.Inexact(), testCase.inexact)
+			}
+		})
+	}
+}
+
+// setDelay задаёт длительность операции на время теста.
+func setDelay(t *testing.T, operation string, duration time.Duration) {
+	delaysMu.Lock()
+	previous, ok := delays[operation]
+	delays[operation] = duration
+	delaysMu.Unlock()
+	t.Cleanup(func() {
+		delaysMu.Lock()
+		defer delaysMu.Unlock()
+		if ok {
+			delays[operation] = previous
+		} else {
+			delete(delays, operation)
+		}
+	})
+}
+
+func TestLoadDelays(t *testing.T) {
+	previous := delays
+	t.Cleanup(func() {
+		delaysMu.Lock()
+		defer delaysMu.Unlock()
+		delays = previous
+	})
+	for _, name := range delayVariables {
+		t.Setenv(name, "5")
+	}
+	t.Setenv("TIME_POWER_MS", "7")
+	if err := LoadDelays(); err != nil {
+		t.Fatalf("LoadDelays returns error: %v", err)
+	}
+	if delays["^"] != 7*time.Millisecond || delays["+"] != 5*time.Millisecond {
+		t.Fatalf("delays = %v", delays)
+	}
+	t.Setenv("TIME_DIVISIONS_MS", "-1")
+	if err := LoadDelays(); err == nil {
+		t.Fatal("LoadDelays accepts a negative delay")
+	}
+	if delays["/"] != 5*time.Millisecond {
+		t.Fatalf("failed LoadDelays changed delays: %v", delays)
+	}
+}
+
+func TestRunGraphParallel(t *testing.T) {
+	setDelay(t, "*", 100*time.Millisecond)
+	setDelay(t, "+", time.Millisecond)
+	w := New(nil, nil)
+	w.pool.Resize(4)
+	defer w.pool.Resize(0)
+
+	// четыре независимых умножения по 100 мс: последовательно это 400 мс,
+	// а по графу — одно умножение и три коротких сложения
+	root, err := parse("1*2 + 3*4 + 5*6 + 7*8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := buildGraph(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	value, err := runGraph(context.Background(), nodes, nil, ModeFloat, w.tasks.Submit, nil)
+	elapsed := time.Since(started)
+	if err != nil || value != "100" {
+		t.Fatalf("runGraph = %q, %v; want 100", value, err)
+	}
+	if elapsed > 250*time.Millisecond {
+		t.Fatalf("independent operations took %v, expected them to run in parallel", elapsed)
+	}
+}
+
+func TestRunGraphFailureDropsTasks(t *testing.T) {
+	d := NewDispatcher(DefaultLease)
+	root, err := parse("1*2 + 3*4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := buildGraph(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := make(chan error, 1)
+	go func() {
+		_, err := runGraph(context.Background(), nodes, nil, ModeFloat, d.Submit, nil)
+		failed <- err
+	}()
+
+	// первая операция завершается ошибкой, и выражение больше не вычисляется
+	leased, err := d.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Complete(leased.ID, leased.Lease, "", ErrDivisionByZero); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-failed; !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("runGraph = %v; want ErrDivisionByZero", err)
+	}
+	// вторая операция того же выражения осталась в очереди, но вычислителям не выдаётся
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if task, err := d.Acquire(ctx); err == nil {
+		t.Fatalf("Acquire = %+v; want the task of the failed expression to be dropped", task)
+	}
+}
+
+func TestDispatcherLease(t *testing.T) {
+	d := NewDispatcher(50 * time.Millisecond)
+	done := make(chan TaskResult, 1)
+	d.Submit(Task{ID: 7, Operation: "+", Arg1: "1", Arg2: "2", Mode: ModeFloat, done: done})
+
+	// первый вычислитель берёт задачу и пропадает
+	lost, err := d.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// после окончания аренды задача достаётся следующему
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	leased, err := d.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expired task was not reassigned: %v", err)
+	}
+	if leased.ID != lost.ID || leased.Lease == lost.Lease {
+		t.Fatalf("Acquire = %+v after %+v; want the same task with a new lease", leased, lost)
+	}
+
+	if err := d.Complete(lost.ID, lost.Lease, "3", nil); !errors.Is(err, ErrLeaseExpired) {
+		t.Fatalf("Complete with expired lease = %v; want ErrLeaseExpired", err)
+	}
+	if err := d.Complete(leased.ID, leased.Lease, "3", nil); err != nil {
+		t.Fatalf("Complete = %v", err)
+	}
+	if result := <-done; result.ID != 7 || result.Value != "3" {
+		t.Fatalf("result = %+v; want task 7 with value 3", result)
+	}
+	if err := d.Complete(leased.ID, leased.Lease, "3", nil); !errors.Is(err, ErrLeaseExpired) {
+		t.Fatalf("second Complete = %v; want ErrLeaseExpired", err)
+	}
+}
+
+func TestDispatcherAcquireCancel(t *testing.T) {
+	d := NewDispatcher(DefaultLease)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire on empty queue = %v; want context.DeadlineExceeded", err)
+	}
+}
+
+func TestDispatcherFairness(t *testing.T) {
+	d := NewDispatcher(DefaultLease)
+	// пользователь 1 успел поставить три задачи раньше, чем пользователи 2 и 3 — по одной
+	for i := 1; i <= 3; i++ {
+		d.Submit(Task{ID: i, Operation: "+", Arg1: "1", Arg2: "1", user: 1})
+	}
+	d.Submit(Task{ID: 4, Operation: "+", Arg1: "1", Arg2: "1", user: 2})
+	d.Submit(Task{ID: 5, Operation: "+", Arg1: "1", Arg2: "1", user: 3})
+
+	order := []int{}
+	for d.Queued() > 0 {
+		leased, err := d.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, leased.Task.ID)
+	}
+	if want := []int{1, 4, 5, 2, 3}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("tasks acquired in order %v; want %v", order, want)
+	}
+}
+
+func TestDispatcherPriority(t *testing.T) {
+	d := NewDispatcher(DefaultLease)
+	d.aging = time.Hour
+	d.Submit(Task{ID: 1, Operation: "+", Arg1: "1", Arg2: "1", priority: dto.PriorityRank(dto.PriorityBatch)})
+	d.Submit(Task{ID: 2, Operation: "+", Arg1: "1", Arg2: "1", priority: dto.PriorityRank(dto.PriorityNormal)})
+	d.Submit(Task{ID: 3, Operation: "+", Arg1: "1", Arg2: "1", priority: dto.PriorityRank(dto.PriorityInteractive)})
+	acquire := func() int {
+		leased, err := d.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leased.Task.ID
+	}
+	if id := acquire(); id != 3 {
+		t.Fatalf("first task = %d; want the interactive task 3", id)
+	}
+	if id := acquire(); id != 2 {
+		t.Fatalf("second task = %d; want the normal task 2", id)
+	}
+
+	// batch-задача ждёт дольше двух интервалов повышения и обгоняет новую normal
+	d.aging = 10 * time.Millisecond
+	time.Sleep(25 * time.Millisecond)
+	d.Submit(Task{ID: 4, Operation: "+", Arg1: "1", Arg2: "1", priority: dto.PriorityRank(dto.PriorityNormal)})
+	if id := acquire(); id != 1 {
+		t.Fatalf("third task = %d; want the aged batch task 1", id)
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	d := NewDispatcher(DefaultLease)
+	p := NewPool(d)
+	p.Resize(3)
+	if p.Size() != 3 {
+		t.Fatalf("Size = %d; want 3", p.Size())
+	}
+	done := make(chan TaskResult, 1)
+	d.Submit(Task{ID: 1, Operation: "+", Arg1: "1", Arg2: "2", Mode: ModeFloat, done: done})
+	if result := <-done; result.Value != "3" {
+		t.Fatalf("result = %+v; want 3", result)
+	}
+
+	// остановленные вычислители завершаются и больше не забирают задачи
+	p.Resize(0)
+	p.Wait()
+	d.Submit(Task{ID: 2, Operation: "+", Arg1: "1", Arg2: "2", Mode: ModeFloat, done: done})
+	time.Sleep(20 * time.Millisecond)
+	if d.Queued() != 1 {
+		t.Fatalf("Queued = %d; want the task to wait for a new executor", d.Queued())
+	}
+	p.Resize(1)
+	if result := <-done; result.ID != 2 {
+		t.Fatalf("result = %+v; want task 2", result)
+	}
+	p.Resize(0)
+	p.Wait()
+}
+
+func TestAutoscaleNext(t *testing.T) {
+	policy := Autoscale{Min: 1, Max: 8, TargetWait: 100 * time.Millisecond}
+	testCases := []struct {
+		name   string
+		size   int
+		queued int
+		wait   time.Duration
+		want   int
+	}{
+		{name: "grow", size: 2, queued: 10, wait: time.Second, want: 4},
+		{name: "grow from zero", size: 0, queued: 1, wait: time.Second, want: 1},
+		{name: "grow up to max", size: 6, queued: 10, wait: time.Second, want: 8},
+		{name: "keep", size: 3, queued: 10, wait: 60 * time.Millisecond, want: 3},
+		{name: "shrink", size: 3, queued: 0, wait: 0, want: 2},
+		{name: "shrink down to min", size: 1, queued: 0, wait: 0, want: 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := policy.next(testCase.size, testCase.queued, testCase.wait); got != testCase.want {
+				t.Fatalf("next(%d, %d, %s) = %d; want %d", testCase.size, testCase.queued, testCase.wait, got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestAutoscaleFromEnv(t *testing.T) {
+	t.Setenv("AUTOSCALE_MIN", "2")
+	t.Setenv("AUTOSCALE_MAX", "8")
+	policy, err := AutoscaleFromEnv()
+	if err != nil || policy.Min != 2 || policy.Max != 8 {
+		t.Fatalf("AutoscaleFromEnv() = %+v, %v; want min 2, max 8", policy, err)
+	}
+
+	t.Setenv("AUTOSCALE_MIN", "9")
+	if _, err := AutoscaleFromEnv(); err == nil {
+		t.Fatal("AutoscaleFromEnv() with AUTOSCALE_MIN > AUTOSCALE_MAX: want error")
+	}
+
+	// без AUTOSCALE_MAX пул не меняется сам, и AUTOSCALE_MIN ни на что не влияет
+	t.Setenv("AUTOSCALE_MAX", "")
+	if policy, err := AutoscaleFromEnv(); err != nil || policy.Enabled() {
+		t.Fatalf("AutoscaleFromEnv() = %+v, %v; want disabled policy", policy, err)
+	}
+}
+
+func TestErrorFromCode(t *testing.T) {
+	err := ErrorFromCode(CodeDivisionByZero, "Division by zero")
+	if !errors.Is(err, ErrDivisionByZero) || err.Error() != "Division by zero" {
+		t.Fatalf("ErrorFromCode = %v; want ErrDivisionByZero", err)
+	}
+	if err := ErrorFromCode("", ""); err != nil {
+		t.Fatalf("ErrorFromCode with empty code = %v; want nil", err)
+	}
+	if code := ErrorCode(ErrorFromCode(CodeInternal, "disk is full")); code != CodeInternal {
+		t.Fatalf("code of internal error = %s", code)
+	}
+}
+
+func TestWorkerRecover(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	storage.EXPECT().RecoverJobs(DefaultJobAttempts).Return(2, []dto.Expression{{ID: 3, UserID: 1, Expression: "1+1"}}, nil)
+	storage.EXPECT().FinishJob(dto.Expression{
+		ID:           3,
+		UserID:       1,
+		Expression:   "1+1",
+		Status:       "Ошибка: expression was interrupted 3 times",
+		Result:       -1,
+		ErrorCode:    CodeInterrupted,
+		ErrorMessage: "expression was interrupted 3 times",
+	}).Return(nil)
+
+	if err := New(storage, nil).recover(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerClaimCapacity(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	// без ожиданий мок падает на любом вызове: при занятых местах ClaimJob вызываться не должен
+	storage := mocks.NewMockStorage(c)
+	w := New(storage, nil)
+	w.maxRunning = 2
+	w.running[1] = running{}
+	w.running[2] = running{}
+	w.claim()
+}
+
+func TestWorkerHoldClaims(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	w := New(mocks.NewMockStorage(c), nil)
+	// мест нет, поэтому claim, дождавшись своей очереди, сразу вернётся, не вызывая ClaimJob
+	w.maxRunning = 0
+	release := w.HoldClaims()
+	claimed := make(chan struct{})
+	go func() {
+		w.claim()
+		close(claimed)
+	}()
+	select {
+	case <-claimed:
+		t.Fatal("claim ran while an expression was being accepted")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	<-claimed
+
+	var none *Worker
+	none.HoldClaims()()
+}
+
+func TestReferences(t *testing.T) {
+	references, err := References("x = #42 * 1.2 + $prev - #42 / #7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"#42", PrevReference, "#7"}; !slices.Equal(references, want) {
+		t.Fatalf("References = %v; want %v", references, want)
+	}
+	if id, ok := ReferenceID("#42"); !ok || id != 42 {
+		t.Fatalf("ReferenceID(#42) = %d, %v; want 42", id, ok)
+	}
+	if _, ok := ReferenceID(PrevReference); ok {
+		t.Fatal("ReferenceID($prev) is ok; want false")
+	}
+	var syntaxErr *SyntaxError
+	if _, err := References("$next + 1"); !errors.As(err, &syntaxErr) || syntaxErr.Token != "$next" {
+		t.Fatalf("References($next) = %v; want unknown reference", err)
+	}
+}
+
+func TestWorkerReferences(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	w := New(storage, nil)
+	storage.EXPECT().GetExpression(1).Return(dto.Expression{ID: 1, UserID: 1, Status: "Ok", Result: 1.0 / 3, Value: "1/3"}, true, nil).AnyTimes()
+	storage.EXPECT().GetExpression(2).Return(dto.Expression{ID: 2, UserID: 1, Status: "Ошибка: Division by zero", Result: -1, ErrorCode: CodeDivisionByZero, ErrorMessage: "Division by zero"}, true, nil).AnyTimes()
+	storage.EXPECT().GetExpression(3).Return(dto.Expression{ID: 3, UserID: 2, Status: "Ok", Result: 5}, true, nil).AnyTimes()
+	storage.EXPECT().GetExpression(4).Return(dto.Expression{ID: 4, UserID: 1, Status: StatusCancelled, ErrorCode: CodeCancelled}, true, nil).AnyTimes()
+	storage.EXPECT().GetExpression(6).Return(dto.Expression{ID: 6, UserID: 1, Status: "Ok", Result: 2, Mode: ModeRational, Value: "2", Inexact: true}, true, nil).AnyTimes()
+
+	// в точном режиме подставляется точная запись результата
+	env := NewEnv(nil, nil)
+	expression := dto.Expression{ID: 5, UserID: 1, Mode: ModeRational, References: map[string]int{"#1": 1, PrevReference: 1}}
+	if err := w.references(expression, env); err != nil {
+		t.Fatal(err)
+	}
+	if literal, _ := env.literal("#1"); literal != "1/3" {
+		t.Fatalf("#1 = %s; want 1/3", literal)
+	}
+	if env.Inexact() {
+		t.Fatal("exact references made the result inexact")
+	}
+	// неточный результат зависимости делает неточным и зависимое выражение
+	env = NewEnv(nil, nil)
+	if err := w.references(dto.Expression{ID: 7, UserID: 1, Mode: ModeRational, References: map[string]int{"#6": 6}}, env); err != nil {
+		t.Fatal(err)
+	}
+	if !env.Inexact() {
+		t.Fatal("reference to an inexact result is not marked inexact")
+	}
+	env = NewEnv(nil, nil)
+	expression.Mode = ""
+	if err := w.references(expression, env); err != nil {
+		t.Fatal(err)
+	}
+	if literal, _ := env.literal(PrevReference); literal != "0.3333333333333333" {
+		t.Fatalf("$prev = %s; want 0.3333333333333333", literal)
+	}
+
+	testCases := []struct {
+		name       string
+		references map[string]int
+		message    string
+	}{
+		{name: "failed", references: map[string]int{"#2": 2}, message: "#2: expression 2 failed: Division by zero"},
+		{name: "other user", references: map[string]int{"#3": 3}, message: "#3: expression not found"},
+		{name: "cancelled", references: map[string]int{PrevReference: 4}, message: "$prev: expression 4 was cancelled"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := w.references(dto.Expression{ID: 5, UserID: 1, References: testCase.references}, NewEnv(nil, nil))
+			if ErrorCode(err) != CodeDependency || err.Error() != testCase.message {
+				t.Fatalf("references = %v (%s); want %q", err, ErrorCode(err), testCase.message)
+			}
+		})
+	}
+}
+
+func TestCalcCancelled(t *testing.T) {
+	setDelay(t, "+", 10*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	started := time.Now()
+	_, err := Calc(ctx, "1+2", nil)
+	if !errors.Is(err, context.Canceled) || ErrorCode(err) != CodeCancelled {
+		t.Fatalf("Calc = %v; want context.Canceled", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("cancelled operation took %v", elapsed)
+	}
+}
+
+func TestWorkerCancel(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	w := New(storage, nil)
+
+	// ожидающее выражение просто убирается из очереди
+	queued := dto.Expression{ID: 1, UserID: 1, Expression: "1+1", Status: "Выражение принято для вычисления"}
+	storage.EXPECT().CancelJob(dto.Expression{
+		ID:           1,
+		UserID:       1,
+		Expression:   "1+1",
+		Status:       StatusCancelled,
+		Result:       -1,
+		ErrorCode:    CodeCancelled,
+		ErrorMessage: "context canceled",
+	}).Return(true, nil)
+	if cancelled, err := w.Cancel(queued); !cancelled || err != nil {
+		t.Fatalf("Cancel queued = %v, %v; want true", cancelled, err)
+	}
+
+	// вычисляемое выражение прерывается: вычислителей нет, и задача 2*3 так и не выполнится
+	finished := make(chan dto.Expression, 1)
+	storage.EXPECT().ClaimJob(w.jobLease, w.userLimit).Return(dto.Expression{ID: 2, UserID: 1, Expression: "2*3"}, true, nil)
+	storage.EXPECT().ClaimJob(w.jobLease, w.userLimit).Return(dto.Expression{}, false, nil)
+	storage.EXPECT().GetVariables(1).Return(nil, nil)
+	storage.EXPECT().GetFunctions(1).Return(nil, nil)
+	storage.EXPECT().FinishJob(gomock.Any()).DoAndReturn(func(expression dto.Expression) error {
+		finished <- expression
+		return nil
+	})
+	w.claim()
+	if cancelled, err := w.Cancel(dto.Expression{ID: 2, UserID: 1, Expression: "2*3"}); !cancelled || err != nil {
+		t.Fatalf("Cancel running = %v, %v; want true", cancelled, err)
+	}
+	expression := <-finished
+	if expression.Status != StatusCancelled || expression.ErrorCode != CodeCancelled {
+		t.Fatalf("cancelled expression = %+v", expression)
+	}
+	if w.tasks.Queued() != 1 {
+		t.Fatalf("Queued = %d; want the abandoned task to stay until a worker skips it", w.tasks.Queued())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := w.tasks.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v; want the cancelled task to be skipped", err)
+	}
+}
+
+func TestWorkerShutdown(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	w := New(storage, nil)
+
+	// вычислителей нет, поэтому выражение не успевает вычислиться и возвращается в очередь
+	released := make(chan int, 1)
+	storage.EXPECT().ClaimJob(w.jobLease, w.userLimit).Return(dto.Expression{ID: 1, UserID: 1, Expression: "2*3"}, true, nil)
+	storage.EXPECT().ClaimJob(w.jobLease, w.userLimit).Return(dto.Expression{}, false, nil)
+	storage.EXPECT().GetVariables(1).Return(nil, nil)
+	storage.EXPECT().GetFunctions(1).Return(nil, nil)
+	storage.EXPECT().ReleaseJob(1).DoAndReturn(func(id int) error {
+		released <- id
+		return nil
+	})
+	w.claim()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	w.Shutdown(ctx)
+	if id := <-released; id != 1 {
+		t.Fatalf("released expression %d; want 1", id)
+	}
+
+	// после остановки выражения больше не забираются из очереди: ClaimJob не вызывается
+	w.claim()
+	w.pool.Resize(2)
+	if w.pool.Size() != 0 {
+		t.Fatalf("pool size after Shutdown = %d; want 0", w.pool.Size())
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	limits := Limits{MaxLength: 20, MaxDepth: 4, MaxOperations: 3}
+	testCases := []struct {
+		name       string
+		expression string
+		err        string
+		kind       error
+	}{
+		{name: "ok", expression: "x = (1+2)*3"},
+		{name: "too long", expression: "1+1+1+1+1+1+1+1+1+1+1", err: "expression is too long: 21 characters, limit is 20", kind: ErrBudget},
+		{name: "too deep", expression: "-(-(-(-1)))", err: "expression is too deep: more than 4 levels", kind: ErrBudget},
+		{name: "too many operations", expression: "(1+2)*(3+4)-5", err: "expression has too many operations: more than 3", kind: ErrBudget},
+		{name: "syntax error", expression: "1+", err: "unexpected end of expression, expected " + expectOperand, kind: ErrSyntax},
+		{name: "unlimited", expression: "1+2+3+4+5+6+7+8+9+10+11+12"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			checkLimits := limits
+			if testCase.name == "unlimited" {
+				checkLimits = Limits{}
+			}
+			err := checkLimits.Check(testCase.expression)
+			if testCase.err == "" {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v", testCase.expression, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != testCase.err || !errors.Is(err, testCase.kind) {
+				t.Fatalf("Check(%q) = %v; want %q", testCase.expression, err, testCase.err)
+			}
+		})
+	}
+}
+
+func TestLimitsCheckStopsEarly(t *testing.T) {
+	// длина не ограничена, а синтаксическая ошибка в самом конце: если бы ограничения проверялись
+	// после разбора, Check вернул бы её, а не превышение числа операций
+	expression := strings.Repeat("1+", 1000) + ")"
+	err := Limits{MaxOperations: 10}.Check(expression)
+	if !errors.Is(err, ErrBudget) {
+		t.Fatalf("Check = %v; want ErrBudget before the syntax error", err)
+	}
+	err = Limits{MaxDepth: 10}.Check(expression)
+	if !errors.Is(err, ErrBudget) {
+		t.Fatalf("Check = %v; want ErrBudget before the syntax error", err)
+	}
+}
+
+func TestOperationBudget(t *testing.T) {
+	setDelay(t, "+", 0)
+	// каждый уровень удваивает число сложений: f5(1) — это 31 сложение и 31 вызов
+	env := NewEnv(nil, []dto.Function{
+		{Name: "f1", Params: []string{"x"}, Body: "x+x"},
+		{Name: "f2", Params: []string{"x"}, Body: "f1(x)+f1(x)"},
+		{Name: "f3", Params: []string{"x"}, Body: "f2(x)+f2(x)"},
+		{Name: "f4", Params: []string{"x"}, Body: "f3(x)+f3(x)"},
+		{Name: "f5", Params: []string{"x"}, Body: "f4(x)+f4(x)"},
+	})
+	env.limitOperations(100)
+	if result, err := Calc(context.Background(), "f5(1)", env); err != nil || result != 32 {
+		t.Fatalf("Calc within budget = %v, %v; want 32", result, err)
+	}
+	env.limitOperations(50)
+	_, err := Calc(context.Background(), "f5(1)", env)
+	if !errors.Is(err, ErrBudget) || ErrorCode(err) != CodeBudget {
+		t.Fatalf("Calc over budget = %v; want ErrBudget", err)
+	}
+}
+
+func TestCalcTimeout(t *testing.T) {
+	setDelay(t, "*", 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Calc(ctx, "2*3", nil)
+	if !errors.Is(err, ErrTimeout) || ErrorCode(err) != CodeTimeout {
+		t.Fatalf("Calc = %v; want ErrTimeout", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	events := NewEvents(2)
+	mine := func(event Event) bool { return event.Expression.UserID == 1 }
+	events.Publish(Event{Type: EventQueued, Expression: dto.Expression{ID: 1, UserID: 1}})
+	events.Publish(Event{Type: EventQueued, Expression: dto.Expression{ID: 2, UserID: 2}})
+	events.Publish(Event{Type: EventRunning, Expression: dto.Expression{ID: 1, UserID: 1}})
+
+	// из истории в два события остался только running первого пользователя
+	replay, unsubscribe := events.Subscribe(mine, 0)
+	first := <-replay
+	if first.Type != EventRunning || first.Expression.ID != 1 {
+		t.Fatalf("replayed %+v; want running of expression 1", first)
+	}
+	events.Publish(Event{Type: EventQueued, Expression: dto.Expression{ID: 3, UserID: 2}})
+	events.Publish(Event{Type: EventDone, Expression: dto.Expression{ID: 1, UserID: 1, Status: "Ok"}})
+	next := <-replay
+	if next.Type != EventDone || next.ID <= first.ID || !next.Final() {
+		t.Fatalf("next event %+v; want final done after %d", next, first.ID)
+	}
+
+	// переподключение после first получает только пропущенное
+	resumed, stop := events.Subscribe(mine, first.ID)
+	if event := <-resumed; event.ID != next.ID {
+		t.Fatalf("resumed with %+v; want event %d", event, next.ID)
+	}
+	stop()
+	unsubscribe()
+	if _, ok := <-replay; ok {
+		t.Fatal("channel is still open after unsubscribe")
+	}
+
+	// отставший подписчик отключается, а не задерживает публикацию
+	slow, stop := events.Subscribe(mine, next.ID)
+	defer stop()
+	for i := 0; i <= subscriberBuffer; i++ {
+		events.Publish(Event{Type: EventProgress, Expression: dto.Expression{ID: 4, UserID: 1}})
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Fatalf("slow subscriber received %d events; want %d before disconnect", received, subscriberBuffer)
+	}
+}
+
+func TestEventsProgressNotKept(t *testing.T) {
+	events := NewEvents(2)
+	all := func(Event) bool { return true }
+	live, unsubscribe := events.Subscribe(all, 0)
+	defer unsubscribe()
+	events.Publish(Event{Type: EventRunning, Expression: dto.Expression{ID: 1}})
+	for i := 0; i < 10; i++ {
+		events.Publish(Event{Type: EventProgress, Expression: dto.Expression{ID: 1}, Done: i, Total: 10})
+	}
+	// пакет выражений ставится в очередь разом
+	for i := 0; i < 10; i++ {
+		events.Publish(Event{Type: EventQueued, Expression: dto.Expression{ID: 2 + i}})
+	}
+	// подписчики получают progress и queued сразу
+	for i := 0; i < 21; i++ {
+		<-live
+	}
+
+	// но они не вытесняют из истории running, и события не считаются потерянными
+	replay, stop := events.Subscribe(all, 0)
+	defer stop()
+	if event := <-replay; event.Type != EventRunning {
+		t.Fatalf("replayed %+v; want running", event)
+	}
+	select {
+	case event := <-replay:
+		t.Fatalf("replayed %+v; progress and queued must not be kept", event)
+	default:
+	}
+	if !events.Covers(events.history[0].ID - 1) {
+		t.Fatal("progress events are reported as dropped")
+	}
+}
+
+func TestEventsCovers(t *testing.T) {
+	events := NewEvents(2)
+	if events.Covers(1) {
+		t.Fatal("events of a previous run are covered")
+	}
+	for i := 0; i < 3; i++ {
+		events.Publish(Event{Type: EventRunning})
+	}
+	// первое из трёх событий уже вытеснено из истории
+	kept := events.history[0].ID
+	if events.Covers(kept - 2) {
+		t.Fatalf("Covers(%d) = true; the first event is dropped", kept-2)
+	}
+	if !events.Covers(kept-1) || !events.Covers(kept) {
+		t.Fatalf("Covers = false for events after %d", kept-1)
+	}
 }
@@ -0,0 +1,172 @@
+package calc
+
+import (
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"sync"
+	"time"
+)
+
+// StatusAccepted — статус выражения, которое ждёт вычисления или вычисляется.
+const StatusAccepted = dto.StatusAccepted
+
+// Типы событий выражения.
+const (
+	EventQueued    = "queued"
+	EventRunning   = "running"
+	EventProgress  = "progress"
+	EventDone      = "done"
+	EventError     = "error"
+	EventCancelled = "cancelled"
+	// EventReset — события после запрошенного не сохранились, состояние выражений нужно перечитать
+	EventReset = "reset"
+)
+
+// DefaultEventHistory — сколько последних событий хранится для подписчиков,
+// которые переподключаются и просят события после уже полученного.
+const DefaultEventHistory = 1024
+
+// subscriberBuffer — сколько событий может ждать медленного подписчика.
+// Подписчик, который отстал сильнее, отключается, чтобы не задерживать остальных.
+const subscriberBuffer = 64
+
+// Event — изменение состояния выражения.
+type Event struct {
+	// ID растёт с каждым событием; по нему переподключившийся подписчик получает пропущенное
+	ID         int64
+	Type       string
+	Expression dto.Expression
+	// Done и Total — сколько операций выражения выполнено и сколько всего, для событий progress
+	Done  int
+	Total int
+}
+
+// EventType возвращает тип события, которым закончилось бы выражение в его текущем статусе.
+func EventType(expression dto.Expression) string {
+	switch expression.Status {
+	case StatusAccepted:
+		return EventQueued
+	case StatusOK:
+		return EventDone
+	case StatusCancelled:
+		return EventCancelled
+	}
+	return EventError
+}
+
+// Final сообщает, что после события с выражением больше ничего не произойдёт.
+func (e Event) Final() bool {
+	switch e.Type {
+	case EventDone, EventError, EventCancelled:
+		return true
+	}
+	return false
+}
+
+type subscriber struct {
+	filter func(Event) bool
+	events chan Event
+}
+
+// Events — издатель событий выражений внутри процесса. Вычислители публикуют в него
+// изменения после записи в базу, а подписчики получают только подходящие им события.
+// Все методы допускают nil: тогда события никуда не отправляются.
+type Events struct {
+	mu      sync.Mutex
+	nextID  int64
+	history []Event
+	limit   int
+	// dropped — ID последнего события, которого уже нет в истории
+	dropped int64
+	subs    map[*subscriber]struct{}
+}
+
+func NewEvents(history int) *Events {
+	start := time.Now().UnixMicro()
+	return &Events{
+		// номера продолжают расти и после перезапуска, поэтому Last-Event-ID
+		// из прошлого запуска не отрезает новые события
+		nextID: start,
+		// события прошлого запуска считаются потерянными
+		dropped: start,
+		limit:   history,
+		subs:    make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish отправляет событие подписчикам и сохраняет его в истории.
+// События progress и queued в историю не попадают: progress приходят на каждую операцию,
+// а queued — на каждое выражение пакета CalcBatch, и те и другие быстро вытеснили бы
+// остальные события. Переподключившийся подписчик всё равно получит следующее событие выражения.
+func (ev *Events) Publish(event Event) {
+	if ev == nil {
+		return
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.nextID++
+	event.ID = ev.nextID
+	if event.Type != EventProgress && event.Type != EventQueued {
+		ev.history = append(ev.history, event)
+	}
+	if len(ev.history) > ev.limit {
+		trimmed := len(ev.history) - ev.limit
+		ev.dropped = ev.history[trimmed-1].ID
+		ev.history = ev.history[trimmed:]
+	}
+	for sub := range ev.subs {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(ev.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe подписывает на события, для которых filter возвращает true. Сначала приходят
+// сохранённые события с ID больше after, затем новые. Канал закрывается вызовом
+// возвращённой функции или если подписчик слишком отстал.
+func (ev *Events) Subscribe(filter func(Event) bool, after int64) (<-chan Event, func()) {
+	if ev == nil {
+		events := make(chan Event)
+		return events, func() {}
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	var missed []Event
+	for _, event := range ev.history {
+		if event.ID > after && filter(event) {
+			missed = append(missed, event)
+		}
+	}
+	sub := &subscriber{filter: filter, events: make(chan Event, len(missed)+subscriberBuffer)}
+	for _, event := range missed {
+		sub.events <- event
+	}
+	ev.subs[sub] = struct{}{}
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			ev.mu.Lock()
+			defer ev.mu.Unlock()
+			if _, ok := ev.subs[sub]; ok {
+				delete(ev.subs, sub)
+				close(sub.events)
+			}
+		})
+	}
+}
+
+// Covers сообщает, сохранились ли в истории все события с ID больше after.
+// Если нет, подписчик с этим after пропустил часть изменений.
+func (ev *Events) Covers(after int64) bool {
+	if ev == nil {
+		return false
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	return after >= ev.dropped
+}
@@ -0,0 +1,262 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Режимы вычисления выражения.
+const (
+	// ModeFloat — обычная арифметика float64.
+	ModeFloat = "float"
+	// ModeRational — точная арифметика дробей, результат записывается дробью: "1/3".
+	ModeRational = "rational"
+	// ModeDecimal — точная арифметика дробей, результат записывается десятичной дробью
+	// с заданным числом знаков после запятой.
+	ModeDecimal = "decimal"
+)
+
+// DefaultPrecision — число знаков после запятой в режиме decimal, если точность не задана.
+const DefaultPrecision = 20
+
+// MaxPrecision ограничивает число знаков после запятой в режиме decimal.
+const MaxPrecision = 1000
+
+// maxExactExponent ограничивает показатель степени, которую можно вычислить точно:
+// дробь 2^1000000 уже занимает сотни килобайт.
+const maxExactExponent = 4096
+
+// maxExactBits ограничивает размер числителя и знаменателя результата степени.
+const maxExactBits = 1 << 20
+
+// ValidateMode проверяет режим вычисления и точность. Пустой режим означает ModeFloat.
+func ValidateMode(mode string, precision int) error {
+	switch mode {
+	case "", ModeFloat, ModeRational, ModeDecimal:
+	default:
+		return fmt.Errorf("unknown mode: %s", mode)
+	}
+	if precision < 0 || precision > MaxPrecision {
+		return fmt.Errorf("precision must be from 0 to %d", MaxPrecision)
+	}
+	return nil
+}
+
+// IsExact сообщает, вычисляется ли выражение в режиме mode без потери точности.
+func IsExact(mode string) bool {
+	return mode == ModeRational || mode == ModeDecimal
+}
+
+// FormatExact записывает точный результат так, как требует режим mode.
+func FormatExact(value *big.Rat, mode string, precision int) string {
+	if mode == ModeDecimal {
+		if precision == 0 {
+			precision = DefaultPrecision
+		}
+		return value.FloatString(precision)
+	}
+	return value.RatString()
+}
+
+// CalcExact вычисляет выражение в окружении env без потери точности.
+// Функции, у которых нет точного вычисления (sqrt, sin, ...), и дробные степени считаются в float64;
+// тогда env.Inexact() возвращает true.
+func CalcExact(ctx context.Context, expression string, env *Env) (*big.Rat, error) {
+	root, err := parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return calcTree[*big.Rat](ctx, root, env, ratArithmetic{})
+}
+
+type ratArithmetic struct{}
+
+func (ratArithmetic) parse(literal string) (*big.Rat, error) {
+	value, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return nil, errorf(ErrUnknownToken, "invalid number: %s", literal)
+	}
+	return value, nil
+}
+
+func (ratArithmetic) format(num *big.Rat) string {
+	return num.RatString()
+}
+
+func (ratArithmetic) operate(ctx context.Context, num1, num2 *big.Rat, operation string) (*big.Rat, error) {
+	if err := delay(ctx, operation); err != nil {
+		return nil, err
+	}
+	switch operation {
+	case "+":
+		return new(big.Rat).Add(num1, num2), nil
+	case "-":
+		return new(big.Rat).Sub(num1, num2), nil
+	case "*":
+		return new(big.Rat).Mul(num1, num2), nil
+	case "/":
+		if num2.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return new(big.Rat).Quo(num1, num2), nil
+	case "^":
+		return powRat(num1, num2)
+	}
+	return nil, fmt.Errorf("unknown operation: %s", operation)
+}
+
+func (ratArithmetic) approximate(num1, num2 *big.Rat, operation string) bool {
+	return operation == "^" && !exactExponent(num2)
+}
+
+func (ratArithmetic) negate(num *big.Rat) *big.Rat {
+	return new(big.Rat).Neg(num)
+}
+
+func (ratArithmetic) call(ctx context.Context, env *Env, name string, args []*big.Rat) (*big.Rat, error) {
+	if f, ok := env.userFunction(name); ok {
+		if err := env.checkArgs(name, len(args)); err != nil {
+			return nil, err
+		}
+		if env.depth >= MaxCallDepth {
+			return nil, errorf(ErrOverflow, "function %s: maximum call depth %d exceeded", name, MaxCallDepth)
+		}
+		return CalcExact(ctx, f.Body, env.exactChild(f.Params, args))
+	}
+	if err := checkArgs(name, len(args)); err != nil {
+		return nil, err
+	}
+	if f, ok := exactFunctions[name]; ok {
+		return f(args), nil
+	}
+	env.markInexact()
+	floatArgs := make([]float64, len(args))
+	for i, arg := range args {
+		floatArgs[i], _ = arg.Float64()
+	}
+	result, err := callFunction(name, floatArgs)
+	if err != nil {
+		return nil, err
+	}
+	return floatToRat(result)
+}
+
+// exactFunctions — встроенные функции, которые вычисляются без перехода к float64.
+var exactFunctions = map[string]func(args []*big.Rat) *big.Rat{
+	"abs": func(args []*big.Rat) *big.Rat {
+		return new(big.Rat).Abs(args[0])
+	},
+	"floor": func(args []*big.Rat) *big.Rat {
+		return new(big.Rat).SetInt(floorRat(args[0]))
+	},
+	"ceil": func(args []*big.Rat) *big.Rat {
+		return new(big.Rat).SetInt(new(big.Int).Neg(floorRat(new(big.Rat).Neg(args[0]))))
+	},
+	"round": func(args []*big.Rat) *big.Rat {
+		// как math.Round: половина округляется от нуля
+		half := new(big.Rat).SetFrac64(1, 2)
+		abs := new(big.Rat).Abs(args[0])
+		rounded := new(big.Rat).SetInt(floorRat(abs.Add(abs, half)))
+		if args[0].Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+		return rounded
+	},
+	"min": func(args []*big.Rat) *big.Rat {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if arg.Cmp(result) < 0 {
+				result = arg
+			}
+		}
+		return result
+	},
+	"max": func(args []*big.Rat) *big.Rat {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if arg.Cmp(result) > 0 {
+				result = arg
+			}
+		}
+		return result
+	},
+}
+
+func floorRat(value *big.Rat) *big.Int {
+	// Div в big.Int — евклидово деление, при положительном знаменателе это floor
+	return new(big.Int).Div(value.Num(), value.Denom())
+}
+
+// exactExponent сообщает, можно ли возвести в степень exponent без перехода к float64.
+func exactExponent(exponent *big.Rat) bool {
+	return exponent.IsInt() && exponent.Num().CmpAbs(big.NewInt(maxExactExponent)) <= 0
+}
+
+// powRat возводит в целую степень точно, а в дробную — через float64.
+func powRat(base, exponent *big.Rat) (*big.Rat, error) {
+	if !exactExponent(exponent) {
+		baseFloat, _ := base.Float64()
+		exponentFloat, _ := exponent.Float64()
+		return floatToRat(math.Pow(baseFloat, exponentFloat))
+	}
+	n := exponent.Num().Int64()
+	if n < 0 {
+		if base.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		base = new(big.Rat).Inv(base)
+		n = -n
+	}
+	if int64(max(base.Num().BitLen(), base.Denom().BitLen()))*n > maxExactBits {
+		return nil, errorf(ErrOverflow, "power is too large to compute exactly")
+	}
+	num := new(big.Int).Exp(base.Num(), big.NewInt(n), nil)
+	denom := new(big.Int).Exp(base.Denom(), big.NewInt(n), nil)
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+func floatToRat(value float64) (*big.Rat, error) {
+	if math.IsNaN(value) {
+		return nil, errorf(ErrDomain, "result is not a real number")
+	}
+	if math.IsInf(value, 0) {
+		return nil, errorf(ErrOverflow, "result is out of range")
+	}
+	// кратчайшая десятичная запись: sqrt(2) даёт 1.4142135623730951, а не двоичную дробь
+	result, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+	return result, nil
+}
+
+func (e *Env) exactChild(params []string, args []*big.Rat) *Env {
+	child := &Env{
+		Variables: e.Variables,
+		Functions: e.Functions,
+		exact:     make(map[string]*big.Rat, len(e.exact)+len(params)),
+		depth:     e.depth + 1,
+		budget:    e.budget,
+		inexact:   e.inexact,
+	}
+	for name, value := range e.exact {
+		child.exact[name] = value
+	}
+	for i, param := range params {
+		child.exact[param] = args[i]
+	}
+	return child
+}
+
+// markInexact отмечает, что часть точного вычисления выполнена в float64.
+func (e *Env) markInexact() {
+	if e != nil && e.inexact != nil {
+		e.inexact.Store(true)
+	}
+}
+
+// Inexact сообщает, что при точном вычислении в этом окружении часть операций выполнена в float64
+// и результат может отличаться от точного.
+func (e *Env) Inexact() bool {
+	return e != nil && e.inexact != nil && e.inexact.Load()
+}
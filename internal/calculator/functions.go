@@ -0,0 +1,93 @@
+package calc
+
+import (
+	"math"
+)
+
+// function описывает встроенную функцию калькулятора.
+// maxArgs < 0 означает, что функция принимает любое количество аргументов начиная с minArgs.
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(args []float64) (float64, error)
+}
+
+var functions = map[string]function{
+	"sqrt":  unary(math.Sqrt),
+	"exp":   unary(math.Exp),
+	"ln":    unary(math.Log),
+	"log10": unary(math.Log10),
+	"abs":   unary(math.Abs),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"asin":  unary(math.Asin),
+	"acos":  unary(math.Acos),
+	"atan":  unary(math.Atan),
+	"pow": {minArgs: 2, maxArgs: 2, call: func(args []float64) (float64, error) {
+		return math.Pow(args[0], args[1]), nil
+	}},
+	"log": {minArgs: 1, maxArgs: 2, call: func(args []float64) (float64, error) {
+		if len(args) == 1 {
+			return math.Log(args[0]), nil
+		}
+		return math.Log(args[0]) / math.Log(args[1]), nil
+	}},
+	"min": {minArgs: 1, maxArgs: -1, call: func(args []float64) (float64, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = math.Min(result, arg)
+		}
+		return result, nil
+	}},
+	"max": {minArgs: 1, maxArgs: -1, call: func(args []float64) (float64, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = math.Max(result, arg)
+		}
+		return result, nil
+	}},
+}
+
+func unary(f func(float64) float64) function {
+	return function{minArgs: 1, maxArgs: 1, call: func(args []float64) (float64, error) {
+		return f(args[0]), nil
+	}}
+}
+
+// checkArgs проверяет, что функция name может быть вызвана с count аргументами.
+func checkArgs(name string, count int) error {
+	f, ok := functions[name]
+	if !ok {
+		return errorf(ErrUnknownToken, "unknown function: %s", name)
+	}
+	switch {
+	case f.maxArgs < 0 && count < f.minArgs:
+		return errorf(ErrArity, "function %s expects at least %d arguments, got %d", name, f.minArgs, count)
+	case f.maxArgs >= 0 && f.minArgs == f.maxArgs && count != f.minArgs:
+		return errorf(ErrArity, "function %s expects %d arguments, got %d", name, f.minArgs, count)
+	case f.maxArgs >= 0 && (count < f.minArgs || count > f.maxArgs):
+		return errorf(ErrArity, "function %s expects from %d to %d arguments, got %d", name, f.minArgs, f.maxArgs, count)
+	}
+	return nil
+}
+
+func callFunction(name string, args []float64) (float64, error) {
+	if err := checkArgs(name, len(args)); err != nil {
+		return 0, err
+	}
+	result, err := functions[name].call(args)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(result) {
+		return 0, errorf(ErrDomain, "function %s: argument out of domain", name)
+	}
+	if math.IsInf(result, 0) {
+		return 0, errorf(ErrOverflow, "function %s: result is out of range", name)
+	}
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package calc
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// Task — одна бинарная операция выражения. Операнды и результат записаны текстом
+// в арифметике режима Mode, поэтому задачу можно выполнить отдельно от остального выражения.
+type Task struct {
+	ID        int
+	Operation string
+	Arg1      string
+	Arg2      string
+	Mode      string
+	// ctx — контекст выражения: задачи отменённого выражения не выдаются вычислителям
+	ctx context.Context
+	// user — владелец выражения, задачи пользователей выдаются по очереди
+	user int
+	// priority — уровень приоритета выражения, 0 — самый высокий
+	priority int
+	// queued — когда задача впервые поставлена в очередь, от этого момента считается повышение приоритета
+	queued time.Time
+	done   chan<- TaskResult
+}
+
+// context возвращает контекст выражения, которому принадлежит задача.
+func (t Task) context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// TaskResult — результат задачи с тем же ID.
+type TaskResult struct {
+	ID    int
+	Value string
+	Err   error
+}
+
+// ExecuteTask выполняет операцию задачи вместе с имитируемой задержкой TIME_*_MS.
+// Задержка прерывается отменой ctx.
+func ExecuteTask(ctx context.Context, task Task) (string, error) {
+	return textArithmeticFor(task.Mode).operate(ctx, task.Arg1, task.Arg2, task.Operation)
+}
+
+// textArithmetic — арифметика режима над числами в текстовой записи.
+type textArithmetic interface {
+	operate(ctx context.Context, num1, num2, operation string) (string, error)
+	approximate(num1, num2, operation string) bool
+	negate(num string) (string, error)
+	call(ctx context.Context, env *Env, name string, args []string) (string, error)
+}
+
+func textArithmeticFor(mode string) textArithmetic {
+	if IsExact(mode) {
+		return text[*big.Rat]{ratArithmetic{}}
+	}
+	return text[float64]{floatArithmetic{}}
+}
+
+type text[T any] struct {
+	arith arithmetic[T]
+}
+
+func (t text[T]) parse(literals ...string) ([]T, error) {
+	nums := make([]T, len(literals))
+	for i, literal := range literals {
+		num, err := t.arith.parse(literal)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = num
+	}
+	return nums, nil
+}
+
+func (t text[T]) operate(ctx context.Context, num1, num2, operation string) (string, error) {
+	nums, err := t.parse(num1, num2)
+	if err != nil {
+		return "", err
+	}
+	result, err := t.arith.operate(ctx, nums[0], nums[1], operation)
+	if err != nil {
+		return "", err
+	}
+	return t.arith.format(result), nil
+}
+
+func (t text[T]) approximate(num1, num2, operation string) bool {
+	nums, err := t.parse(num1, num2)
+	return err == nil && t.arith.approximate(nums[0], nums[1], operation)
+}
+
+func (t text[T]) negate(num string) (string, error) {
+	nums, err := t.parse(num)
+	if err != nil {
+		return "", err
+	}
+	return t.arith.format(t.arith.negate(nums[0])), nil
+}
+
+func (t text[T]) call(ctx context.Context, env *Env, name string, args []string) (string, error) {
+	nums, err := t.parse(args...)
+	if err != nil {
+		return "", err
+	}
+	result, err := t.arith.call(ctx, env, name, nums)
+	if err != nil {
+		return "", err
+	}
+	return t.arith.format(result), nil
+}
+
+// graphNode — вершина графа зависимостей выражения.
+// У листа operation пустая и value известно сразу. Бинарные операции становятся задачами,
+// а унарный минус и вызовы функций вычисляются на месте, как только готовы их аргументы.
+type graphNode struct {
+	operation string
+	args      []int
+	parent    int
+	// waiting — сколько аргументов ещё не вычислено
+	waiting int
+	value   string
+}
+
+func (n *graphNode) isTask() bool {
+	switch n.operation {
+	case "+", "-", "*", "/", "^":
+		return true
+	}
+	return false
+}
+
+// buildGraph раскладывает выражение в граф зависимостей. Корень графа — последняя вершина.
+// Граф строится по польской записи, поэтому переменные, константы и вызовы функций
+// проверяются так же, как при последовательном вычислении.
+func buildGraph(root node, env *Env) ([]*graphNode, error) {
+	polishNotation, err := compile(root, env, []string{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*graphNode, 0, len(polishNotation))
+	operands := []int{}
+	add := func(operation string, count int) error {
+		if len(operands) < count {
+			return errorf(ErrSyntax, "Wrong expression")
+		}
+		n := &graphNode{operation: operation, parent: -1, waiting: count}
+		n.args = append(n.args, operands[len(operands)-count:]...)
+		operands = operands[:len(operands)-count]
+		for _, arg := range n.args {
+			nodes[arg].parent = len(nodes)
+		}
+		operands = append(operands, len(nodes))
+		nodes = append(nodes, n)
+		return nil
+	}
+	for _, elem := range polishNotation {
+		switch elem {
+		case "+", "-", "*", "/", "^":
+			err = add(elem, 2)
+		case "~":
+			err = add(elem, 1)
+		default:
+			if name, count, ok := parseFunctionCall(elem); ok {
+				err = add(name, count)
+				break
+			}
+			operands = append(operands, len(nodes))
+			nodes = append(nodes, &graphNode{parent: -1, value: elem})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(operands) != 1 {
+		return nil, errorf(ErrSyntax, "Wrong expression")
+	}
+	return nodes, nil
+}
+
+// runGraph вычисляет граф: каждая задача передаётся в submit, как только готовы её операнды,
+// поэтому независимые операции выполняются параллельно, а общее время определяется
+// самой длинной цепочкой зависимых операций. При отмене ctx вычисление прекращается,
+// а ещё не выданные задачи выражения вычислители пропускают.
+// progress, если задан, вызывается после каждой вычисленной операции, кроме последней.
+// Когда runGraph возвращается, в том числе с ошибкой, контекст задач отменяется:
+// оставшиеся в очереди операции выражения больше никому не выдаются.
+func runGraph(ctx context.Context, nodes []*graphNode, env *Env, mode string, submit func(Task), progress func(done, total int)) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	arith := textArithmeticFor(mode)
+	// буфер на все вершины: исполнители и локальные вычисления никогда не ждут координатора
+	results := make(chan TaskResult, len(nodes))
+	start := func(i int) {
+		n := nodes[i]
+		if err := env.spend(); err != nil {
+			results <- TaskResult{ID: i, Err: err}
+			return
+		}
+		args := make([]string, len(n.args))
+		for j, arg := range n.args {
+			args[j] = nodes[arg].value
+		}
+		if n.isTask() {
+			if arith.approximate(args[0], args[1], n.operation) {
+				env.markInexact()
+			}
+			submit(Task{ID: i, Operation: n.operation, Arg1: args[0], Arg2: args[1], Mode: mode, ctx: ctx, done: results})
+			return
+		}
+		go func() {
+			var value string
+			var err error
+			if n.operation == "~" {
+				value, err = arith.negate(args[0])
+			} else {
+				value, err = arith.call(ctx, env, n.operation, args)
+			}
+			results <- TaskResult{ID: i, Value: value, Err: err}
+		}()
+	}
+	// finish сохраняет значение вершины и запускает родителя, если это был его последний аргумент
+	finish := func(i int, value string) {
+		nodes[i].value = value
+		if parent := nodes[i].parent; parent >= 0 {
+			nodes[parent].waiting--
+			if nodes[parent].waiting == 0 {
+				start(parent)
+			}
+		}
+	}
+
+	root := len(nodes) - 1
+	if nodes[root].operation == "" {
+		return nodes[root].value, nil
+	}
+	done, total := 0, 0
+	for _, n := range nodes {
+		if n.operation != "" {
+			total++
+		}
+	}
+	for i, n := range nodes {
+		switch {
+		case n.operation == "":
+			finish(i, n.value)
+		case len(n.args) == 0:
+			// функция без аргументов готова к вычислению сразу
+			start(i)
+		}
+	}
+	for {
+		var result TaskResult
+		select {
+		case result = <-results:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if result.Err != nil {
+			return "", result.Err
+		}
+		if result.ID == root {
+			return result.Value, nil
+		}
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
+		finish(result.ID, result.Value)
+	}
+}
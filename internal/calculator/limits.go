@@ -0,0 +1,88 @@
+package calc
+
+import (
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Ограничения по умолчанию, если переменные окружения не заданы.
+const (
+	DefaultMaxLength     = 10000
+	DefaultMaxDepth      = 1000
+	DefaultMaxOperations = 10000
+)
+
+// Limits ограничивает размер выражения и объём его вычисления.
+type Limits struct {
+	// MaxLength — число символов в тексте выражения; 0 — без ограничения,
+	// тогда размер выражения ограничивают только MaxDepth и MaxOperations
+	MaxLength int
+	// MaxDepth — глубина синтаксического дерева: "1+2*3" имеет глубину 3; 0 — без ограничения,
+	// но глубже MaxNesting парсер не разбирает в любом случае
+	MaxDepth int
+	// MaxOperations — число операций, включая операции в телах вызываемых пользовательских функций;
+	// 0 — без ограничения
+	MaxOperations int
+}
+
+// LimitsFromEnv читает ограничения из MAX_EXPRESSION_LENGTH, MAX_AST_DEPTH и MAX_OPERATIONS.
+// Значение 0 в любой из них отключает это ограничение.
+func LimitsFromEnv() Limits {
+	return Limits{
+		MaxLength:     settings.Int("MAX_EXPRESSION_LENGTH", DefaultMaxLength, settings.NonNegative),
+		MaxDepth:      settings.Int("MAX_AST_DEPTH", DefaultMaxDepth, settings.NonNegative),
+		MaxOperations: settings.Int("MAX_OPERATIONS", DefaultMaxOperations, settings.NonNegative),
+	}
+}
+
+// Check проверяет синтаксис выражения и его размер до вычисления. Глубина и число операций
+// проверяются во время разбора: выражение отклоняется, как только превысит ограничение,
+// даже если длина не ограничена. Операции в телах пользовательских функций здесь не видны,
+// их ограничивает бюджет при вычислении.
+func (l Limits) Check(expression string) error {
+	if length := utf8.RuneCountInString(expression); l.MaxLength > 0 && length > l.MaxLength {
+		return errorf(ErrBudget, "expression is too long: %d characters, limit is %d", length, l.MaxLength)
+	}
+	_, _, err := parseStatementWithin(expression, l)
+	return err
+}
+
+func children(n node) []node {
+	switch n := n.(type) {
+	case *negateNode:
+		return []node{n.operand}
+	case *binaryNode:
+		return []node{n.left, n.right}
+	case *callNode:
+		return n.args
+	}
+	return nil
+}
+
+// budget — сколько операций ещё можно выполнить. Один бюджет на выражение
+// вместе со всеми вызванными в нём пользовательскими функциями.
+type budget struct {
+	limit int
+	left  atomic.Int64
+}
+
+// limitOperations ограничивает число операций при вычислении в окружении e и его дочерних.
+func (e *Env) limitOperations(limit int) {
+	if limit <= 0 {
+		return
+	}
+	e.budget = &budget{limit: limit}
+	e.budget.left.Store(int64(limit))
+}
+
+// spend списывает одну операцию из бюджета.
+func (e *Env) spend() error {
+	if e == nil || e.budget == nil {
+		return nil
+	}
+	if e.budget.left.Add(-1) < 0 {
+		return errorf(ErrBudget, "evaluation exceeded the limit of %d operations", e.budget.limit)
+	}
+	return nil
+}
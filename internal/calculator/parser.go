@@ -0,0 +1,553 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError описывает ошибку в тексте выражения: где она, какой токен помешал и что ожидалось.
+// Line и Column считаются с единицы, Column — в символах, а не в байтах.
+// Err — вид ошибки: ErrSyntax, ErrUnbalancedParens, ErrUnknownToken, ErrInvalidName, ErrArity
+// или ErrBudget, если выражение вложено глубже MaxNesting.
+type SyntaxError struct {
+	Err      error
+	Line     int
+	Column   int
+	Token    string
+	Expected string
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// position — место токена или узла дерева в исходном выражении.
+type position struct {
+	line   int
+	column int
+}
+
+func (p position) errorf(kind error, token, expected, format string, args ...any) *SyntaxError {
+	return &SyntaxError{
+		Err:      kind,
+		Line:     p.line,
+		Column:   p.column,
+		Token:    token,
+		Expected: expected,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenName
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenAssign
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  position
+}
+
+// tokenize разбивает выражение на числа, имена, операции, скобки, запятые и '='.
+// Ссылки на другие выражения (#42 и $prev) становятся именами.
+// Пробелы и переводы строк пропускаются, но учитываются в позициях токенов.
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(expression)
+	pos := position{line: 1, column: 1}
+	for i := 0; i < len(runes); {
+		character := runes[i]
+		begin := i
+		switch {
+		case character == '\n':
+			i++
+			pos = position{line: pos.line + 1, column: 1}
+			continue
+		case unicode.IsSpace(character):
+			i++
+		case unicode.IsDigit(character) || character == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[begin:i])
+			if strings.Count(text, ".") > 1 || text == "." {
+				return nil, pos.errorf(ErrUnknownToken, text, "number", "malformed number %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, pos: pos})
+		case unicode.IsLetter(character) || character == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: string(runes[begin:i]), pos: pos})
+		case character == '#' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			i++
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: string(runes[begin:i]), pos: pos})
+		case character == '$' && i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || runes[i+1] == '_'):
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := string(runes[begin:i])
+			if text != PrevReference {
+				return nil, pos.errorf(ErrUnknownToken, text, PrevReference, "unknown reference %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenName, text: text, pos: pos})
+		case strings.ContainsRune("+-*/^", character):
+			i++
+			tokens = append(tokens, token{kind: tokenOperator, text: string(character), pos: pos})
+		case character == '(':
+			i++
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: pos})
+		case character == ')':
+			i++
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: pos})
+		case character == ',':
+			i++
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+		case character == '=':
+			i++
+			tokens = append(tokens, token{kind: tokenAssign, text: "=", pos: pos})
+		default:
+			return nil, pos.errorf(ErrUnknownToken, string(character), "number, name, operator or bracket", "unexpected symbol %q", string(character))
+		}
+		pos.column += i - begin
+	}
+	return append(tokens, token{kind: tokenEOF, pos: pos}), nil
+}
+
+// Узлы синтаксического дерева выражения.
+type (
+	node interface {
+		position() position
+	}
+
+	numberNode struct {
+		pos     position
+		literal string
+	}
+
+	nameNode struct {
+		pos  position
+		name string
+	}
+
+	// negateNode — унарный минус.
+	negateNode struct {
+		pos     position
+		operand node
+	}
+
+	binaryNode struct {
+		pos         position
+		operation   string
+		left, right node
+	}
+
+	callNode struct {
+		pos  position
+		name string
+		args []node
+	}
+)
+
+func (n *numberNode) position() position { return n.pos }
+func (n *nameNode) position() position   { return n.pos }
+func (n *negateNode) position() position { return n.pos }
+func (n *binaryNode) position() position { return n.pos }
+func (n *callNode) position() position   { return n.pos }
+
+// Подсказки для SyntaxError.Expected.
+const (
+	expectOperand  = `number, name, "-" or "("`
+	expectOperator = "operator or end of expression"
+)
+
+// MaxNesting — наибольшая глубина синтаксического дерева, которую принимает парсер.
+// Разбор и обход дерева рекурсивны, и без этого ограничения тело функции из десятков тысяч
+// скобок или минусов исчерпало бы стек горутины и уронило процесс. Limits.MaxDepth
+// может ограничивать глубину принимаемых выражений сильнее.
+const MaxNesting = 10000
+
+// parser — рекурсивный спуск по грамматике:
+//
+//	statement  = [ name "=" ] expression
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = "-" unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | name | name "(" [ expression { "," expression } ] ")" | "(" expression ")"
+//
+// '^' связывает сильнее унарного минуса и группируется справа налево: -2^2 = -4, 2^3^2 = 512.
+type parser struct {
+	tokens []token
+	pos    int
+	// depth — число открытых и ещё не закрытых скобок
+	depth int
+	// nesting — глубина рекурсии разбора: скобки, вызовы, унарные минусы и показатели степени
+	nesting int
+	// limits проверяются по мере построения дерева, поэтому слишком большое выражение
+	// отклоняется, как только превысит ограничение, а не после полного разбора
+	limits Limits
+	// operations — сколько операций уже разобрано
+	operations int
+	// heights — глубина поддерева каждой построенной операции; у листьев глубина 1
+	heights map[node]int
+}
+
+// parse строит дерево выражения без присваивания.
+func parse(expression string) (node, error) {
+	p, err := newParser(expression, Limits{})
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// parseStatement строит дерево выражения, которое может начинаться с присваивания "x = ...".
+// Для обычного выражения возвращается пустое имя.
+func parseStatement(expression string) (string, node, error) {
+	return parseStatementWithin(expression, Limits{})
+}
+
+// parseStatementWithin — parseStatement, который прерывает разбор, как только дерево
+// превышает limits.MaxDepth или limits.MaxOperations.
+func parseStatementWithin(expression string, limits Limits) (string, node, error) {
+	p, err := newParser(expression, limits)
+	if err != nil {
+		return "", nil, err
+	}
+	name := ""
+	if p.peek().kind == tokenName && p.tokens[p.pos+1].kind == tokenAssign {
+		target := p.next()
+		if err := ValidateVariableName(target.text); err != nil {
+			return "", nil, target.pos.errorf(ErrInvalidName, target.text, "variable name", "%v", err)
+		}
+		name = target.text
+		p.next()
+	}
+	root, err := p.expression()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := p.end(); err != nil {
+		return "", nil, err
+	}
+	return name, root, nil
+}
+
+// Check проверяет синтаксис выражения или присваивания, не вычисляя его.
+func Check(expression string) error {
+	_, _, err := parseStatement(expression)
+	return err
+}
+
+func newParser(expression string, limits Limits) (*parser, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, ErrEmptyExpression
+	}
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, limits: limits, heights: make(map[node]int)}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// unexpected сообщает, что на месте текущего токена ожидалось expected.
+func (p *parser) unexpected(expected string) *SyntaxError {
+	t := p.peek()
+	kind := ErrSyntax
+	if (t.kind == tokenEOF && p.depth > 0) || (t.kind == tokenRightParen && p.depth == 0) {
+		kind = ErrUnbalancedParens
+	}
+	if t.kind == tokenEOF {
+		return t.pos.errorf(kind, "", expected, "unexpected end of expression, expected %s", expected)
+	}
+	return t.pos.errorf(kind, t.text, expected, "unexpected %q, expected %s", t.text, expected)
+}
+
+// enter отмечает вход во вложенную конструкцию, которая начинается токеном t.
+// Каждому enter без ошибки соответствует leave.
+func (p *parser) enter(t token) error {
+	if p.nesting >= MaxNesting {
+		return t.pos.errorf(ErrBudget, t.text, "", "expression is nested too deeply: more than %d levels", MaxNesting)
+	}
+	p.nesting++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.nesting--
+}
+
+// built учитывает только что построенную операцию n: её глубину и число операций.
+// Цепочки вида 1+1+...+1 разбираются циклом без enter, но дают дерево, глубина которого
+// растёт с длиной цепочки, поэтому глубина дерева проверяется здесь, а не в enter.
+func (p *parser) built(n node) (node, error) {
+	height := 1
+	for _, child := range children(n) {
+		height = max(height, p.height(child)+1)
+	}
+	p.heights[n] = height
+	p.operations++
+	pos := n.position()
+	if height > MaxNesting {
+		return nil, pos.errorf(ErrBudget, "", "", "expression is nested too deeply: more than %d levels", MaxNesting)
+	}
+	if p.limits.MaxDepth > 0 && height > p.limits.MaxDepth {
+		return nil, pos.errorf(ErrBudget, "", "", "expression is too deep: more than %d levels", p.limits.MaxDepth)
+	}
+	if p.limits.MaxOperations > 0 && p.operations > p.limits.MaxOperations {
+		return nil, pos.errorf(ErrBudget, "", "", "expression has too many operations: more than %d", p.limits.MaxOperations)
+	}
+	return n, nil
+}
+
+// height возвращает глубину поддерева n.
+func (p *parser) height(n node) int {
+	if height, ok := p.heights[n]; ok {
+		return height
+	}
+	return 1
+}
+
+func (p *parser) end() error {
+	if p.peek().kind != tokenEOF {
+		return p.unexpected(expectOperator)
+	}
+	return nil
+}
+
+func (p *parser) isOperator(operations ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, operation := range operations {
+		if t.text == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		operation := p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left, err = p.built(&binaryNode{pos: operation.pos, operation: operation.text, left: left, right: right})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*", "/") {
+		operation := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left, err = p.built(&binaryNode{pos: operation.pos, operation: operation.text, left: left, right: right})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isOperator("-") {
+		minus := p.next()
+		if err := p.enter(minus); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.built(&negateNode{pos: minus.pos, operand: operand})
+	}
+	return p.power()
+}
+
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("^") {
+		return base, nil
+	}
+	operation := p.next()
+	if err := p.enter(operation); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return p.built(&binaryNode{pos: operation.pos, operation: "^", left: base, right: exponent})
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.next()
+		return &numberNode{pos: t.pos, literal: t.text}, nil
+	case tokenName:
+		p.next()
+		if p.peek().kind != tokenLeftParen {
+			return &nameNode{pos: t.pos, name: t.text}, nil
+		}
+		return p.call(t)
+	case tokenLeftParen:
+		p.next()
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		p.depth++
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRightParen {
+			return nil, p.unexpected(`operator or ")"`)
+		}
+		p.next()
+		p.depth--
+		return inner, nil
+	}
+	return nil, p.unexpected(expectOperand)
+}
+
+// call разбирает аргументы вызова функции name; открывающая скобка — текущий токен.
+func (p *parser) call(name token) (node, error) {
+	if err := p.enter(p.next()); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	p.depth++
+	call := &callNode{pos: name.pos, name: name.text}
+	if p.peek().kind == tokenRightParen {
+		p.next()
+		p.depth--
+		return p.built(call)
+	}
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		switch p.peek().kind {
+		case tokenComma:
+			p.next()
+		case tokenRightParen:
+			p.next()
+			p.depth--
+			return p.built(call)
+		default:
+			return nil, p.unexpected(`operator, "," or ")"`)
+		}
+	}
+}
+
+// compile переводит дерево в польскую запись, подставляя значения переменных и констант из env.
+func compile(n node, env *Env, polishNotation []string) ([]string, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return append(polishNotation, n.literal), nil
+	case *nameNode:
+		literal, ok := env.literal(n.name)
+		if !ok {
+			return nil, n.pos.errorf(ErrUnknownToken, n.name, "variable or constant", "unknown identifier: %s", n.name)
+		}
+		return append(polishNotation, literal), nil
+	case *negateNode:
+		polishNotation, err := compile(n.operand, env, polishNotation)
+		if err != nil {
+			return nil, err
+		}
+		return append(polishNotation, "~"), nil
+	case *binaryNode:
+		polishNotation, err := compile(n.left, env, polishNotation)
+		if err != nil {
+			return nil, err
+		}
+		polishNotation, err = compile(n.right, env, polishNotation)
+		if err != nil {
+			return nil, err
+		}
+		return append(polishNotation, n.operation), nil
+	case *callNode:
+		if !env.hasFunction(n.name) {
+			return nil, n.pos.errorf(ErrUnknownToken, n.name, "function", "unknown function: %s", n.name)
+		}
+		if err := env.checkArgs(n.name, len(n.args)); err != nil {
+			return nil, n.pos.errorf(ErrArity, n.name, "", "%v", err)
+		}
+		var err error
+		for _, arg := range n.args {
+			polishNotation, err = compile(arg, env, polishNotation)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(polishNotation, functionCall(n.name, len(n.args))), nil
+	}
+	return nil, fmt.Errorf("unknown node %T", n)
+}
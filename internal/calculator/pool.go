@@ -0,0 +1,169 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
+	"log"
+	"sync"
+	"time"
+)
+
+// MaxPoolSize ограничивает число локальных вычислителей.
+const MaxPoolSize = 1024
+
+// DefaultComputingPower — число вычислителей, если COMPUTING_POWER не задана.
+const DefaultComputingPower = 10
+
+// Pool — локальные вычислители, которые забирают операции из Dispatcher.
+// Их число меняется на ходу; остановленный вычислитель сначала дописывает результат
+// операции, которую уже выполняет, поэтому уменьшение пула не теряет задачи.
+type Pool struct {
+	tasks *Dispatcher
+
+	mu sync.Mutex
+	// stops останавливают вычислители, по одной функции на каждый
+	stops []context.CancelFunc
+	// closed — пул остановлен насовсем и больше не меняет размер
+	closed bool
+	// running считает и работающие, и ещё дописывающие результат вычислители
+	running sync.WaitGroup
+}
+
+func NewPool(tasks *Dispatcher) *Pool {
+	return &Pool{tasks: tasks}
+}
+
+// Size возвращает число работающих вычислителей.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.stops)
+}
+
+// Resize запускает или останавливает вычислители, пока их не станет size.
+// Resize не ждёт, пока остановленные вычислители допишут результаты, для этого есть Wait.
+func (p *Pool) Resize(size int) {
+	size = min(max(size, 0), MaxPoolSize)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.resize(size)
+}
+
+func (p *Pool) resize(size int) {
+	for len(p.stops) < size {
+		ctx, stop := context.WithCancel(context.Background())
+		p.stops = append(p.stops, stop)
+		p.running.Add(1)
+		go p.executor(ctx)
+	}
+	for len(p.stops) > size {
+		last := len(p.stops) - 1
+		p.stops[last]()
+		p.stops = p.stops[:last]
+	}
+}
+
+// Wait ждёт, пока завершатся все остановленные вычислители. Вызывается после Resize(0).
+func (p *Pool) Wait() {
+	p.running.Wait()
+}
+
+// Close останавливает все вычислители и ждёт, пока они допишут результаты.
+// После Close размер пула больше не меняется.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.resize(0)
+	p.mu.Unlock()
+	p.Wait()
+}
+
+// executor выполняет отдельные операции выражений, пока не отменён ctx.
+func (p *Pool) executor(ctx context.Context) {
+	defer p.running.Done()
+	for {
+		leased, err := p.tasks.Acquire(ctx)
+		if err != nil {
+			// вычислитель остановлен уменьшением пула
+			return
+		}
+		value, err := ExecuteTask(leased.Task.context(), leased.Task)
+		if err := p.tasks.Complete(leased.ID, leased.Lease, value, err); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Autoscale — политика автоматического изменения размера пула. При нулевом Max пул
+// меняется только вручную.
+type Autoscale struct {
+	Min int
+	Max int
+	// Interval — как часто пересматривается размер пула
+	Interval time.Duration
+	// TargetWait — сколько операция может ждать вычислителя, прежде чем пул вырастет
+	TargetWait time.Duration
+}
+
+// Значения политики по умолчанию.
+const (
+	DefaultAutoscaleInterval   = 5 * time.Second
+	DefaultAutoscaleTargetWait = 500 * time.Millisecond
+)
+
+// AutoscaleFromEnv читает политику из AUTOSCALE_MIN, AUTOSCALE_MAX, AUTOSCALE_INTERVAL_MS
+// и AUTOSCALE_TARGET_WAIT_MS. Без AUTOSCALE_MAX автоматическое изменение выключено.
+// AUTOSCALE_MIN больше AUTOSCALE_MAX — ошибка: пул не смог бы выбрать размер между ними.
+func AutoscaleFromEnv() (Autoscale, error) {
+	policy := Autoscale{
+		Min:        settings.Int("AUTOSCALE_MIN", 0, settings.NonNegative),
+		Max:        min(settings.Int("AUTOSCALE_MAX", 0, settings.NonNegative), MaxPoolSize),
+		Interval:   settings.Duration("AUTOSCALE_INTERVAL_MS", DefaultAutoscaleInterval),
+		TargetWait: settings.Duration("AUTOSCALE_TARGET_WAIT_MS", DefaultAutoscaleTargetWait),
+	}
+	if policy.Enabled() && policy.Min > policy.Max {
+		return Autoscale{}, fmt.Errorf("AUTOSCALE_MIN (%d) must not exceed AUTOSCALE_MAX (%d)", policy.Min, policy.Max)
+	}
+	return policy, nil
+}
+
+// Enabled сообщает, меняет ли политика размер пула сама.
+func (a Autoscale) Enabled() bool {
+	return a.Max > 0
+}
+
+// next выбирает размер пула по текущему размеру, числу ожидающих операций и времени их ожидания.
+// Если операции ждут дольше TargetWait, пул удваивается; если очередь пуста и операции
+// не ждут, пул уменьшается на один вычислитель. Размер всегда остаётся между Min и Max.
+func (a Autoscale) next(size, queued int, wait time.Duration) int {
+	switch {
+	case queued > 0 && wait > a.TargetWait:
+		size = max(size*2, size+1)
+	case queued == 0 && wait < a.TargetWait/2:
+		size--
+	}
+	return min(max(size, a.Min), a.Max)
+}
+
+// autoscale пересматривает размер пула по политике policy, пока не отменён ctx.
+func (p *Pool) autoscale(ctx context.Context, policy Autoscale) {
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		queued, wait := p.tasks.load(time.Now())
+		size := p.Size()
+		if next := policy.next(size, queued, wait); next != size {
+			log.Printf("[WORKER] autoscale: %d -> %d executors, %d tasks queued, wait %s", size, next, queued, wait)
+			p.Resize(next)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package calc
+
+import (
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// PrevReference — ссылка на результат предыдущего выражения пользователя.
+// Выражение #42 ссылается на результат выражения 42.
+const PrevReference = "$prev"
+
+// References возвращает ссылки на другие выражения, которые встречаются в expression,
+// каждую один раз и в порядке появления.
+func References(expression string) ([]string, error) {
+	_, root, err := parseStatement(expression)
+	if err != nil {
+		return nil, err
+	}
+	var references []string
+	seen := make(map[string]bool)
+	var walk func(n node)
+	walk = func(n node) {
+		if name, ok := n.(*nameNode); ok && isReference(name.name) && !seen[name.name] {
+			seen[name.name] = true
+			references = append(references, name.name)
+		}
+		for _, child := range children(n) {
+			walk(child)
+		}
+	}
+	walk(root)
+	return references, nil
+}
+
+// ReferenceID возвращает номер выражения из ссылки вида "#42".
+func ReferenceID(reference string) (int, bool) {
+	digits, ok := strings.CutPrefix(reference, "#")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(digits)
+	return id, err == nil
+}
+
+func isReference(name string) bool {
+	return strings.HasPrefix(name, "#") || name == PrevReference
+}
+
+// setReference подставляет вместо ссылки name результат выражения dependency.
+// В точных режимах берётся точная запись результата, если она есть; результат, вычисленный
+// в float64 целиком или частично, делает неточным и это вычисление.
+func (e *Env) setReference(name string, dependency dto.Expression, mode string) {
+	e.Variables[name] = dependency.Result
+	if !IsExact(mode) {
+		return
+	}
+	if dependency.Inexact || dependency.Value == "" {
+		e.markInexact()
+	}
+	if dependency.Value == "" {
+		return
+	}
+	value, ok := new(big.Rat).SetString(dependency.Value)
+	if !ok {
+		return
+	}
+	if e.exact == nil {
+		e.exact = make(map[string]*big.Rat)
+	}
+	e.exact[name] = value
+}
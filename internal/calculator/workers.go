@@ -1,49 +1,469 @@
 package calc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
 	"github.com/philipslstwoyears/calculator-go/internal/storage"
 	"log"
-	"os"
-	"strconv"
+	"maps"
+	"slices"
+	"sync"
+	"time"
 )
 
+// Параметры очереди выражений в базе.
+const (
+	// DefaultJobLease — аренда выражения, если JOB_LEASE_MS не задана.
+	// Пока выражение вычисляется, аренда продлевается.
+	DefaultJobLease = time.Minute
+	// DefaultJobAttempts — сколько раз выражение может быть прервано остановкой процесса,
+	// если JOB_MAX_ATTEMPTS не задана. После этого ему записывается ошибка.
+	DefaultJobAttempts = 3
+	// DefaultUserConcurrency — сколько выражений одного пользователя вычисляется одновременно,
+	// если MAX_RUNNING_PER_USER не задана. Остальные его выражения ждут в очереди.
+	DefaultUserConcurrency = 8
+	// DefaultMaxRunning — сколько выражений процесс вычисляет одновременно, если MAX_RUNNING не задана.
+	// Остальные ждут в очереди, а не занимают горутины и память.
+	DefaultMaxRunning = 256
+	// jobPollInterval — как часто очередь проверяется без уведомлений о новых выражениях.
+	jobPollInterval = time.Second
+)
+
+// Статусы вычисленного и отменённого выражения.
+const (
+	StatusOK        = dto.StatusOK
+	StatusCancelled = dto.StatusCancelled
+)
+
+// errShutdown — причина отмены выражений, которые не успели вычислиться до конца остановки сервера.
+var errShutdown = errors.New("server is shutting down")
+
+// running — выражение, которое вычисляется в этом процессе.
+type running struct {
+	cancel context.CancelFunc
+	// done закрывается, когда результат выражения записан
+	done chan struct{}
+}
+
+// Worker забирает выражения из очереди в базе и разбирает их на графы задач. Сами задачи выполняют
+// вычислители этого процесса и удалённые агенты, которые забирают их из Tasks.
 type Worker struct {
 	storage storage.Storage
-	input   chan dto.Expression
+	// queued сообщает о новых выражениях в очереди, чтобы не ждать следующей проверки
+	queued <-chan struct{}
+	// finished сообщает, что выражение вычислено и его пользователь, возможно, снова может получить выражение
+	finished    chan struct{}
+	tasks       *Dispatcher
+	pool        *Pool
+	autoscale   Autoscale
+	jobLease    time.Duration
+	maxAttempts int
+	userLimit   int
+	maxRunning  int
+	limits      Limits
+
+	// mu защищает running и делает атомарными забор выражения из очереди и его отмену
+	mu      sync.Mutex
+	running map[int]running
+
+	// closing отменяется в начале остановки: новые выражения больше не забираются из очереди
+	closing      context.Context
+	stopClaiming context.CancelFunc
+	// jobs — родительский контекст вычисляемых выражений. Он отменяется с причиной errShutdown,
+	// если выражения не успели вычислиться до конца остановки.
+	jobs  context.Context
+	abort context.CancelCauseFunc
+	// processing считает вычисляемые выражения
+	processing sync.WaitGroup
+
+	// Events получает изменения состояния выражений; nil — события не публикуются
+	Events *Events
+	// accepting не даёт claim забрать выражение, пока приём ещё не опубликовал для него queued, см. HoldClaims
+	accepting sync.RWMutex
 }
 
-func New(storage storage.Storage, input chan dto.Expression) *Worker {
+func New(storage storage.Storage, queued <-chan struct{}) *Worker {
+	tasks := NewDispatcher(LeaseFromEnv())
+	tasks.aging = PriorityAgingFromEnv()
+	closing, stopClaiming := context.WithCancel(context.Background())
+	jobs, abort := context.WithCancelCause(context.Background())
 	return &Worker{
-		storage: storage,
-		input:   input,
+		storage:      storage,
+		queued:       queued,
+		finished:     make(chan struct{}, 1),
+		tasks:        tasks,
+		pool:         NewPool(tasks),
+		jobLease:     settings.Duration("JOB_LEASE_MS", DefaultJobLease),
+		maxAttempts:  settings.Int("JOB_MAX_ATTEMPTS", DefaultJobAttempts, settings.Positive),
+		userLimit:    settings.Int("MAX_RUNNING_PER_USER", DefaultUserConcurrency, settings.NonNegative),
+		maxRunning:   settings.Int("MAX_RUNNING", DefaultMaxRunning, settings.Positive),
+		limits:       LimitsFromEnv(),
+		running:      make(map[int]running),
+		closing:      closing,
+		stopClaiming: stopClaiming,
+		jobs:         jobs,
+		abort:        abort,
+	}
+}
+
+// Tasks возвращает очередь задач, из которой вычислители забирают операции.
+func (w *Worker) Tasks() *Dispatcher {
+	return w.tasks
+}
+
+// Pool возвращает пул локальных вычислителей, размер которого можно менять на ходу.
+func (w *Worker) Pool() *Pool {
+	return w.pool
+}
+
+// Autoscale возвращает политику автоматического изменения размера пула.
+func (w *Worker) Autoscale() Autoscale {
+	return w.autoscale
+}
+
+// HoldClaims не даёт забирать выражения из очереди до вызова возвращённой функции.
+// Приём выражений держит её от сохранения до публикации queued: иначе вычислитель мог бы
+// забрать выражение и опубликовать running раньше. Приёмы друг другу не мешают.
+// Допускает nil: тогда ничего не задерживается.
+func (w *Worker) HoldClaims() (release func()) {
+	if w == nil {
+		return func() {}
 	}
+	w.accepting.RLock()
+	return w.accepting.RUnlock
+}
+
+// SetAutoscale задаёт политику автоматического изменения размера пула, см. AutoscaleFromEnv.
+// Вызывается до Start; без неё размер пула меняется только через Pool().Resize.
+func (w *Worker) SetAutoscale(policy Autoscale) {
+	w.autoscale = policy
 }
+
+// Running сообщает, вычисляется ли выражение id в этом процессе.
+func (w *Worker) Running(id int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.running[id]
+	return ok
+}
+
+// Start восстанавливает очередь после прошлого запуска, затем запускает приём выражений
+// и COMPUTING_POWER локальных вычислителей, а если задан AUTOSCALE_MAX — и автоматическое
+// изменение их числа.
+// COMPUTING_POWER=0 допустим: тогда все задачи выполняют удалённые агенты (cmd/agent).
 func (w *Worker) Start() error {
-	countWorkers := os.Getenv("COMPUTING_POWER")
-	computerWorkers, err := strconv.Atoi(countWorkers)
+	computerWorkers := settings.Int("COMPUTING_POWER", DefaultComputingPower, settings.NonNegative)
+	if err := w.recover(); err != nil {
+		return err
+	}
+	go w.worker()
+	w.pool.Resize(computerWorkers)
+	if w.autoscale.Enabled() {
+		go w.pool.autoscale(w.closing, w.autoscale)
+	}
+	return nil
+}
+
+// recover возвращает в очередь выражения, прерванные остановкой процесса,
+// и записывает ошибку тем, что прерывались уже maxAttempts раз.
+func (w *Worker) recover() error {
+	requeued, failed, err := w.storage.RecoverJobs(w.maxAttempts)
 	if err != nil {
 		return err
 	}
-	for i := 0; i < computerWorkers; i++ {
-		go w.worker()
+	for _, expression := range failed {
+		setStatus(&expression, errorf(ErrInterrupted, "expression was interrupted %d times", w.maxAttempts))
+		expression.Result = -1
+		if err := w.storage.FinishJob(expression); err != nil {
+			return err
+		}
+		w.Events.Publish(Event{Type: EventError, Expression: expression})
+	}
+	if requeued > 0 || len(failed) > 0 {
+		log.Printf("[WORKER] recovered queue: %d expressions requeued, %d failed", requeued, len(failed))
 	}
 	return nil
 }
 
+// worker забирает выражения из очереди. Каждое выражение ждёт свои задачи в отдельной горутине,
+// поэтому число одновременно вычисляемых выражений не ограничено числом вычислителей,
+// а ограничено MAX_RUNNING для процесса и MAX_RUNNING_PER_USER для каждого пользователя.
 func (w *Worker) worker() {
-	for expression := range w.input {
-		calc, err := Calc(expression.Expression)
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		w.claim()
+		select {
+		case <-w.queued:
+		case <-w.finished:
+		case <-ticker.C:
+		case <-w.closing.Done():
+			return
+		}
+	}
+}
+
+// claim забирает из очереди выражения, которые можно начать вычислять сейчас, но не больше,
+// чем осталось свободных мест из maxRunning. Когда выражение вычислено, claim вызывается снова.
+// Выражения выдаются по кругу пользователей, см. storage.DbStorage.ClaimJob.
+func (w *Worker) claim() {
+	for {
+		w.accepting.Lock()
+		w.mu.Lock()
+		if w.closing.Err() != nil || len(w.running) >= w.maxRunning {
+			w.mu.Unlock()
+			w.accepting.Unlock()
+			return
+		}
+		expression, ok, err := w.storage.ClaimJob(w.jobLease, w.userLimit)
+		if err != nil || !ok {
+			w.mu.Unlock()
+			w.accepting.Unlock()
+			if err != nil {
+				log.Println(err)
+			}
+			return
+		}
+		ctx, cancel := context.WithCancel(w.jobs)
+		job := running{cancel: cancel, done: make(chan struct{})}
+		w.running[expression.ID] = job
+		w.processing.Add(1)
+		w.mu.Unlock()
+		w.Events.Publish(Event{Type: EventRunning, Expression: expression})
+		w.accepting.Unlock()
+		go w.process(ctx, expression, job)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, expression dto.Expression, job running) {
+	defer w.processing.Done()
+	defer close(job.done)
+	defer func() {
+		w.mu.Lock()
+		delete(w.running, expression.ID)
+		w.mu.Unlock()
+		job.cancel()
+		// место освободилось: worker может забрать следующее выражение, не дожидаясь проверки по таймеру
+		select {
+		case w.finished <- struct{}{}:
+		default:
+		}
+	}()
+	if expression.Deadline != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(expression.Deadline))
+		defer cancel()
+	}
+	stop := w.renew(expression.ID)
+	out, err := w.calc(ctx, expression)
+	stop()
+	if err != nil && errors.Is(context.Cause(ctx), errShutdown) {
+		// выражение не успело вычислиться до остановки: оно остаётся в очереди
+		// и при следующем запуске вычисляется заново
+		if err := w.storage.ReleaseJob(expression.ID); err != nil {
+			log.Println(err)
+		}
+		w.Events.Publish(Event{Type: EventQueued, Expression: expression})
+		return
+	}
+	setStatus(&expression, err)
+	expression.Result = out.result
+	expression.Value = out.value
+	expression.Inexact = out.inexact
+	err = w.storage.FinishJob(expression)
+	if err != nil {
+		log.Println(err)
+	}
+	w.Events.Publish(Event{Type: EventType(expression), Expression: expression})
+}
+
+// Shutdown останавливает вычисления: новые выражения больше не забираются из очереди,
+// а начатые вычисляются до отмены ctx. Выражения, которые к этому моменту не успели
+// вычислиться, возвращаются в очередь. Затем останавливаются локальные вычислители.
+func (w *Worker) Shutdown(ctx context.Context) {
+	w.mu.Lock()
+	w.stopClaiming()
+	w.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		w.processing.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		w.abort(errShutdown)
+		<-finished
+	}
+	w.pool.Close()
+}
+
+// Cancel отменяет выражение: ожидающее убирается из очереди, а вычисляемое прерывается
+// между операциями. Cancel возвращается, когда статус StatusCancelled уже записан.
+// Если выражение уже вычислено, возвращается false.
+func (w *Worker) Cancel(expression dto.Expression) (bool, error) {
+	w.mu.Lock()
+	job, ok := w.running[expression.ID]
+	if !ok {
+		defer w.mu.Unlock()
+		setStatus(&expression, ErrCancelled)
+		expression.Result = -1
+		expression.Value = ""
+		cancelled, err := w.storage.CancelJob(expression)
+		if cancelled {
+			w.Events.Publish(Event{Type: EventCancelled, Expression: expression})
+			// выражения, которые ссылались на отменённое, теперь можно выдать: они завершатся с ошибкой
+			select {
+			case w.finished <- struct{}{}:
+			default:
+			}
+		}
+		return cancelled, err
+	}
+	w.mu.Unlock()
+	job.cancel()
+	<-job.done
+	return true, nil
+}
+
+// setStatus записывает в выражение статус по ошибке вычисления err.
+func setStatus(expression *dto.Expression, err error) {
+	switch {
+	case err == nil:
+		expression.Status = StatusOK
+	case errors.Is(err, ErrCancelled):
+		expression.Status = StatusCancelled
+		expression.ErrorCode = ErrorCode(err)
+		expression.ErrorMessage = err.Error()
+	default:
+		expression.Status = fmt.Sprintf("Ошибка: %v", err)
+		expression.ErrorCode = ErrorCode(err)
+		expression.ErrorMessage = err.Error()
+	}
+}
+
+// renew продлевает аренду выражения id, пока не будет вызвана возвращённая функция.
+func (w *Worker) renew(id int) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.jobLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := w.storage.RenewJob(id, w.jobLease); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// outcome — результат вычисления выражения.
+type outcome struct {
+	result float64
+	// value — точная запись результата в режимах rational и decimal
+	value string
+	// inexact — часть точного вычисления выполнена в float64
+	inexact bool
+}
+
+// calc вычисляет выражение с переменными и функциями пользователя и сохраняет результат присваивания.
+// Кроме значения float64 возвращается точная запись результата для режимов rational и decimal.
+func (w *Worker) calc(ctx context.Context, expression dto.Expression) (outcome, error) {
+	name, root, err := parseStatement(expression.Expression)
+	if err != nil {
+		return outcome{}, err
+	}
+	variables, err := w.storage.GetVariables(expression.UserID)
+	if err != nil {
+		return outcome{}, err
+	}
+	functions, err := w.storage.GetFunctions(expression.UserID)
+	if err != nil {
+		return outcome{}, err
+	}
+	env := NewEnv(variables, functions)
+	env.limitOperations(w.limits.MaxOperations)
+	if err := w.references(expression, env); err != nil {
+		return outcome{result: -1}, err
+	}
+	out, err := w.evaluate(ctx, expression, root, env)
+	if err != nil {
+		return outcome{result: out.result}, err
+	}
+	if name != "" {
+		err = w.storage.SetVariable(dto.Variable{UserID: expression.UserID, Name: name, Value: out.result})
 		if err != nil {
-			expression.Status = fmt.Sprintf("Ошибка: %v", err)
-		} else {
-			expression.Status = "Ok"
+			return out, err
 		}
-		expression.Result = calc
-		err = w.storage.UpdateExpression(expression)
+	}
+	return out, nil
+}
+
+// references подставляет в env результаты выражений, на которые ссылается expression.
+// Выражение не выдаётся из очереди, пока его зависимости не завершены, поэтому здесь
+// остаётся проверить, что они вычислены без ошибки.
+func (w *Worker) references(expression dto.Expression, env *Env) error {
+	for _, name := range slices.Sorted(maps.Keys(expression.References)) {
+		id := expression.References[name]
+		dependency, ok, err := w.storage.GetExpression(id)
 		if err != nil {
-			log.Println(err)
+			return err
 		}
+		if !ok || dependency.UserID != expression.UserID {
+			return errorf(ErrDependency, "%s: expression not found", name)
+		}
+		switch EventType(dependency) {
+		case EventDone:
+			env.setReference(name, dependency, expression.Mode)
+		case EventCancelled:
+			return errorf(ErrDependency, "%s: expression %d was cancelled", name, id)
+		case EventError:
+			return errorf(ErrDependency, "%s: expression %d failed: %s", name, id, dependency.ErrorMessage)
+		default:
+			return errorf(ErrDependency, "%s: expression %d is not computed yet", name, id)
+		}
+	}
+	return nil
+}
+
+// evaluate вычисляет дерево root выражения expression по графу задач. Для режимов rational и decimal
+// кроме значения float64 возвращается точная запись результата.
+func (w *Worker) evaluate(ctx context.Context, expression dto.Expression, root node, env *Env) (outcome, error) {
+	mode, precision := expression.Mode, expression.Precision
+	nodes, err := buildGraph(root, env)
+	if err != nil {
+		return outcome{result: -1}, err
+	}
+	literal, err := runGraph(ctx, nodes, env, mode, func(task Task) {
+		task.user = expression.UserID
+		task.priority = dto.PriorityRank(expression.Priority)
+		w.tasks.Submit(task)
+	}, func(done, total int) {
+		w.Events.Publish(Event{Type: EventProgress, Expression: expression, Done: done, Total: total})
+	})
+	if err != nil {
+		return outcome{result: -1}, err
+	}
+	if !IsExact(mode) {
+		result, err := floatArithmetic{}.parse(literal)
+		if err != nil {
+			return outcome{result: -1}, err
+		}
+		return outcome{result: result}, nil
+	}
+	exact, err := ratArithmetic{}.parse(literal)
+	if err != nil {
+		return outcome{result: -1}, err
 	}
+	result, _ := exact.Float64()
+	return outcome{result: result, value: FormatExact(exact, mode, precision), inexact: env.Inexact()}, nil
 }
@@ -18,6 +18,11 @@ func (rw *responseWrapper) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap даёт http.ResponseController добраться до исходного ответа, например для Flush в потоках событий.
+func (rw *responseWrapper) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func RecoverMiddleware(next http.Handler) http.Handler { // ловим панику
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
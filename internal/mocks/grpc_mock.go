@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	proto "github.com/philipslstwoyears/calculator-go/proto"
 	grpc "google.golang.org/grpc"
+	metadata "google.golang.org/grpc/metadata"
 )
 
 // MockCalcServiceClient is a mock of CalcServiceClient interface.
@@ -36,6 +37,26 @@ func (m *MockCalcServiceClient) EXPECT() *MockCalcServiceClientMockRecorder {
 	return m.recorder
 }
 
+// AddWebhook mocks base method.
+func (m *MockCalcServiceClient) AddWebhook(ctx context.Context, in *proto.Webhook, opts ...grpc.CallOption) (*proto.Webhook, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AddWebhook", varargs...)
+	ret0, _ := ret[0].(*proto.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockCalcServiceClientMockRecorder) AddWebhook(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockCalcServiceClient)(nil).AddWebhook), varargs...)
+}
+
 // Calc mocks base method.
 func (m *MockCalcServiceClient) Calc(ctx context.Context, in *proto.Request, opts ...grpc.CallOption) (*proto.Id, error) {
 	m.ctrl.T.Helper()
@@ -56,6 +77,126 @@ func (mr *MockCalcServiceClientMockRecorder) Calc(ctx, in interface{}, opts ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calc", reflect.TypeOf((*MockCalcServiceClient)(nil).Calc), varargs...)
 }
 
+// CalcBatch mocks base method.
+func (m *MockCalcServiceClient) CalcBatch(ctx context.Context, in *proto.BatchRequest, opts ...grpc.CallOption) (*proto.BatchResult, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CalcBatch", varargs...)
+	ret0, _ := ret[0].(*proto.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CalcBatch indicates an expected call of CalcBatch.
+func (mr *MockCalcServiceClientMockRecorder) CalcBatch(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalcBatch", reflect.TypeOf((*MockCalcServiceClient)(nil).CalcBatch), varargs...)
+}
+
+// CancelExpression mocks base method.
+func (m *MockCalcServiceClient) CancelExpression(ctx context.Context, in *proto.Expression, opts ...grpc.CallOption) (*proto.Expression, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CancelExpression", varargs...)
+	ret0, _ := ret[0].(*proto.Expression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelExpression indicates an expected call of CancelExpression.
+func (mr *MockCalcServiceClientMockRecorder) CancelExpression(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelExpression", reflect.TypeOf((*MockCalcServiceClient)(nil).CancelExpression), varargs...)
+}
+
+// DeleteFunction mocks base method.
+func (m *MockCalcServiceClient) DeleteFunction(ctx context.Context, in *proto.Function, opts ...grpc.CallOption) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteFunction", varargs...)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFunction indicates an expected call of DeleteFunction.
+func (mr *MockCalcServiceClientMockRecorder) DeleteFunction(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFunction", reflect.TypeOf((*MockCalcServiceClient)(nil).DeleteFunction), varargs...)
+}
+
+// DeleteVariable mocks base method.
+func (m *MockCalcServiceClient) DeleteVariable(ctx context.Context, in *proto.Variable, opts ...grpc.CallOption) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteVariable", varargs...)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteVariable indicates an expected call of DeleteVariable.
+func (mr *MockCalcServiceClientMockRecorder) DeleteVariable(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariable", reflect.TypeOf((*MockCalcServiceClient)(nil).DeleteVariable), varargs...)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockCalcServiceClient) DeleteWebhook(ctx context.Context, in *proto.Webhook, opts ...grpc.CallOption) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteWebhook", varargs...)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockCalcServiceClientMockRecorder) DeleteWebhook(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockCalcServiceClient)(nil).DeleteWebhook), varargs...)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockCalcServiceClient) GetDeliveries(ctx context.Context, in *proto.DeliveryRequest, opts ...grpc.CallOption) (*proto.Deliveries, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetDeliveries", varargs...)
+	ret0, _ := ret[0].(*proto.Deliveries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockCalcServiceClientMockRecorder) GetDeliveries(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockCalcServiceClient)(nil).GetDeliveries), varargs...)
+}
+
 // GetExpression mocks base method.
 func (m *MockCalcServiceClient) GetExpression(ctx context.Context, in *proto.Id, opts ...grpc.CallOption) (*proto.Expression, error) {
 	m.ctrl.T.Helper()
@@ -96,183 +237,1057 @@ func (mr *MockCalcServiceClientMockRecorder) GetExpressions(ctx, in interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpressions", reflect.TypeOf((*MockCalcServiceClient)(nil).GetExpressions), varargs...)
 }
 
-// Login mocks base method.
-func (m *MockCalcServiceClient) Login(ctx context.Context, in *proto.User, opts ...grpc.CallOption) (*proto.Id, error) {
+// GetFunction mocks base method.
+func (m *MockCalcServiceClient) GetFunction(ctx context.Context, in *proto.Function, opts ...grpc.CallOption) (*proto.Function, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, in}
 	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "Login", varargs...)
-	ret0, _ := ret[0].(*proto.Id)
+	ret := m.ctrl.Call(m, "GetFunction", varargs...)
+	ret0, _ := ret[0].(*proto.Function)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Login indicates an expected call of Login.
-func (mr *MockCalcServiceClientMockRecorder) Login(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+// GetFunction indicates an expected call of GetFunction.
+func (mr *MockCalcServiceClientMockRecorder) GetFunction(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, in}, opts...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockCalcServiceClient)(nil).Login), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunction", reflect.TypeOf((*MockCalcServiceClient)(nil).GetFunction), varargs...)
 }
 
-// Register mocks base method.
-func (m *MockCalcServiceClient) Register(ctx context.Context, in *proto.User, opts ...grpc.CallOption) (*proto.Id, error) {
+// GetFunctions mocks base method.
+func (m *MockCalcServiceClient) GetFunctions(ctx context.Context, in *proto.Id, opts ...grpc.CallOption) (*proto.Functions, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, in}
 	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "Register", varargs...)
-	ret0, _ := ret[0].(*proto.Id)
+	ret := m.ctrl.Call(m, "GetFunctions", varargs...)
+	ret0, _ := ret[0].(*proto.Functions)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Register indicates an expected call of Register.
-func (mr *MockCalcServiceClientMockRecorder) Register(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+// GetFunctions indicates an expected call of GetFunctions.
+func (mr *MockCalcServiceClientMockRecorder) GetFunctions(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, in}, opts...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockCalcServiceClient)(nil).Register), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunctions", reflect.TypeOf((*MockCalcServiceClient)(nil).GetFunctions), varargs...)
 }
 
-// MockCalcServiceServer is a mock of CalcServiceServer interface.
-type MockCalcServiceServer struct {
-	ctrl     *gomock.Controller
-	recorder *MockCalcServiceServerMockRecorder
+// GetPool mocks base method.
+func (m *MockCalcServiceClient) GetPool(ctx context.Context, in *proto.Empty, opts ...grpc.CallOption) (*proto.Pool, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetPool", varargs...)
+	ret0, _ := ret[0].(*proto.Pool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockCalcServiceServerMockRecorder is the mock recorder for MockCalcServiceServer.
-type MockCalcServiceServerMockRecorder struct {
-	mock *MockCalcServiceServer
+// GetPool indicates an expected call of GetPool.
+func (mr *MockCalcServiceClientMockRecorder) GetPool(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPool", reflect.TypeOf((*MockCalcServiceClient)(nil).GetPool), varargs...)
 }
 
-// NewMockCalcServiceServer creates a new mock instance.
-func NewMockCalcServiceServer(ctrl *gomock.Controller) *MockCalcServiceServer {
-	mock := &MockCalcServiceServer{ctrl: ctrl}
-	mock.recorder = &MockCalcServiceServerMockRecorder{mock}
-	return mock
+// GetQueue mocks base method.
+func (m *MockCalcServiceClient) GetQueue(ctx context.Context, in *proto.Empty, opts ...grpc.CallOption) (*proto.Queue, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetQueue", varargs...)
+	ret0, _ := ret[0].(*proto.Queue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockCalcServiceServer) EXPECT() *MockCalcServiceServerMockRecorder {
-	return m.recorder
+// GetQueue indicates an expected call of GetQueue.
+func (mr *MockCalcServiceClientMockRecorder) GetQueue(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueue", reflect.TypeOf((*MockCalcServiceClient)(nil).GetQueue), varargs...)
 }
 
-// Calc mocks base method.
-func (m *MockCalcServiceServer) Calc(arg0 context.Context, arg1 *proto.Request) (*proto.Id, error) {
+// GetTask mocks base method.
+func (m *MockCalcServiceClient) GetTask(ctx context.Context, in *proto.TaskRequest, opts ...grpc.CallOption) (*proto.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Calc", arg0, arg1)
-	ret0, _ := ret[0].(*proto.Id)
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetTask", varargs...)
+	ret0, _ := ret[0].(*proto.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Calc indicates an expected call of Calc.
-func (mr *MockCalcServiceServerMockRecorder) Calc(arg0, arg1 interface{}) *gomock.Call {
+// GetTask indicates an expected call of GetTask.
+func (mr *MockCalcServiceClientMockRecorder) GetTask(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calc", reflect.TypeOf((*MockCalcServiceServer)(nil).Calc), arg0, arg1)
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*MockCalcServiceClient)(nil).GetTask), varargs...)
 }
 
-// GetExpression mocks base method.
-func (m *MockCalcServiceServer) GetExpression(arg0 context.Context, arg1 *proto.Id) (*proto.Expression, error) {
+// GetVariables mocks base method.
+func (m *MockCalcServiceClient) GetVariables(ctx context.Context, in *proto.Id, opts ...grpc.CallOption) (*proto.Variables, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetExpression", arg0, arg1)
-	ret0, _ := ret[0].(*proto.Expression)
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetVariables", varargs...)
+	ret0, _ := ret[0].(*proto.Variables)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetExpression indicates an expected call of GetExpression.
-func (mr *MockCalcServiceServerMockRecorder) GetExpression(arg0, arg1 interface{}) *gomock.Call {
+// GetVariables indicates an expected call of GetVariables.
+func (mr *MockCalcServiceClientMockRecorder) GetVariables(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpression", reflect.TypeOf((*MockCalcServiceServer)(nil).GetExpression), arg0, arg1)
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVariables", reflect.TypeOf((*MockCalcServiceClient)(nil).GetVariables), varargs...)
 }
 
-// GetExpressions mocks base method.
-func (m *MockCalcServiceServer) GetExpressions(arg0 context.Context, arg1 *proto.Id) (*proto.Expressions, error) {
+// GetWebhooks mocks base method.
+func (m *MockCalcServiceClient) GetWebhooks(ctx context.Context, in *proto.Id, opts ...grpc.CallOption) (*proto.Webhooks, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetExpressions", arg0, arg1)
-	ret0, _ := ret[0].(*proto.Expressions)
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetWebhooks", varargs...)
+	ret0, _ := ret[0].(*proto.Webhooks)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetExpressions indicates an expected call of GetExpressions.
-func (mr *MockCalcServiceServerMockRecorder) GetExpressions(arg0, arg1 interface{}) *gomock.Call {
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockCalcServiceClientMockRecorder) GetWebhooks(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpressions", reflect.TypeOf((*MockCalcServiceServer)(nil).GetExpressions), arg0, arg1)
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockCalcServiceClient)(nil).GetWebhooks), varargs...)
 }
 
 // Login mocks base method.
-func (m *MockCalcServiceServer) Login(arg0 context.Context, arg1 *proto.User) (*proto.Id, error) {
+func (m *MockCalcServiceClient) Login(ctx context.Context, in *proto.User, opts ...grpc.CallOption) (*proto.Id, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", arg0, arg1)
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Login", varargs...)
 	ret0, _ := ret[0].(*proto.Id)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Login indicates an expected call of Login.
-func (mr *MockCalcServiceServerMockRecorder) Login(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCalcServiceClientMockRecorder) Login(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockCalcServiceServer)(nil).Login), arg0, arg1)
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockCalcServiceClient)(nil).Login), varargs...)
 }
 
 // Register mocks base method.
-func (m *MockCalcServiceServer) Register(arg0 context.Context, arg1 *proto.User) (*proto.Id, error) {
+func (m *MockCalcServiceClient) Register(ctx context.Context, in *proto.User, opts ...grpc.CallOption) (*proto.Id, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Register", arg0, arg1)
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Register", varargs...)
 	ret0, _ := ret[0].(*proto.Id)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Register indicates an expected call of Register.
-func (mr *MockCalcServiceServerMockRecorder) Register(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCalcServiceClientMockRecorder) Register(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockCalcServiceServer)(nil).Register), arg0, arg1)
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockCalcServiceClient)(nil).Register), varargs...)
 }
 
-// mustEmbedUnimplementedCalcServiceServer mocks base method.
-func (m *MockCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {
+// ResizePool mocks base method.
+func (m *MockCalcServiceClient) ResizePool(ctx context.Context, in *proto.PoolSize, opts ...grpc.CallOption) (*proto.Pool, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "mustEmbedUnimplementedCalcServiceServer")
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResizePool", varargs...)
+	ret0, _ := ret[0].(*proto.Pool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// mustEmbedUnimplementedCalcServiceServer indicates an expected call of mustEmbedUnimplementedCalcServiceServer.
-func (mr *MockCalcServiceServerMockRecorder) mustEmbedUnimplementedCalcServiceServer() *gomock.Call {
+// ResizePool indicates an expected call of ResizePool.
+func (mr *MockCalcServiceClientMockRecorder) ResizePool(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCalcServiceServer", reflect.TypeOf((*MockCalcServiceServer)(nil).mustEmbedUnimplementedCalcServiceServer))
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizePool", reflect.TypeOf((*MockCalcServiceClient)(nil).ResizePool), varargs...)
 }
 
-// MockUnsafeCalcServiceServer is a mock of UnsafeCalcServiceServer interface.
-type MockUnsafeCalcServiceServer struct {
-	ctrl     *gomock.Controller
-	recorder *MockUnsafeCalcServiceServerMockRecorder
+// RotateWebhookSecret mocks base method.
+func (m *MockCalcServiceClient) RotateWebhookSecret(ctx context.Context, in *proto.Id, opts ...grpc.CallOption) (*proto.WebhookSecret, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RotateWebhookSecret", varargs...)
+	ret0, _ := ret[0].(*proto.WebhookSecret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockUnsafeCalcServiceServerMockRecorder is the mock recorder for MockUnsafeCalcServiceServer.
-type MockUnsafeCalcServiceServerMockRecorder struct {
-	mock *MockUnsafeCalcServiceServer
+// RotateWebhookSecret indicates an expected call of RotateWebhookSecret.
+func (mr *MockCalcServiceClientMockRecorder) RotateWebhookSecret(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateWebhookSecret", reflect.TypeOf((*MockCalcServiceClient)(nil).RotateWebhookSecret), varargs...)
 }
 
-// NewMockUnsafeCalcServiceServer creates a new mock instance.
-func NewMockUnsafeCalcServiceServer(ctrl *gomock.Controller) *MockUnsafeCalcServiceServer {
-	mock := &MockUnsafeCalcServiceServer{ctrl: ctrl}
-	mock.recorder = &MockUnsafeCalcServiceServerMockRecorder{mock}
-	return mock
+// SetFunction mocks base method.
+func (m *MockCalcServiceClient) SetFunction(ctx context.Context, in *proto.Function, opts ...grpc.CallOption) (*proto.Function, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SetFunction", varargs...)
+	ret0, _ := ret[0].(*proto.Function)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockUnsafeCalcServiceServer) EXPECT() *MockUnsafeCalcServiceServerMockRecorder {
-	return m.recorder
+// SetFunction indicates an expected call of SetFunction.
+func (mr *MockCalcServiceClientMockRecorder) SetFunction(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFunction", reflect.TypeOf((*MockCalcServiceClient)(nil).SetFunction), varargs...)
 }
 
-// mustEmbedUnimplementedCalcServiceServer mocks base method.
-func (m *MockUnsafeCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {
+// SetVariable mocks base method.
+func (m *MockCalcServiceClient) SetVariable(ctx context.Context, in *proto.Variable, opts ...grpc.CallOption) (*proto.Variable, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "mustEmbedUnimplementedCalcServiceServer")
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SetVariable", varargs...)
+	ret0, _ := ret[0].(*proto.Variable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetVariable indicates an expected call of SetVariable.
+func (mr *MockCalcServiceClientMockRecorder) SetVariable(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVariable", reflect.TypeOf((*MockCalcServiceClient)(nil).SetVariable), varargs...)
+}
+
+// SubmitResult mocks base method.
+func (m *MockCalcServiceClient) SubmitResult(ctx context.Context, in *proto.TaskResult, opts ...grpc.CallOption) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SubmitResult", varargs...)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitResult indicates an expected call of SubmitResult.
+func (mr *MockCalcServiceClientMockRecorder) SubmitResult(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitResult", reflect.TypeOf((*MockCalcServiceClient)(nil).SubmitResult), varargs...)
+}
+
+// WaitExpression mocks base method.
+func (m *MockCalcServiceClient) WaitExpression(ctx context.Context, in *proto.WaitRequest, opts ...grpc.CallOption) (*proto.Expression, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WaitExpression", varargs...)
+	ret0, _ := ret[0].(*proto.Expression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitExpression indicates an expected call of WaitExpression.
+func (mr *MockCalcServiceClientMockRecorder) WaitExpression(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitExpression", reflect.TypeOf((*MockCalcServiceClient)(nil).WaitExpression), varargs...)
+}
+
+// WatchExpression mocks base method.
+func (m *MockCalcServiceClient) WatchExpression(ctx context.Context, in *proto.WatchExpressionRequest, opts ...grpc.CallOption) (proto.CalcService_WatchExpressionClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WatchExpression", varargs...)
+	ret0, _ := ret[0].(proto.CalcService_WatchExpressionClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchExpression indicates an expected call of WatchExpression.
+func (mr *MockCalcServiceClientMockRecorder) WatchExpression(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchExpression", reflect.TypeOf((*MockCalcServiceClient)(nil).WatchExpression), varargs...)
+}
+
+// WatchExpressions mocks base method.
+func (m *MockCalcServiceClient) WatchExpressions(ctx context.Context, in *proto.WatchRequest, opts ...grpc.CallOption) (proto.CalcService_WatchExpressionsClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WatchExpressions", varargs...)
+	ret0, _ := ret[0].(proto.CalcService_WatchExpressionsClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchExpressions indicates an expected call of WatchExpressions.
+func (mr *MockCalcServiceClientMockRecorder) WatchExpressions(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchExpressions", reflect.TypeOf((*MockCalcServiceClient)(nil).WatchExpressions), varargs...)
+}
+
+// MockCalcService_WatchExpressionClient is a mock of CalcService_WatchExpressionClient interface.
+type MockCalcService_WatchExpressionClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockCalcService_WatchExpressionClientMockRecorder
+}
+
+// MockCalcService_WatchExpressionClientMockRecorder is the mock recorder for MockCalcService_WatchExpressionClient.
+type MockCalcService_WatchExpressionClientMockRecorder struct {
+	mock *MockCalcService_WatchExpressionClient
+}
+
+// NewMockCalcService_WatchExpressionClient creates a new mock instance.
+func NewMockCalcService_WatchExpressionClient(ctrl *gomock.Controller) *MockCalcService_WatchExpressionClient {
+	mock := &MockCalcService_WatchExpressionClient{ctrl: ctrl}
+	mock.recorder = &MockCalcService_WatchExpressionClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCalcService_WatchExpressionClient) EXPECT() *MockCalcService_WatchExpressionClientMockRecorder {
+	return m.recorder
+}
+
+// CloseSend mocks base method.
+func (m *MockCalcService_WatchExpressionClient) CloseSend() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSend")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSend indicates an expected call of CloseSend.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) CloseSend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSend", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).CloseSend))
+}
+
+// Context mocks base method.
+func (m *MockCalcService_WatchExpressionClient) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).Context))
+}
+
+// Header mocks base method.
+func (m *MockCalcService_WatchExpressionClient) Header() (metadata.MD, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(metadata.MD)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).Header))
+}
+
+// Recv mocks base method.
+func (m *MockCalcService_WatchExpressionClient) Recv() (*proto.ExpressionEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*proto.ExpressionEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).Recv))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionClient) RecvMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).RecvMsg), m)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionClient) SendMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).SendMsg), m)
+}
+
+// Trailer mocks base method.
+func (m *MockCalcService_WatchExpressionClient) Trailer() metadata.MD {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trailer")
+	ret0, _ := ret[0].(metadata.MD)
+	return ret0
+}
+
+// Trailer indicates an expected call of Trailer.
+func (mr *MockCalcService_WatchExpressionClientMockRecorder) Trailer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trailer", reflect.TypeOf((*MockCalcService_WatchExpressionClient)(nil).Trailer))
+}
+
+// MockCalcService_WatchExpressionsClient is a mock of CalcService_WatchExpressionsClient interface.
+type MockCalcService_WatchExpressionsClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockCalcService_WatchExpressionsClientMockRecorder
+}
+
+// MockCalcService_WatchExpressionsClientMockRecorder is the mock recorder for MockCalcService_WatchExpressionsClient.
+type MockCalcService_WatchExpressionsClientMockRecorder struct {
+	mock *MockCalcService_WatchExpressionsClient
+}
+
+// NewMockCalcService_WatchExpressionsClient creates a new mock instance.
+func NewMockCalcService_WatchExpressionsClient(ctrl *gomock.Controller) *MockCalcService_WatchExpressionsClient {
+	mock := &MockCalcService_WatchExpressionsClient{ctrl: ctrl}
+	mock.recorder = &MockCalcService_WatchExpressionsClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCalcService_WatchExpressionsClient) EXPECT() *MockCalcService_WatchExpressionsClientMockRecorder {
+	return m.recorder
+}
+
+// CloseSend mocks base method.
+func (m *MockCalcService_WatchExpressionsClient) CloseSend() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSend")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSend indicates an expected call of CloseSend.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) CloseSend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSend", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).CloseSend))
+}
+
+// Context mocks base method.
+func (m *MockCalcService_WatchExpressionsClient) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).Context))
+}
+
+// Header mocks base method.
+func (m *MockCalcService_WatchExpressionsClient) Header() (metadata.MD, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(metadata.MD)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).Header))
+}
+
+// Recv mocks base method.
+func (m *MockCalcService_WatchExpressionsClient) Recv() (*proto.ExpressionEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*proto.ExpressionEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).Recv))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionsClient) RecvMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).RecvMsg), m)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionsClient) SendMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).SendMsg), m)
+}
+
+// Trailer mocks base method.
+func (m *MockCalcService_WatchExpressionsClient) Trailer() metadata.MD {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trailer")
+	ret0, _ := ret[0].(metadata.MD)
+	return ret0
+}
+
+// Trailer indicates an expected call of Trailer.
+func (mr *MockCalcService_WatchExpressionsClientMockRecorder) Trailer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trailer", reflect.TypeOf((*MockCalcService_WatchExpressionsClient)(nil).Trailer))
+}
+
+// MockCalcServiceServer is a mock of CalcServiceServer interface.
+type MockCalcServiceServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCalcServiceServerMockRecorder
+}
+
+// MockCalcServiceServerMockRecorder is the mock recorder for MockCalcServiceServer.
+type MockCalcServiceServerMockRecorder struct {
+	mock *MockCalcServiceServer
+}
+
+// NewMockCalcServiceServer creates a new mock instance.
+func NewMockCalcServiceServer(ctrl *gomock.Controller) *MockCalcServiceServer {
+	mock := &MockCalcServiceServer{ctrl: ctrl}
+	mock.recorder = &MockCalcServiceServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCalcServiceServer) EXPECT() *MockCalcServiceServerMockRecorder {
+	return m.recorder
+}
+
+// AddWebhook mocks base method.
+func (m *MockCalcServiceServer) AddWebhook(arg0 context.Context, arg1 *proto.Webhook) (*proto.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWebhook", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockCalcServiceServerMockRecorder) AddWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockCalcServiceServer)(nil).AddWebhook), arg0, arg1)
+}
+
+// Calc mocks base method.
+func (m *MockCalcServiceServer) Calc(arg0 context.Context, arg1 *proto.Request) (*proto.Id, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Calc", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Id)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Calc indicates an expected call of Calc.
+func (mr *MockCalcServiceServerMockRecorder) Calc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calc", reflect.TypeOf((*MockCalcServiceServer)(nil).Calc), arg0, arg1)
+}
+
+// CalcBatch mocks base method.
+func (m *MockCalcServiceServer) CalcBatch(arg0 context.Context, arg1 *proto.BatchRequest) (*proto.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CalcBatch", arg0, arg1)
+	ret0, _ := ret[0].(*proto.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CalcBatch indicates an expected call of CalcBatch.
+func (mr *MockCalcServiceServerMockRecorder) CalcBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalcBatch", reflect.TypeOf((*MockCalcServiceServer)(nil).CalcBatch), arg0, arg1)
+}
+
+// CancelExpression mocks base method.
+func (m *MockCalcServiceServer) CancelExpression(arg0 context.Context, arg1 *proto.Expression) (*proto.Expression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelExpression", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Expression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelExpression indicates an expected call of CancelExpression.
+func (mr *MockCalcServiceServerMockRecorder) CancelExpression(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelExpression", reflect.TypeOf((*MockCalcServiceServer)(nil).CancelExpression), arg0, arg1)
+}
+
+// DeleteFunction mocks base method.
+func (m *MockCalcServiceServer) DeleteFunction(arg0 context.Context, arg1 *proto.Function) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFunction", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFunction indicates an expected call of DeleteFunction.
+func (mr *MockCalcServiceServerMockRecorder) DeleteFunction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFunction", reflect.TypeOf((*MockCalcServiceServer)(nil).DeleteFunction), arg0, arg1)
+}
+
+// DeleteVariable mocks base method.
+func (m *MockCalcServiceServer) DeleteVariable(arg0 context.Context, arg1 *proto.Variable) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariable", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteVariable indicates an expected call of DeleteVariable.
+func (mr *MockCalcServiceServerMockRecorder) DeleteVariable(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariable", reflect.TypeOf((*MockCalcServiceServer)(nil).DeleteVariable), arg0, arg1)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockCalcServiceServer) DeleteWebhook(arg0 context.Context, arg1 *proto.Webhook) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockCalcServiceServerMockRecorder) DeleteWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockCalcServiceServer)(nil).DeleteWebhook), arg0, arg1)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockCalcServiceServer) GetDeliveries(arg0 context.Context, arg1 *proto.DeliveryRequest) (*proto.Deliveries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveries", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Deliveries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockCalcServiceServerMockRecorder) GetDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockCalcServiceServer)(nil).GetDeliveries), arg0, arg1)
+}
+
+// GetExpression mocks base method.
+func (m *MockCalcServiceServer) GetExpression(arg0 context.Context, arg1 *proto.Id) (*proto.Expression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpression", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Expression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpression indicates an expected call of GetExpression.
+func (mr *MockCalcServiceServerMockRecorder) GetExpression(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpression", reflect.TypeOf((*MockCalcServiceServer)(nil).GetExpression), arg0, arg1)
+}
+
+// GetExpressions mocks base method.
+func (m *MockCalcServiceServer) GetExpressions(arg0 context.Context, arg1 *proto.Id) (*proto.Expressions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpressions", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Expressions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpressions indicates an expected call of GetExpressions.
+func (mr *MockCalcServiceServerMockRecorder) GetExpressions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpressions", reflect.TypeOf((*MockCalcServiceServer)(nil).GetExpressions), arg0, arg1)
+}
+
+// GetFunction mocks base method.
+func (m *MockCalcServiceServer) GetFunction(arg0 context.Context, arg1 *proto.Function) (*proto.Function, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFunction", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Function)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFunction indicates an expected call of GetFunction.
+func (mr *MockCalcServiceServerMockRecorder) GetFunction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunction", reflect.TypeOf((*MockCalcServiceServer)(nil).GetFunction), arg0, arg1)
+}
+
+// GetFunctions mocks base method.
+func (m *MockCalcServiceServer) GetFunctions(arg0 context.Context, arg1 *proto.Id) (*proto.Functions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFunctions", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Functions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFunctions indicates an expected call of GetFunctions.
+func (mr *MockCalcServiceServerMockRecorder) GetFunctions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunctions", reflect.TypeOf((*MockCalcServiceServer)(nil).GetFunctions), arg0, arg1)
+}
+
+// GetPool mocks base method.
+func (m *MockCalcServiceServer) GetPool(arg0 context.Context, arg1 *proto.Empty) (*proto.Pool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPool", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Pool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPool indicates an expected call of GetPool.
+func (mr *MockCalcServiceServerMockRecorder) GetPool(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPool", reflect.TypeOf((*MockCalcServiceServer)(nil).GetPool), arg0, arg1)
+}
+
+// GetQueue mocks base method.
+func (m *MockCalcServiceServer) GetQueue(arg0 context.Context, arg1 *proto.Empty) (*proto.Queue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQueue", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Queue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQueue indicates an expected call of GetQueue.
+func (mr *MockCalcServiceServerMockRecorder) GetQueue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueue", reflect.TypeOf((*MockCalcServiceServer)(nil).GetQueue), arg0, arg1)
+}
+
+// GetTask mocks base method.
+func (m *MockCalcServiceServer) GetTask(arg0 context.Context, arg1 *proto.TaskRequest) (*proto.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTask", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTask indicates an expected call of GetTask.
+func (mr *MockCalcServiceServerMockRecorder) GetTask(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*MockCalcServiceServer)(nil).GetTask), arg0, arg1)
+}
+
+// GetVariables mocks base method.
+func (m *MockCalcServiceServer) GetVariables(arg0 context.Context, arg1 *proto.Id) (*proto.Variables, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVariables", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Variables)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVariables indicates an expected call of GetVariables.
+func (mr *MockCalcServiceServerMockRecorder) GetVariables(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVariables", reflect.TypeOf((*MockCalcServiceServer)(nil).GetVariables), arg0, arg1)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockCalcServiceServer) GetWebhooks(arg0 context.Context, arg1 *proto.Id) (*proto.Webhooks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Webhooks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockCalcServiceServerMockRecorder) GetWebhooks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockCalcServiceServer)(nil).GetWebhooks), arg0, arg1)
+}
+
+// Login mocks base method.
+func (m *MockCalcServiceServer) Login(arg0 context.Context, arg1 *proto.User) (*proto.Id, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Id)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockCalcServiceServerMockRecorder) Login(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockCalcServiceServer)(nil).Login), arg0, arg1)
+}
+
+// Register mocks base method.
+func (m *MockCalcServiceServer) Register(arg0 context.Context, arg1 *proto.User) (*proto.Id, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Id)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockCalcServiceServerMockRecorder) Register(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockCalcServiceServer)(nil).Register), arg0, arg1)
+}
+
+// ResizePool mocks base method.
+func (m *MockCalcServiceServer) ResizePool(arg0 context.Context, arg1 *proto.PoolSize) (*proto.Pool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResizePool", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Pool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResizePool indicates an expected call of ResizePool.
+func (mr *MockCalcServiceServerMockRecorder) ResizePool(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizePool", reflect.TypeOf((*MockCalcServiceServer)(nil).ResizePool), arg0, arg1)
+}
+
+// RotateWebhookSecret mocks base method.
+func (m *MockCalcServiceServer) RotateWebhookSecret(arg0 context.Context, arg1 *proto.Id) (*proto.WebhookSecret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateWebhookSecret", arg0, arg1)
+	ret0, _ := ret[0].(*proto.WebhookSecret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateWebhookSecret indicates an expected call of RotateWebhookSecret.
+func (mr *MockCalcServiceServerMockRecorder) RotateWebhookSecret(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateWebhookSecret", reflect.TypeOf((*MockCalcServiceServer)(nil).RotateWebhookSecret), arg0, arg1)
+}
+
+// SetFunction mocks base method.
+func (m *MockCalcServiceServer) SetFunction(arg0 context.Context, arg1 *proto.Function) (*proto.Function, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFunction", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Function)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetFunction indicates an expected call of SetFunction.
+func (mr *MockCalcServiceServerMockRecorder) SetFunction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFunction", reflect.TypeOf((*MockCalcServiceServer)(nil).SetFunction), arg0, arg1)
+}
+
+// SetVariable mocks base method.
+func (m *MockCalcServiceServer) SetVariable(arg0 context.Context, arg1 *proto.Variable) (*proto.Variable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVariable", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Variable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetVariable indicates an expected call of SetVariable.
+func (mr *MockCalcServiceServerMockRecorder) SetVariable(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVariable", reflect.TypeOf((*MockCalcServiceServer)(nil).SetVariable), arg0, arg1)
+}
+
+// SubmitResult mocks base method.
+func (m *MockCalcServiceServer) SubmitResult(arg0 context.Context, arg1 *proto.TaskResult) (*proto.Empty, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitResult", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Empty)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitResult indicates an expected call of SubmitResult.
+func (mr *MockCalcServiceServerMockRecorder) SubmitResult(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitResult", reflect.TypeOf((*MockCalcServiceServer)(nil).SubmitResult), arg0, arg1)
+}
+
+// WaitExpression mocks base method.
+func (m *MockCalcServiceServer) WaitExpression(arg0 context.Context, arg1 *proto.WaitRequest) (*proto.Expression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitExpression", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Expression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitExpression indicates an expected call of WaitExpression.
+func (mr *MockCalcServiceServerMockRecorder) WaitExpression(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitExpression", reflect.TypeOf((*MockCalcServiceServer)(nil).WaitExpression), arg0, arg1)
+}
+
+// WatchExpression mocks base method.
+func (m *MockCalcServiceServer) WatchExpression(arg0 *proto.WatchExpressionRequest, arg1 proto.CalcService_WatchExpressionServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchExpression", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchExpression indicates an expected call of WatchExpression.
+func (mr *MockCalcServiceServerMockRecorder) WatchExpression(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchExpression", reflect.TypeOf((*MockCalcServiceServer)(nil).WatchExpression), arg0, arg1)
+}
+
+// WatchExpressions mocks base method.
+func (m *MockCalcServiceServer) WatchExpressions(arg0 *proto.WatchRequest, arg1 proto.CalcService_WatchExpressionsServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchExpressions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchExpressions indicates an expected call of WatchExpressions.
+func (mr *MockCalcServiceServerMockRecorder) WatchExpressions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchExpressions", reflect.TypeOf((*MockCalcServiceServer)(nil).WatchExpressions), arg0, arg1)
+}
+
+// mustEmbedUnimplementedCalcServiceServer mocks base method.
+func (m *MockCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "mustEmbedUnimplementedCalcServiceServer")
+}
+
+// mustEmbedUnimplementedCalcServiceServer indicates an expected call of mustEmbedUnimplementedCalcServiceServer.
+func (mr *MockCalcServiceServerMockRecorder) mustEmbedUnimplementedCalcServiceServer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCalcServiceServer", reflect.TypeOf((*MockCalcServiceServer)(nil).mustEmbedUnimplementedCalcServiceServer))
+}
+
+// MockUnsafeCalcServiceServer is a mock of UnsafeCalcServiceServer interface.
+type MockUnsafeCalcServiceServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnsafeCalcServiceServerMockRecorder
+}
+
+// MockUnsafeCalcServiceServerMockRecorder is the mock recorder for MockUnsafeCalcServiceServer.
+type MockUnsafeCalcServiceServerMockRecorder struct {
+	mock *MockUnsafeCalcServiceServer
+}
+
+// NewMockUnsafeCalcServiceServer creates a new mock instance.
+func NewMockUnsafeCalcServiceServer(ctrl *gomock.Controller) *MockUnsafeCalcServiceServer {
+	mock := &MockUnsafeCalcServiceServer{ctrl: ctrl}
+	mock.recorder = &MockUnsafeCalcServiceServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnsafeCalcServiceServer) EXPECT() *MockUnsafeCalcServiceServerMockRecorder {
+	return m.recorder
+}
+
+// mustEmbedUnimplementedCalcServiceServer mocks base method.
+func (m *MockUnsafeCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "mustEmbedUnimplementedCalcServiceServer")
 }
 
 // mustEmbedUnimplementedCalcServiceServer indicates an expected call of mustEmbedUnimplementedCalcServiceServer.
@@ -280,3 +1295,241 @@ func (mr *MockUnsafeCalcServiceServerMockRecorder) mustEmbedUnimplementedCalcSer
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCalcServiceServer", reflect.TypeOf((*MockUnsafeCalcServiceServer)(nil).mustEmbedUnimplementedCalcServiceServer))
 }
+
+// MockCalcService_WatchExpressionServer is a mock of CalcService_WatchExpressionServer interface.
+type MockCalcService_WatchExpressionServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCalcService_WatchExpressionServerMockRecorder
+}
+
+// MockCalcService_WatchExpressionServerMockRecorder is the mock recorder for MockCalcService_WatchExpressionServer.
+type MockCalcService_WatchExpressionServerMockRecorder struct {
+	mock *MockCalcService_WatchExpressionServer
+}
+
+// NewMockCalcService_WatchExpressionServer creates a new mock instance.
+func NewMockCalcService_WatchExpressionServer(ctrl *gomock.Controller) *MockCalcService_WatchExpressionServer {
+	mock := &MockCalcService_WatchExpressionServer{ctrl: ctrl}
+	mock.recorder = &MockCalcService_WatchExpressionServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCalcService_WatchExpressionServer) EXPECT() *MockCalcService_WatchExpressionServerMockRecorder {
+	return m.recorder
+}
+
+// Context mocks base method.
+func (m *MockCalcService_WatchExpressionServer) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).Context))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionServer) RecvMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).RecvMsg), m)
+}
+
+// Send mocks base method.
+func (m *MockCalcService_WatchExpressionServer) Send(arg0 *proto.ExpressionEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) Send(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).Send), arg0)
+}
+
+// SendHeader mocks base method.
+func (m *MockCalcService_WatchExpressionServer) SendHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHeader indicates an expected call of SendHeader.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) SendHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHeader", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).SendHeader), arg0)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionServer) SendMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).SendMsg), m)
+}
+
+// SetHeader mocks base method.
+func (m *MockCalcService_WatchExpressionServer) SetHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeader indicates an expected call of SetHeader.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) SetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeader", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).SetHeader), arg0)
+}
+
+// SetTrailer mocks base method.
+func (m *MockCalcService_WatchExpressionServer) SetTrailer(arg0 metadata.MD) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrailer", arg0)
+}
+
+// SetTrailer indicates an expected call of SetTrailer.
+func (mr *MockCalcService_WatchExpressionServerMockRecorder) SetTrailer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrailer", reflect.TypeOf((*MockCalcService_WatchExpressionServer)(nil).SetTrailer), arg0)
+}
+
+// MockCalcService_WatchExpressionsServer is a mock of CalcService_WatchExpressionsServer interface.
+type MockCalcService_WatchExpressionsServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCalcService_WatchExpressionsServerMockRecorder
+}
+
+// MockCalcService_WatchExpressionsServerMockRecorder is the mock recorder for MockCalcService_WatchExpressionsServer.
+type MockCalcService_WatchExpressionsServerMockRecorder struct {
+	mock *MockCalcService_WatchExpressionsServer
+}
+
+// NewMockCalcService_WatchExpressionsServer creates a new mock instance.
+func NewMockCalcService_WatchExpressionsServer(ctrl *gomock.Controller) *MockCalcService_WatchExpressionsServer {
+	mock := &MockCalcService_WatchExpressionsServer{ctrl: ctrl}
+	mock.recorder = &MockCalcService_WatchExpressionsServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCalcService_WatchExpressionsServer) EXPECT() *MockCalcService_WatchExpressionsServerMockRecorder {
+	return m.recorder
+}
+
+// Context mocks base method.
+func (m *MockCalcService_WatchExpressionsServer) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).Context))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionsServer) RecvMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).RecvMsg), m)
+}
+
+// Send mocks base method.
+func (m *MockCalcService_WatchExpressionsServer) Send(arg0 *proto.ExpressionEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) Send(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).Send), arg0)
+}
+
+// SendHeader mocks base method.
+func (m *MockCalcService_WatchExpressionsServer) SendHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHeader indicates an expected call of SendHeader.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) SendHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHeader", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).SendHeader), arg0)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCalcService_WatchExpressionsServer) SendMsg(m any) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).SendMsg), m)
+}
+
+// SetHeader mocks base method.
+func (m *MockCalcService_WatchExpressionsServer) SetHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeader indicates an expected call of SetHeader.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) SetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeader", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).SetHeader), arg0)
+}
+
+// SetTrailer mocks base method.
+func (m *MockCalcService_WatchExpressionsServer) SetTrailer(arg0 metadata.MD) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrailer", arg0)
+}
+
+// SetTrailer indicates an expected call of SetTrailer.
+func (mr *MockCalcService_WatchExpressionsServerMockRecorder) SetTrailer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrailer", reflect.TypeOf((*MockCalcService_WatchExpressionsServer)(nil).SetTrailer), arg0)
+}
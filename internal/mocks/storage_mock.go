@@ -6,6 +6,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	dto "github.com/philipslstwoyears/calculator-go/internal/model/dto"
@@ -35,18 +36,33 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 }
 
 // AddExpression mocks base method.
-func (m *MockStorage) AddExpression(e dto.Expression) (int, error) {
+func (m *MockStorage) AddExpression(e dto.Expression, maxDepth int) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddExpression", e)
+	ret := m.ctrl.Call(m, "AddExpression", e, maxDepth)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddExpression indicates an expected call of AddExpression.
-func (mr *MockStorageMockRecorder) AddExpression(e interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) AddExpression(e, maxDepth interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddExpression", reflect.TypeOf((*MockStorage)(nil).AddExpression), e)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddExpression", reflect.TypeOf((*MockStorage)(nil).AddExpression), e, maxDepth)
+}
+
+// AddExpressions mocks base method.
+func (m *MockStorage) AddExpressions(es []dto.Expression, maxDepth int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddExpressions", es, maxDepth)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddExpressions indicates an expected call of AddExpressions.
+func (mr *MockStorageMockRecorder) AddExpressions(es, maxDepth interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddExpressions", reflect.TypeOf((*MockStorage)(nil).AddExpressions), es, maxDepth)
 }
 
 // AddUser mocks base method.
@@ -64,13 +80,149 @@ func (mr *MockStorageMockRecorder) AddUser(e interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockStorage)(nil).AddUser), e)
 }
 
+// AddWebhook mocks base method.
+func (m *MockStorage) AddWebhook(w dto.Webhook) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWebhook", w)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockStorageMockRecorder) AddWebhook(w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockStorage)(nil).AddWebhook), w)
+}
+
+// CancelJob mocks base method.
+func (m *MockStorage) CancelJob(e dto.Expression) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelJob", e)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelJob indicates an expected call of CancelJob.
+func (mr *MockStorageMockRecorder) CancelJob(e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelJob", reflect.TypeOf((*MockStorage)(nil).CancelJob), e)
+}
+
+// ClaimJob mocks base method.
+func (m *MockStorage) ClaimJob(lease time.Duration, userLimit int) (dto.Expression, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJob", lease, userLimit)
+	ret0, _ := ret[0].(dto.Expression)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimJob indicates an expected call of ClaimJob.
+func (mr *MockStorageMockRecorder) ClaimJob(lease, userLimit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJob", reflect.TypeOf((*MockStorage)(nil).ClaimJob), lease, userLimit)
+}
+
+// DeleteFunction mocks base method.
+func (m *MockStorage) DeleteFunction(userID int, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFunction", userID, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFunction indicates an expected call of DeleteFunction.
+func (mr *MockStorageMockRecorder) DeleteFunction(userID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFunction", reflect.TypeOf((*MockStorage)(nil).DeleteFunction), userID, name)
+}
+
+// DeleteVariable mocks base method.
+func (m *MockStorage) DeleteVariable(userID int, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariable", userID, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteVariable indicates an expected call of DeleteVariable.
+func (mr *MockStorageMockRecorder) DeleteVariable(userID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariable", reflect.TypeOf((*MockStorage)(nil).DeleteVariable), userID, name)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockStorage) DeleteWebhook(userID, id int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", userID, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockStorageMockRecorder) DeleteWebhook(userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockStorage)(nil).DeleteWebhook), userID, id)
+}
+
+// DueDeliveries mocks base method.
+func (m *MockStorage) DueDeliveries(now time.Time, limit int) ([]dto.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DueDeliveries", now, limit)
+	ret0, _ := ret[0].([]dto.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DueDeliveries indicates an expected call of DueDeliveries.
+func (mr *MockStorageMockRecorder) DueDeliveries(now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DueDeliveries", reflect.TypeOf((*MockStorage)(nil).DueDeliveries), now, limit)
+}
+
+// FinishJob mocks base method.
+func (m *MockStorage) FinishJob(e dto.Expression) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishJob", e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishJob indicates an expected call of FinishJob.
+func (mr *MockStorageMockRecorder) FinishJob(e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishJob", reflect.TypeOf((*MockStorage)(nil).FinishJob), e)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockStorage) GetDeliveries(userID, expressionID int) ([]dto.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveries", userID, expressionID)
+	ret0, _ := ret[0].([]dto.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockStorageMockRecorder) GetDeliveries(userID, expressionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockStorage)(nil).GetDeliveries), userID, expressionID)
+}
+
 // GetExpression mocks base method.
-func (m *MockStorage) GetExpression(id int) (dto.Expression, bool) {
+func (m *MockStorage) GetExpression(id int) (dto.Expression, bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetExpression", id)
 	ret0, _ := ret[0].(dto.Expression)
 	ret1, _ := ret[1].(bool)
-	return ret0, ret1
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetExpression indicates an expected call of GetExpression.
@@ -94,6 +246,36 @@ func (mr *MockStorageMockRecorder) GetExpressions(userID interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpressions", reflect.TypeOf((*MockStorage)(nil).GetExpressions), userID)
 }
 
+// GetFunction mocks base method.
+func (m *MockStorage) GetFunction(userID int, name string) (dto.Function, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFunction", userID, name)
+	ret0, _ := ret[0].(dto.Function)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetFunction indicates an expected call of GetFunction.
+func (mr *MockStorageMockRecorder) GetFunction(userID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunction", reflect.TypeOf((*MockStorage)(nil).GetFunction), userID, name)
+}
+
+// GetFunctions mocks base method.
+func (m *MockStorage) GetFunctions(userID int) ([]dto.Function, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFunctions", userID)
+	ret0, _ := ret[0].([]dto.Function)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFunctions indicates an expected call of GetFunctions.
+func (mr *MockStorageMockRecorder) GetFunctions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunctions", reflect.TypeOf((*MockStorage)(nil).GetFunctions), userID)
+}
+
 // GetUser mocks base method.
 func (m *MockStorage) GetUser(login string) (dto.User, bool) {
 	m.ctrl.T.Helper()
@@ -109,6 +291,213 @@ func (mr *MockStorageMockRecorder) GetUser(login interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStorage)(nil).GetUser), login)
 }
 
+// GetVariables mocks base method.
+func (m *MockStorage) GetVariables(userID int) ([]dto.Variable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVariables", userID)
+	ret0, _ := ret[0].([]dto.Variable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVariables indicates an expected call of GetVariables.
+func (mr *MockStorageMockRecorder) GetVariables(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVariables", reflect.TypeOf((*MockStorage)(nil).GetVariables), userID)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockStorage) GetWebhooks(userID int) ([]dto.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", userID)
+	ret0, _ := ret[0].([]dto.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockStorageMockRecorder) GetWebhooks(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockStorage)(nil).GetWebhooks), userID)
+}
+
+// LastExpressionID mocks base method.
+func (m *MockStorage) LastExpressionID(userID int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastExpressionID", userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastExpressionID indicates an expected call of LastExpressionID.
+func (mr *MockStorageMockRecorder) LastExpressionID(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastExpressionID", reflect.TypeOf((*MockStorage)(nil).LastExpressionID), userID)
+}
+
+// NextDeliveryAt mocks base method.
+func (m *MockStorage) NextDeliveryAt(after time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NextDeliveryAt", after)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NextDeliveryAt indicates an expected call of NextDeliveryAt.
+func (mr *MockStorageMockRecorder) NextDeliveryAt(after interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextDeliveryAt", reflect.TypeOf((*MockStorage)(nil).NextDeliveryAt), after)
+}
+
+// QueueDepth mocks base method.
+func (m *MockStorage) QueueDepth() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueueDepth")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueueDepth indicates an expected call of QueueDepth.
+func (mr *MockStorageMockRecorder) QueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueDepth", reflect.TypeOf((*MockStorage)(nil).QueueDepth))
+}
+
+// QueuePositions mocks base method.
+func (m *MockStorage) QueuePositions(userID int) (map[int]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueuePositions", userID)
+	ret0, _ := ret[0].(map[int]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueuePositions indicates an expected call of QueuePositions.
+func (mr *MockStorageMockRecorder) QueuePositions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueuePositions", reflect.TypeOf((*MockStorage)(nil).QueuePositions), userID)
+}
+
+// RecoverJobs mocks base method.
+func (m *MockStorage) RecoverJobs(maxAttempts int) (int, []dto.Expression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecoverJobs", maxAttempts)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].([]dto.Expression)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RecoverJobs indicates an expected call of RecoverJobs.
+func (mr *MockStorageMockRecorder) RecoverJobs(maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecoverJobs", reflect.TypeOf((*MockStorage)(nil).RecoverJobs), maxAttempts)
+}
+
+// ReleaseJob mocks base method.
+func (m *MockStorage) ReleaseJob(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseJob", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseJob indicates an expected call of ReleaseJob.
+func (mr *MockStorageMockRecorder) ReleaseJob(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseJob", reflect.TypeOf((*MockStorage)(nil).ReleaseJob), id)
+}
+
+// RenewJob mocks base method.
+func (m *MockStorage) RenewJob(id int, lease time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewJob", id, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewJob indicates an expected call of RenewJob.
+func (mr *MockStorageMockRecorder) RenewJob(id, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewJob", reflect.TypeOf((*MockStorage)(nil).RenewJob), id, lease)
+}
+
+// RevealWebhookSecret mocks base method.
+func (m *MockStorage) RevealWebhookSecret(userID int) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevealWebhookSecret", userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RevealWebhookSecret indicates an expected call of RevealWebhookSecret.
+func (mr *MockStorageMockRecorder) RevealWebhookSecret(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevealWebhookSecret", reflect.TypeOf((*MockStorage)(nil).RevealWebhookSecret), userID)
+}
+
+// RotateWebhookSecret mocks base method.
+func (m *MockStorage) RotateWebhookSecret(userID int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateWebhookSecret", userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateWebhookSecret indicates an expected call of RotateWebhookSecret.
+func (mr *MockStorageMockRecorder) RotateWebhookSecret(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateWebhookSecret", reflect.TypeOf((*MockStorage)(nil).RotateWebhookSecret), userID)
+}
+
+// SetFunction mocks base method.
+func (m *MockStorage) SetFunction(f dto.Function) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFunction", f)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFunction indicates an expected call of SetFunction.
+func (mr *MockStorageMockRecorder) SetFunction(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFunction", reflect.TypeOf((*MockStorage)(nil).SetFunction), f)
+}
+
+// SetVariable mocks base method.
+func (m *MockStorage) SetVariable(v dto.Variable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVariable", v)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVariable indicates an expected call of SetVariable.
+func (mr *MockStorageMockRecorder) SetVariable(v interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVariable", reflect.TypeOf((*MockStorage)(nil).SetVariable), v)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockStorage) UpdateDelivery(d dto.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockStorageMockRecorder) UpdateDelivery(d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockStorage)(nil).UpdateDelivery), d)
+}
+
 // UpdateExpression mocks base method.
 func (m *MockStorage) UpdateExpression(e dto.Expression) error {
 	m.ctrl.T.Helper()
@@ -122,3 +511,18 @@ func (mr *MockStorageMockRecorder) UpdateExpression(e interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExpression", reflect.TypeOf((*MockStorage)(nil).UpdateExpression), e)
 }
+
+// WebhookSecret mocks base method.
+func (m *MockStorage) WebhookSecret(userID int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WebhookSecret", userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WebhookSecret indicates an expected call of WebhookSecret.
+func (mr *MockStorageMockRecorder) WebhookSecret(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WebhookSecret", reflect.TypeOf((*MockStorage)(nil).WebhookSecret), userID)
+}
@@ -3,14 +3,209 @@ package convert
 import (
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
 	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"maps"
+	"math"
+	"slices"
+	"time"
 )
 
 func ExpressionToDTO(e *proto.Expression) *dto.Expression {
 	return &dto.Expression{
-		ID:         int(e.Id),
-		Expression: e.Expression,
-		UserID:     int(e.UserId),
-		Status:     e.Status,
-		Result:     float64(e.Result),
+		ID:           int(e.Id),
+		Expression:   e.Expression,
+		UserID:       int(e.UserId),
+		Status:       e.Status,
+		Result:       e.Result,
+		Mode:         e.Mode,
+		Precision:    int(e.Precision),
+		Value:        e.Value,
+		Inexact:      e.Inexact,
+		ErrorCode:    e.ErrorCode,
+		ErrorMessage: e.ErrorMessage,
+		Deadline:     e.Deadline,
+		Position:     int(e.Position),
+		Priority:     e.Priority,
+		CallbackURL:  e.CallbackUrl,
+		References:   referencesToDTO(e.References),
+	}
+}
+
+func ExpressionToProto(e dto.Expression) *proto.Expression {
+	return &proto.Expression{
+		Id:           int32(e.ID),
+		Expression:   e.Expression,
+		UserId:       int32(e.UserID),
+		Status:       e.Status,
+		Result:       e.Result,
+		Mode:         e.Mode,
+		Precision:    int32(e.Precision),
+		Value:        e.Value,
+		Inexact:      e.Inexact,
+		ErrorCode:    e.ErrorCode,
+		ErrorMessage: e.ErrorMessage,
+		Deadline:     e.Deadline,
+		Position:     int32(e.Position),
+		Priority:     e.Priority,
+		CallbackUrl:  e.CallbackURL,
+		References:   referencesToProto(e.References),
+	}
+}
+
+func referencesToDTO(references []*proto.Reference) map[string]int {
+	if len(references) == 0 {
+		return nil
+	}
+	result := make(map[string]int, len(references))
+	for _, reference := range references {
+		result[reference.Name] = int(reference.Id)
+	}
+	return result
+}
+
+// referencesToProto возвращает ссылки, упорядоченные по имени, чтобы ответ не зависел от порядка обхода map.
+func referencesToProto(references map[string]int) []*proto.Reference {
+	if len(references) == 0 {
+		return nil
+	}
+	result := make([]*proto.Reference, 0, len(references))
+	for _, name := range slices.Sorted(maps.Keys(references)) {
+		result = append(result, &proto.Reference{Name: name, Id: int32(references[name])})
+	}
+	return result
+}
+
+func PoolToDTO(pool *proto.Pool) *dto.Pool {
+	return &dto.Pool{
+		Size:      int(pool.GetSize()),
+		Autoscale: pool.GetAutoscale(),
+		Min:       int(pool.GetMin()),
+		Max:       int(pool.GetMax()),
+	}
+}
+
+func EventToDTO(event *proto.ExpressionEvent) *dto.ExpressionEvent {
+	result := &dto.ExpressionEvent{
+		ID:    event.GetId(),
+		Type:  event.GetType(),
+		Done:  int(event.GetDone()),
+		Total: int(event.GetTotal()),
+	}
+	if event.Expression != nil {
+		result.Expression = ExpressionToDTO(event.Expression)
+	}
+	return result
+}
+
+func WebhookToDTO(webhook *proto.Webhook) *dto.Webhook {
+	return &dto.Webhook{
+		ID:     int(webhook.GetId()),
+		UserID: int(webhook.GetUserId()),
+		URL:    webhook.GetUrl(),
+		Secret: webhook.GetSecret(),
+	}
+}
+
+func WebhookToProto(webhook dto.Webhook) *proto.Webhook {
+	return &proto.Webhook{
+		Id:     int32(webhook.ID),
+		UserId: int32(webhook.UserID),
+		Url:    webhook.URL,
+		Secret: webhook.Secret,
+	}
+}
+
+func DeliveryToDTO(delivery *proto.Delivery) *dto.Delivery {
+	return &dto.Delivery{
+		ID:            int(delivery.GetId()),
+		ExpressionID:  int(delivery.GetExpressionId()),
+		URL:           delivery.GetUrl(),
+		Status:        delivery.GetStatus(),
+		Attempts:      int(delivery.GetAttempts()),
+		ResponseCode:  int(delivery.GetResponseCode()),
+		Error:         delivery.GetError(),
+		NextAttemptAt: delivery.GetNextAttemptAt(),
+		UpdatedAt:     delivery.GetUpdatedAt(),
+	}
+}
+
+func DeliveryToProto(delivery dto.Delivery) *proto.Delivery {
+	return &proto.Delivery{
+		Id:            int32(delivery.ID),
+		ExpressionId:  int32(delivery.ExpressionID),
+		Url:           delivery.URL,
+		Status:        delivery.Status,
+		Attempts:      int32(delivery.Attempts),
+		ResponseCode:  int32(delivery.ResponseCode),
+		Error:         delivery.Error,
+		NextAttemptAt: delivery.NextAttemptAt,
+		UpdatedAt:     delivery.UpdatedAt,
+	}
+}
+
+// ErrorToDTO переводит ошибку агента в ответ; позиция синтаксической ошибки берётся из деталей статуса.
+func ErrorToDTO(err error) *dto.ErrorResponse {
+	response := &dto.ErrorResponse{Error: err.Error()}
+	for _, detail := range status.Convert(err).Details() {
+		if syntaxErr, ok := detail.(*proto.SyntaxError); ok {
+			response.Syntax = SyntaxErrorToDTO(syntaxErr)
+		}
+	}
+	return response
+}
+
+func SyntaxErrorToDTO(e *proto.SyntaxError) *dto.SyntaxError {
+	if e == nil {
+		return nil
+	}
+	return &dto.SyntaxError{
+		Code:     e.Code,
+		Line:     int(e.Line),
+		Column:   int(e.Column),
+		Token:    e.Token,
+		Expected: e.Expected,
+		Message:  e.Message,
+	}
+}
+
+func BatchItemToDTO(item *proto.BatchItem) dto.BatchItem {
+	return dto.BatchItem{
+		ID:         int(item.Id),
+		Error:      item.Error,
+		Code:       item.Code,
+		Syntax:     SyntaxErrorToDTO(item.Syntax),
+		RetryAfter: int(math.Ceil((time.Duration(item.RetryAfterMs) * time.Millisecond).Seconds())),
+	}
+}
+
+// RetryAfter достаёт из статуса ResourceExhausted, через сколько стоит повторить запрос.
+func RetryAfter(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+func VariableToDTO(v *proto.Variable) *dto.Variable {
+	return &dto.Variable{
+		UserID: int(v.UserId),
+		Name:   v.Name,
+		Value:  v.Value,
+	}
+}
+
+func FunctionToDTO(f *proto.Function) *dto.Function {
+	params := f.Params
+	if params == nil {
+		params = []string{}
+	}
+	return &dto.Function{
+		UserID: int(f.UserId),
+		Name:   f.Name,
+		Params: params,
+		Body:   f.Body,
 	}
 }
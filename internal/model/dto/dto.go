@@ -1,7 +1,70 @@
 package dto
 
+import "time"
+
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string       `json:"error"`
+	Syntax *SyntaxError `json:"syntax,omitempty"`
+}
+
+// SyntaxError — место ошибки в выражении, чтобы клиент мог её подчеркнуть.
+type SyntaxError struct {
+	Code     string `json:"code"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Token    string `json:"token"`
+	Expected string `json:"expected,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Статусы выражения. Выражение с ошибкой получает статус "Ошибка: " и текст ошибки.
+const (
+	// StatusAccepted — выражение ждёт вычисления или вычисляется
+	StatusAccepted = "Выражение принято для вычисления"
+	// StatusOK — выражение вычислено
+	StatusOK = "Ok"
+	// StatusCancelled — выражение отменено пользователем
+	StatusCancelled = "Cancelled"
+)
+
+// Приоритеты выражений. В expressions.priority и jobs.priority хранится не название,
+// а уровень приоритета, см. PriorityRank.
+const (
+	// PriorityInteractive — выражения, результат которых ждёт человек.
+	PriorityInteractive = "interactive"
+	// PriorityNormal — приоритет по умолчанию.
+	PriorityNormal = "normal"
+	// PriorityBatch — фоновые выражения, например ночные пакеты.
+	PriorityBatch = "batch"
+)
+
+// PriorityLevels — число уровней приоритета.
+const PriorityLevels = 3
+
+// DefaultPriorityAging — за сколько ожидания выражение поднимается на один уровень приоритета.
+const DefaultPriorityAging = time.Minute
+
+// PriorityRank возвращает уровень приоритета: 0 — самый высокий. Пустой или неизвестный
+// приоритет — PriorityNormal.
+func PriorityRank(priority string) int {
+	switch priority {
+	case PriorityInteractive:
+		return 0
+	case PriorityBatch:
+		return 2
+	}
+	return 1
+}
+
+// PriorityName возвращает приоритет по уровню, обратно PriorityRank.
+func PriorityName(rank int) string {
+	switch rank {
+	case 0:
+		return PriorityInteractive
+	case 2:
+		return PriorityBatch
+	}
+	return PriorityNormal
 }
 
 type Expression struct {
@@ -10,6 +73,27 @@ type Expression struct {
 	Status     string  `json:"status"`
 	Result     float64 `json:"result"`
 	Expression string  `json:"expression"`
+	Mode       string  `json:"mode,omitempty"`
+	Precision  int     `json:"precision,omitempty"`
+	// Value — результат без потери точности: дробь в режиме rational или десятичная запись в режиме decimal.
+	Value string `json:"value,omitempty"`
+	// Inexact — в режимах rational и decimal часть операций (sqrt, sin, дробные степени, ...)
+	// выполнена в float64, и Value может отличаться от точного результата.
+	Inexact bool `json:"inexact,omitempty"`
+	// ErrorCode — стабильный код ошибки вычисления (division_by_zero, unbalanced_parens, ...), ErrorMessage — её текст.
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	// Deadline — срок вычисления в миллисекундах Unix; не успевшее выражение завершается с кодом timeout.
+	Deadline int64 `json:"deadline,omitempty"`
+	// Priority — interactive, normal или batch.
+	Priority string `json:"priority,omitempty"`
+	// Position — место ожидающего выражения в очереди, считая с единицы; у вычисляемых и вычисленных 0.
+	Position int `json:"position,omitempty"`
+	// CallbackURL — куда, кроме webhooks пользователя, отправить выражение после вычисления.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// References — ссылки выражения на результаты других выражений пользователя:
+	// ссылка так, как она записана (#42 или $prev), и номер выражения, на которое она указывает.
+	References map[string]int `json:"references,omitempty"`
 }
 
 type User struct {
@@ -20,4 +104,126 @@ type User struct {
 
 type Request struct {
 	Expression string `json:"expression"`
+	Mode       string `json:"mode,omitempty"`
+	Precision  int    `json:"precision,omitempty"`
+	// Timeout — сколько можно вычислять выражение, например "30s" или "1m30s".
+	Timeout string `json:"timeout,omitempty"`
+	// Deadline — момент, к которому выражение должно быть вычислено, в формате RFC 3339.
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// Priority — interactive, normal (по умолчанию) или batch.
+	Priority string `json:"priority,omitempty"`
+	// CallbackURL — куда отправить выражение после вычисления, кроме зарегистрированных webhooks.
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// BatchRequest — тело POST /api/v1/calculate/batch: выражения в том же виде, что и для /calculate.
+type BatchRequest struct {
+	Expressions []Request `json:"expressions"`
+}
+
+// BatchItem — результат одного выражения пакета: номер или ошибка.
+type BatchItem struct {
+	ID     int          `json:"id,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Syntax *SyntaxError `json:"syntax,omitempty"`
+	// RetryAfter — через сколько секунд повторить выражение, не поместившееся в очередь
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// BatchResponse — результаты в порядке выражений запроса.
+type BatchResponse struct {
+	Results []BatchItem `json:"results"`
+}
+
+// Queue — загрузка очередей вычисления.
+type Queue struct {
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+	Tasks    int `json:"tasks"`
+}
+
+// Pool — локальные вычислители сервера.
+type Pool struct {
+	Size      int  `json:"size"`
+	Autoscale bool `json:"autoscale"`
+	Min       int  `json:"min,omitempty"`
+	Max       int  `json:"max,omitempty"`
+}
+
+// PoolRequest — новый размер пула. Size — указатель, чтобы отличить отсутствующее поле от нуля.
+type PoolRequest struct {
+	Size *int `json:"size"`
+}
+
+type Variable struct {
+	UserID int     `json:"user_id"`
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+}
+
+type Function struct {
+	UserID int      `json:"user_id"`
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Body   string   `json:"body"`
+}
+
+type FunctionRequest struct {
+	Definition string `json:"definition"`
+}
+
+// ExpressionEvent — изменение состояния выражения, которое сервер отправляет в поток событий.
+type ExpressionEvent struct {
+	ID         int64       `json:"id,omitempty"`
+	Type       string      `json:"type"`
+	Expression *Expression `json:"expression,omitempty"`
+	Done       int         `json:"done,omitempty"`
+	Total      int         `json:"total,omitempty"`
+}
+
+// Webhook — адрес, на который POST-запросом отправляются вычисленные выражения пользователя.
+type Webhook struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	URL    string `json:"url"`
+	// Secret — ключ подписи доставок; есть только в ответе на создание первого webhook пользователя.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Webhooks — адреса пользователя.
+type Webhooks struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+// WebhookSecret — новый ключ, которым подписываются все доставки пользователя.
+type WebhookSecret struct {
+	Secret string `json:"secret"`
+}
+
+type WebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// Состояния доставки.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery — отправка вычисленного выражения на один адрес со всеми её попытками.
+type Delivery struct {
+	ID           int    `json:"id"`
+	ExpressionID int    `json:"expression_id"`
+	UserID       int    `json:"-"`
+	URL          string `json:"url"`
+	Status       string `json:"status"`
+	Attempts     int    `json:"attempts"`
+	// ResponseCode и Error — результат последней попытки
+	ResponseCode int    `json:"response_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// NextAttemptAt — когда будет следующая попытка, в миллисекундах Unix; только у pending
+	NextAttemptAt int64 `json:"next_attempt_at,omitempty"`
+	UpdatedAt     int64 `json:"updated_at"`
 }
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/philipslstwoyears/calculator-go/internal/agent"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"strings"
+)
+
+// checkAdmin пропускает запрос с заголовком "Authorization: Bearer <ADMIN_TOKEN>".
+// Если ADMIN_TOKEN не задан, ручки администратора выключены.
+func (a *Application) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if a.config == nil || a.config.AdminToken == "" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: "admin API is disabled: ADMIN_TOKEN is not set"})
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.config.AdminToken)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: "invalid admin token"})
+		return false
+	}
+	return true
+}
+
+// adminContext передаёт ADMIN_TOKEN агенту: GetPool и ResizePool проверяют его сами.
+func (a *Application) adminContext(r *http.Request) context.Context {
+	return metadata.AppendToOutgoingContext(r.Context(), agent.AdminTokenKey, a.config.AdminToken)
+}
+
+// workersHandler показывает размер пула локальных вычислителей.
+func (a *Application) workersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !a.checkAdmin(w, r) {
+		return
+	}
+
+	pool, err := a.agent.GetPool(a.adminContext(r), &proto.Empty{})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.PoolToDTO(pool))
+}
+
+// resizeWorkersHandler меняет число локальных вычислителей.
+func (a *Application) resizeWorkersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !a.checkAdmin(w, r) {
+		return
+	}
+
+	request := new(dto.PoolRequest)
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err == nil && request.Size == nil {
+		err = errors.New("size is required")
+	}
+	// размер проверяется до перевода в int32, иначе большое число превратилось бы в другой размер
+	if err == nil && (*request.Size < 0 || *request.Size > calc.MaxPoolSize) {
+		err = fmt.Errorf("pool size must be from 0 to %d", calc.MaxPoolSize)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pool, err := a.agent.ResizePool(a.adminContext(r), &proto.PoolSize{Size: int32(*request.Size)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.PoolToDTO(pool))
+}
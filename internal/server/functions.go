@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+func (a *Application) functionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	functions, err := a.agent.GetFunctions(r.Context(), &proto.Id{Id: int32(userId)})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result := make([]*dto.Function, len(functions.GetFunctions()))
+	for i, f := range functions.GetFunctions() {
+		result[i] = convert.FunctionToDTO(f)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (a *Application) functionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	f, err := a.agent.GetFunction(r.Context(), &proto.Function{UserId: int32(userId), Name: mux.Vars(r)["name"]})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.FunctionToDTO(f))
+}
+
+// setFunctionHandler создаёт функцию (POST /functions) или изменяет существующую (PUT /functions/{name}).
+func (a *Application) setFunctionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request := new(dto.FunctionRequest)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	name, f, err := calc.ParseFunction(request.Definition)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if pathName, ok := mux.Vars(r)["name"]; ok && pathName != name {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: fmt.Sprintf("definition is for function %s, not %s", name, pathName)})
+		return
+	}
+
+	result, err := a.agent.SetFunction(r.Context(), &proto.Function{
+		UserId: int32(userId),
+		Name:   name,
+		Params: f.Params,
+		Body:   f.Body,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(convert.ErrorToDTO(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.FunctionToDTO(result))
+}
+
+func (a *Application) deleteFunctionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	_, err = a.agent.DeleteFunction(r.Context(), &proto.Function{UserId: int32(userId), Name: name})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"name": name})
+}
+
+// writeAgentError отвечает 404 на codes.NotFound, 403 на codes.PermissionDenied,
+// 409 на codes.FailedPrecondition и 500 на остальные ошибки агента.
+func writeAgentError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.NotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case codes.PermissionDenied:
+		w.WriteHeader(http.StatusForbidden)
+	case codes.FailedPrecondition:
+		w.WriteHeader(http.StatusConflict)
+	case codes.InvalidArgument:
+		w.WriteHeader(http.StatusBadRequest)
+	case codes.Unavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+}
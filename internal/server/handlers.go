@@ -1,12 +1,19 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/philipslstwoyears/calculator-go/internal/agent"
 	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
 	"github.com/philipslstwoyears/calculator-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -36,16 +43,144 @@ func (a *Application) CalculateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := a.agent.Calc(r.Context(), &proto.Request{Expression: request.Expression, UserId: int32(userId)})
+	deadline, err := requestDeadline(request, time.Now())
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	id, err := a.agent.Calc(r.Context(), &proto.Request{
+		Expression:  request.Expression,
+		UserId:      int32(userId),
+		Mode:        request.Mode,
+		Precision:   int32(request.Precision),
+		Deadline:    deadline,
+		Priority:    request.Priority,
+		CallbackUrl: request.CallbackURL,
+	})
+	if status.Code(err) == codes.ResourceExhausted {
+		if retryAfter, ok := convert.RetryAfter(err); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(convert.ErrorToDTO(err))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(convert.ErrorToDTO(err))
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]int32{"id": id.Id})
 }
+
+// MaxBatchBytes — наибольший размер тела POST /api/v1/calculate/batch. Пакет уходит агенту
+// одним сообщением gRPC, а больше 4 МиБ gRPC по умолчанию не принимает.
+const MaxBatchBytes = 4 << 20
+
+// CalculateBatchHandler принимает много выражений одним запросом. Ответ 200 содержит результат
+// каждого выражения на его месте: номер или ошибку; ошибка одного выражения не мешает остальным.
+func (a *Application) CalculateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request := new(dto.BatchRequest)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBatchBytes)).Decode(request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: fmt.Sprintf("batch is larger than %d bytes, split it into several requests", MaxBatchBytes)})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(request.Expressions) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: "expressions must not be empty"})
+		return
+	}
+
+	results := make([]dto.BatchItem, len(request.Expressions))
+	batch := &proto.BatchRequest{UserId: int32(userId)}
+	// positions[j] — место в ответе j-го запроса, отправленного агенту
+	var positions []int
+	for i := range request.Expressions {
+		item := &request.Expressions[i]
+		deadline, err := requestDeadline(item, time.Now())
+		if err != nil {
+			results[i] = dto.BatchItem{Error: err.Error(), Code: codes.InvalidArgument.String()}
+			continue
+		}
+		batch.Requests = append(batch.Requests, &proto.Request{
+			Expression:  item.Expression,
+			UserId:      int32(userId),
+			Mode:        item.Mode,
+			Precision:   int32(item.Precision),
+			Deadline:    deadline,
+			Priority:    item.Priority,
+			CallbackUrl: item.CallbackURL,
+		})
+		positions = append(positions, i)
+	}
+
+	if len(batch.Requests) > 0 {
+		result, err := a.agent.CalcBatch(r.Context(), batch)
+		if status.Code(err) == codes.ResourceExhausted {
+			if retryAfter, ok := convert.RetryAfter(err); ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(convert.ErrorToDTO(err))
+			return
+		}
+		if err != nil {
+			log.Printf("CalculateBatchHandler: agent error, err=%v", err)
+			writeAgentError(w, err)
+			return
+		}
+		for j, item := range result.GetItems() {
+			results[positions[j]] = convert.BatchItemToDTO(item)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&dto.BatchResponse{Results: results})
+}
+
+// requestDeadline переводит timeout и deadline запроса в срок в миллисекундах Unix.
+// Если заданы оба, действует более ранний; 0 — без срока.
+func requestDeadline(request *dto.Request, now time.Time) (int64, error) {
+	var deadline time.Time
+	if request.Timeout != "" {
+		timeout, err := time.ParseDuration(request.Timeout)
+		if err != nil {
+			return 0, err
+		}
+		if timeout <= 0 {
+			return 0, fmt.Errorf("timeout must be positive: %s", request.Timeout)
+		}
+		deadline = now.Add(timeout)
+	}
+	if request.Deadline != nil && (deadline.IsZero() || request.Deadline.Before(deadline)) {
+		deadline = *request.Deadline
+	}
+	if deadline.IsZero() {
+		return 0, nil
+	}
+	return deadline.UnixMilli(), nil
+}
+
 func (a *Application) expressionsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -107,12 +242,31 @@ func (a *Application) expressionHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	expression, err := a.agent.GetExpression(r.Context(), &proto.Id{Id: int32(id)})
-	if err != nil {
-		log.Printf("expressionHandler: agent error, err=%v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
-		return
+	var wait time.Duration
+	if raw := r.URL.Query().Get("wait"); raw != "" {
+		wait, err = time.ParseDuration(raw)
+		if err != nil || wait < 0 || wait > agent.MaxWait {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: fmt.Sprintf("wait must be a duration from 0s to %s, for example 30s", agent.MaxWait)})
+			return
+		}
+	}
+
+	var expression *proto.Expression
+	if wait > 0 {
+		expression, err = a.waitExpression(r, id, userId, wait)
+		if err != nil {
+			log.Printf("expressionHandler: agent error, err=%v", err)
+			writeAgentError(w, err)
+			return
+		}
+	} else {
+		expression, err = a.agent.GetExpression(r.Context(), &proto.Id{Id: int32(id)})
+		if err != nil {
+			log.Printf("expressionHandler: agent error, err=%v", err)
+			writeAgentError(w, err)
+			return
+		}
 	}
 
 	if expression.GetUserId() != int32(userId) {
@@ -127,6 +281,34 @@ func (a *Application) expressionHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(convert.ExpressionToDTO(expression))
 }
 
+// cancelExpressionHandler отменяет выражение, которое ещё не вычислено.
+func (a *Application) cancelExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	expression, err := a.agent.CancelExpression(r.Context(), &proto.Expression{Id: int32(id), UserId: int32(userId)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.ExpressionToDTO(expression))
+}
+
 func (a *Application) loginHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("loginHandler: start")
 	w.Header().Set("Content-Type", "application/json")
@@ -190,3 +372,52 @@ func (a *Application) registerHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]int32{"id": id.Id})
 }
+
+// queueHandler показывает загрузку очередей: сколько выражений ждёт вычисления
+// и сколько операций ждёт вычислителя. Как и остальные ручки, требует cookie с id.
+func (a *Application) queueHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := userIdFromCookie(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	queue, err := a.agent.GetQueue(r.Context(), &proto.Empty{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&dto.Queue{
+		Depth:    int(queue.GetDepth()),
+		Capacity: int(queue.GetCapacity()),
+		Tasks:    int(queue.GetTasks()),
+	})
+}
+
+// waitExpression ждёт завершения выражения через агента. Если сервер останавливается,
+// ожидание прерывается и клиент получает текущее состояние выражения.
+func (a *Application) waitExpression(r *http.Request, id, userId int, wait time.Duration) (*proto.Expression, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	expression, err := a.agent.WaitExpression(ctx, &proto.WaitRequest{
+		Id:     int32(id),
+		UserId: int32(userId),
+		WaitMs: wait.Milliseconds(),
+	})
+	if status.Code(err) == codes.Canceled && r.Context().Err() == nil {
+		return a.agent.GetExpression(r.Context(), &proto.Id{Id: int32(id)})
+	}
+	return expression, err
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"github.com/gorilla/mux"
 	"github.com/philipslstwoyears/calculator-go/internal/middleware"
 	"github.com/philipslstwoyears/calculator-go/proto"
@@ -13,6 +15,8 @@ import (
 
 type Config struct {
 	Addr string
+	// AdminToken открывает ручки /api/v1/admin; без него они отвечают 403
+	AdminToken string
 }
 
 func ConfigFromEnv() *Config {
@@ -21,18 +25,25 @@ func ConfigFromEnv() *Config {
 	if config.Addr == "" {
 		config.Addr = "8080"
 	}
+	config.AdminToken = os.Getenv("ADMIN_TOKEN")
 	return config
 }
 
 type Application struct {
 	config *Config
 	agent  proto.CalcServiceClient
+	server *http.Server
+	// closing закрывается при остановке и завершает потоки событий, которые иначе не кончаются
+	closing chan struct{}
 }
 
 func New() (*Application, error) {
 	app := &Application{
-		config: ConfigFromEnv(),
+		config:  ConfigFromEnv(),
+		server:  &http.Server{},
+		closing: make(chan struct{}),
 	}
+	app.server.RegisterOnShutdown(func() { close(app.closing) })
 	conn, err := grpc.NewClient("0.0.0.0:8081", grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, err
@@ -46,9 +57,43 @@ func (a *Application) RunServer() error {
 	r.HandleFunc("/api/v1/register", a.registerHandler)
 	r.HandleFunc("/api/v1/login", a.loginHandler)
 	r.HandleFunc("/api/v1/calculate", a.CalculateHandler)
+	r.HandleFunc("/api/v1/calculate/batch", a.CalculateBatchHandler).Methods(http.MethodPost)
 	r.HandleFunc("/api/v1/expressions", a.expressionsHandler)
+	r.HandleFunc("/api/v1/expressions/stream", a.expressionsStreamHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/expressions/{id}", a.cancelExpressionHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/api/v1/expressions/{id}", a.expressionHandler)
+	r.HandleFunc("/api/v1/queue", a.queueHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/workers", a.workersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/workers", a.resizeWorkersHandler).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/webhooks", a.webhooksHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/webhooks", a.addWebhookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/webhooks/deliveries", a.deliveriesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/webhooks/secret", a.rotateSecretHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/webhooks/{id}", a.deleteWebhookHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/variables", a.variablesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/variables/{name}", a.setVariableHandler).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/variables/{name}", a.deleteVariableHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/functions", a.functionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/functions", a.setFunctionHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/functions/{name}", a.functionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/functions/{name}", a.setFunctionHandler).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/functions/{name}", a.deleteFunctionHandler).Methods(http.MethodDelete)
 	r.Use(middleware.LoggerMiddleware, middleware.RecoverMiddleware)
 	log.Println("Listening on port", a.config.Addr)
-	return http.ListenAndServe(":"+a.config.Addr, r)
+	a.server.Addr = ":" + a.config.Addr
+	a.server.Handler = r
+	if err := a.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown перестаёт принимать соединения и ждёт, пока допишутся ответы на начатые запросы.
+// Если ctx отменён раньше, оставшиеся соединения закрываются.
+func (a *Application) Shutdown(ctx context.Context) error {
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		a.server.Close()
+	}
+	return err
 }
@@ -1,14 +1,20 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
+	"github.com/philipslstwoyears/calculator-go/internal/agent"
 	"github.com/philipslstwoyears/calculator-go/internal/mocks"
 	"github.com/philipslstwoyears/calculator-go/proto"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -24,6 +30,8 @@ func TestCalculateHandler(t *testing.T) {
 		mockBehavior   func(m *mocks.MockCalcServiceClient)
 		expectedStatus int
 		expectedBody   interface{}
+		// expectedRetryAfter — ожидаемый заголовок Retry-After, если он должен быть
+		expectedRetryAfter string
 	}{
 		{
 			name:   "Success",
@@ -59,6 +67,62 @@ func TestCalculateHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   map[string]interface{}{"error": "unexpected EOF"},
 		},
+		{
+			name:   "DecimalMode",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			body:   `{"expression": "1/3", "mode": "decimal", "precision": 5}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().Calc(gomock.Any(), &proto.Request{Expression: "1/3", UserId: 1, Mode: "decimal", Precision: 5}).Return(&proto.Id{Id: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]interface{}{"id": float64(2)},
+		},
+		{
+			name:   "Timeout",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			body:   `{"expression": "5+5", "timeout": "30s", "deadline": "2100-01-01T00:00:00Z"}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().Calc(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in *proto.Request, opts ...grpc.CallOption) (*proto.Id, error) {
+					// timeout раньше deadline, поэтому срок — через 30 секунд
+					deadline := time.UnixMilli(in.GetDeadline())
+					assert.WithinDuration(t, time.Now().Add(30*time.Second), deadline, time.Second)
+					return &proto.Id{Id: 3}, nil
+				})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]interface{}{"id": float64(3)},
+		},
+		{
+			name:           "InvalidTimeout",
+			cookie:         &http.Cookie{Name: "id", Value: "1"},
+			body:           `{"expression": "5+5", "timeout": "-1s"}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "timeout must be positive: -1s"},
+		},
+		{
+			name:   "SyntaxError",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			body:   `{"expression": "2+*3"}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				st, _ := status.New(codes.InvalidArgument, `unexpected "*"`).WithDetails(&proto.SyntaxError{
+					Code: "syntax_error", Line: 1, Column: 3, Token: "*", Expected: "number", Message: `unexpected "*"`,
+				})
+				m.EXPECT().Calc(gomock.Any(), &proto.Request{Expression: "2+*3", UserId: 1}).Return(nil, st.Err())
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: map[string]interface{}{
+				"error": "rpc error: code = InvalidArgument desc = unexpected \"*\"",
+				"syntax": map[string]interface{}{
+					"code":     "syntax_error",
+					"line":     float64(1),
+					"column":   float64(3),
+					"token":    "*",
+					"expected": "number",
+					"message":  `unexpected "*"`,
+				},
+			},
+		},
 		{
 			name:   "AgentError",
 			cookie: &http.Cookie{Name: "id", Value: "1"},
@@ -69,6 +133,20 @@ func TestCalculateHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   map[string]interface{}{"error": "rpc error: code = InvalidArgument desc = invalid expression"},
 		},
+		{
+			name:   "QueueFull",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			body:   `{"expression": "5+5"}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				st, _ := status.New(codes.ResourceExhausted, "queue is full: 1000 expressions are waiting").WithDetails(&errdetails.RetryInfo{
+					RetryDelay: durationpb.New(1500 * time.Millisecond),
+				})
+				m.EXPECT().Calc(gomock.Any(), &proto.Request{Expression: "5+5", UserId: 1}).Return(nil, st.Err())
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedBody:       map[string]interface{}{"error": "rpc error: code = ResourceExhausted desc = queue is full: 1000 expressions are waiting"},
+			expectedRetryAfter: "2",
+		},
 	}
 
 	for _, test := range tests {
@@ -89,6 +167,7 @@ func TestCalculateHandler(t *testing.T) {
 			app.CalculateHandler(rr, req)
 
 			assert.Equal(t, test.expectedStatus, rr.Code)
+			assert.Equal(t, test.expectedRetryAfter, rr.Header().Get("Retry-After"))
 			if test.expectedStatus == http.StatusOK {
 				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 			}
@@ -101,6 +180,98 @@ func TestCalculateHandler(t *testing.T) {
 	}
 }
 
+func TestCalculateBatchHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(m *mocks.MockCalcServiceClient)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: `{"expressions": [{"expression": "1+1"}, {"expression": "2+2", "timeout": "soon"}, {"expression": ")1("}, {"expression": "3+3"}]}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CalcBatch(gomock.Any(), &proto.BatchRequest{UserId: 1, Requests: []*proto.Request{
+					{Expression: "1+1", UserId: 1},
+					{Expression: ")1(", UserId: 1},
+					{Expression: "3+3", UserId: 1},
+				}}).Return(&proto.BatchResult{Items: []*proto.BatchItem{
+					{Id: 5},
+					{Error: "unexpected \")\"", Code: "InvalidArgument", Syntax: &proto.SyntaxError{Code: "unbalanced_parens", Line: 1, Column: 1, Token: ")", Message: "unexpected \")\""}},
+					{Error: "queue is full: 2 expressions are waiting", Code: "ResourceExhausted", RetryAfterMs: 2500},
+				}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results": [
+				{"id": 5},
+				{"error": "time: invalid duration \"soon\"", "code": "InvalidArgument"},
+				{"error": "unexpected \")\"", "code": "InvalidArgument", "syntax": {"code": "unbalanced_parens", "line": 1, "column": 1, "token": ")", "message": "unexpected \")\""}},
+				{"error": "queue is full: 2 expressions are waiting", "code": "ResourceExhausted", "retry_after": 3}
+			]}`,
+		},
+		{
+			name:           "AllInvalid",
+			body:           `{"expressions": [{"expression": "1+1", "timeout": "-1s"}]}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"results": [{"error": "timeout must be positive: -1s", "code": "InvalidArgument"}]}`,
+		},
+		{
+			name:           "Empty",
+			body:           `{"expressions": []}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "expressions must not be empty"}`,
+		},
+		{
+			name:           "BodyTooLarge",
+			body:           `{"expressions": [{"expression": "` + strings.Repeat("1+", MaxBatchBytes/2) + `1"}]}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   `{"error": "batch is larger than 4194304 bytes, split it into several requests"}`,
+		},
+		{
+			name: "QueueFull",
+			body: `{"expressions": [{"expression": "1+1"}]}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CalcBatch(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.ResourceExhausted, "queue is full: 2 expressions are waiting"))
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"error": "rpc error: code = ResourceExhausted desc = queue is full: 2 expressions are waiting"}`,
+		},
+		{
+			name: "TooLarge",
+			body: `{"expressions": [{"expression": "1+1"}]}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CalcBatch(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.InvalidArgument, "batch has 10001 expressions, at most 10000 are allowed"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "rpc error: code = InvalidArgument desc = batch has 10001 expressions, at most 10000 are allowed"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+			test.mockBehavior(mockAgent)
+
+			app := &Application{agent: mockAgent}
+			req := httptest.NewRequest(http.MethodPost, "/calculate/batch", strings.NewReader(test.body))
+			req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+			rr := httptest.NewRecorder()
+
+			app.CalculateBatchHandler(rr, req)
+
+			assert.Equal(t, test.expectedStatus, rr.Code)
+			assert.JSONEq(t, test.expectedBody, rr.Body.String())
+		})
+	}
+}
+
 func TestExpressionsHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -200,6 +371,89 @@ func TestExpressionHandler(t *testing.T) {
 				"user_id":    float64(1),
 			},
 		},
+		{
+			name:   "LargeResult",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			url:    "/expression/2",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetExpression(gomock.Any(), &proto.Id{Id: 2}).Return(&proto.Expression{
+					Id:         2,
+					Expression: "123456789",
+					Status:     "Ok",
+					Result:     123456789,
+					UserId:     1,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"id":         float64(2),
+				"expression": "123456789",
+				"status":     "Ok",
+				"result":     float64(123456789),
+				"user_id":    float64(1),
+			},
+		},
+		{
+			name:   "Wait",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			url:    "/expression/1?wait=30s",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().WaitExpression(gomock.Any(), &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 30000}).Return(&proto.Expression{
+					Id:         1,
+					Expression: "5+5",
+					Status:     "Ok",
+					Result:     10,
+					UserId:     1,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"id":         float64(1),
+				"expression": "5+5",
+				"status":     "Ok",
+				"result":     float64(10),
+				"user_id":    float64(1),
+			},
+		},
+		{
+			name:   "WaitZero",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			url:    "/expression/1?wait=0s",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetExpression(gomock.Any(), &proto.Id{Id: 1}).Return(&proto.Expression{
+					Id:         1,
+					Expression: "5+5",
+					Status:     "Выражение принято для вычисления",
+					UserId:     1,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"id":         float64(1),
+				"expression": "5+5",
+				"status":     "Выражение принято для вычисления",
+				"result":     float64(0),
+				"user_id":    float64(1),
+			},
+		},
+		{
+			name:           "InvalidWait",
+			cookie:         &http.Cookie{Name: "id", Value: "1"},
+			url:            "/expression/1?wait=2m",
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "wait must be a duration from 0s to 1m0s, for example 30s"},
+		},
+		{
+			name:   "WaitForbidden",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			url:    "/expression/1?wait=5s",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().WaitExpression(gomock.Any(), &proto.WaitRequest{Id: 1, UserId: 1, WaitMs: 5000}).Return(nil, status.Error(codes.PermissionDenied, "It is not your expression"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = PermissionDenied desc = It is not your expression"},
+		},
 		{
 			name:           "NoCookie",
 			cookie:         nil,
@@ -225,15 +479,25 @@ func TestExpressionHandler(t *testing.T) {
 			expectedBody:   map[string]interface{}{"error": "strconv.Atoi: parsing \"invalid\": invalid syntax"},
 		},
 		{
-			name:   "AgentError",
+			name:   "NotFound",
 			cookie: &http.Cookie{Name: "id", Value: "1"},
 			url:    "/expression/1",
 			mockBehavior: func(m *mocks.MockCalcServiceClient) {
 				m.EXPECT().GetExpression(gomock.Any(), &proto.Id{Id: 1}).Return(nil, status.Error(codes.NotFound, "expression not found"))
 			},
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusNotFound,
 			expectedBody:   map[string]interface{}{"error": "rpc error: code = NotFound desc = expression not found"},
 		},
+		{
+			name:   "AgentError",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			url:    "/expression/1",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetExpression(gomock.Any(), &proto.Id{Id: 1}).Return(nil, status.Error(codes.Internal, "database is locked"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = Internal desc = database is locked"},
+		},
 		{
 			name:   "Forbidden",
 			cookie: &http.Cookie{Name: "id", Value: "1"},
@@ -284,6 +548,79 @@ func TestExpressionHandler(t *testing.T) {
 	}
 }
 
+func TestCancelExpressionHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		mockBehavior   func(m *mocks.MockCalcServiceClient)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name: "Success",
+			url:  "/expression/1",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CancelExpression(gomock.Any(), &proto.Expression{Id: 1, UserId: 1}).Return(&proto.Expression{
+					Id: 1, UserId: 1, Expression: "5+5", Status: "Cancelled", Result: -1, ErrorCode: "cancelled", ErrorMessage: "context canceled",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"id":            float64(1),
+				"user_id":       float64(1),
+				"expression":    "5+5",
+				"status":        "Cancelled",
+				"result":        float64(-1),
+				"error_code":    "cancelled",
+				"error_message": "context canceled",
+			},
+		},
+		{
+			name: "AlreadyFinished",
+			url:  "/expression/1",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CancelExpression(gomock.Any(), &proto.Expression{Id: 1, UserId: 1}).Return(nil, status.Error(codes.FailedPrecondition, "expression is already finished"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = FailedPrecondition desc = expression is already finished"},
+		},
+		{
+			name: "NotYours",
+			url:  "/expression/2",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().CancelExpression(gomock.Any(), &proto.Expression{Id: 2, UserId: 1}).Return(nil, status.Error(codes.PermissionDenied, "It is not your expression"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = PermissionDenied desc = It is not your expression"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+			test.mockBehavior(mockAgent)
+
+			app := &Application{agent: mockAgent}
+			req := httptest.NewRequest(http.MethodDelete, test.url, nil)
+			req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+			rr := httptest.NewRecorder()
+
+			router := mux.NewRouter()
+			router.HandleFunc("/expression/{id}", app.cancelExpressionHandler).Methods(http.MethodDelete)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.expectedStatus, rr.Code)
+			var actualBody map[string]interface{}
+			err := json.NewDecoder(rr.Body).Decode(&actualBody)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBody, actualBody)
+		})
+	}
+}
+
 func TestLoginHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -447,3 +784,451 @@ func TestRegisterHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestVariableHandlers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		cookie         *http.Cookie
+		mockBehavior   func(m *mocks.MockCalcServiceClient)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:   "List",
+			method: http.MethodGet,
+			url:    "/api/v1/variables",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetVariables(gomock.Any(), &proto.Id{Id: 1}).Return(&proto.Variables{
+					Variables: []*proto.Variable{{UserId: 1, Name: "x", Value: 12}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []interface{}{map[string]interface{}{"user_id": float64(1), "name": "x", "value": float64(12)}},
+		},
+		{
+			name:           "ListNoCookie",
+			method:         http.MethodGet,
+			url:            "/api/v1/variables",
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "http: named cookie not present"},
+		},
+		{
+			name:   "Set",
+			method: http.MethodPut,
+			url:    "/api/v1/variables/x",
+			body:   `{"value": 12}`,
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().SetVariable(gomock.Any(), &proto.Variable{UserId: 1, Name: "x", Value: 12}).Return(&proto.Variable{UserId: 1, Name: "x", Value: 12}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]interface{}{"user_id": float64(1), "name": "x", "value": float64(12)},
+		},
+		{
+			name:   "SetInvalidName",
+			method: http.MethodPut,
+			url:    "/api/v1/variables/pi",
+			body:   `{"value": 3}`,
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().SetVariable(gomock.Any(), &proto.Variable{UserId: 1, Name: "pi", Value: 3}).Return(nil, status.Error(codes.InvalidArgument, "cannot assign to constant pi"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = InvalidArgument desc = cannot assign to constant pi"},
+		},
+		{
+			name:   "SetStorageError",
+			method: http.MethodPut,
+			url:    "/api/v1/variables/x",
+			body:   `{"value": 12}`,
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().SetVariable(gomock.Any(), &proto.Variable{UserId: 1, Name: "x", Value: 12}).Return(nil, status.Error(codes.Internal, "database is locked"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = Internal desc = database is locked"},
+		},
+		{
+			name:   "Delete",
+			method: http.MethodDelete,
+			url:    "/api/v1/variables/x",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().DeleteVariable(gomock.Any(), &proto.Variable{UserId: 1, Name: "x"}).Return(&proto.Empty{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]interface{}{"name": "x"},
+		},
+		{
+			name:   "DeleteNotFound",
+			method: http.MethodDelete,
+			url:    "/api/v1/variables/x",
+			cookie: &http.Cookie{Name: "id", Value: "1"},
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().DeleteVariable(gomock.Any(), &proto.Variable{UserId: 1, Name: "x"}).Return(nil, status.Error(codes.NotFound, "variable not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = NotFound desc = variable not found"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+			test.mockBehavior(mockAgent)
+
+			app := &Application{agent: mockAgent}
+			req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if test.cookie != nil {
+				req.AddCookie(test.cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/v1/variables", app.variablesHandler).Methods(http.MethodGet)
+			router.HandleFunc("/api/v1/variables/{name}", app.setVariableHandler).Methods(http.MethodPut)
+			router.HandleFunc("/api/v1/variables/{name}", app.deleteVariableHandler).Methods(http.MethodDelete)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.expectedStatus, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+			var actualBody interface{}
+			err := json.NewDecoder(rr.Body).Decode(&actualBody)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBody, actualBody)
+		})
+	}
+}
+
+func TestFunctionHandlers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		mockBehavior   func(m *mocks.MockCalcServiceClient)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:   "Create",
+			method: http.MethodPost,
+			url:    "/api/v1/functions",
+			body:   `{"definition": "hyp(a, b) = sqrt(a*a+b*b)"}`,
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				f := &proto.Function{UserId: 1, Name: "hyp", Params: []string{"a", "b"}, Body: "sqrt(a*a+b*b)"}
+				m.EXPECT().SetFunction(gomock.Any(), f).Return(f, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"user_id": float64(1),
+				"name":    "hyp",
+				"params":  []interface{}{"a", "b"},
+				"body":    "sqrt(a*a+b*b)",
+			},
+		},
+		{
+			name:           "CreateInvalidDefinition",
+			method:         http.MethodPost,
+			url:            "/api/v1/functions",
+			body:           `{"definition": "hyp"}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "function definition must look like f(x) = expression"},
+		},
+		{
+			name:           "UpdateNameMismatch",
+			method:         http.MethodPut,
+			url:            "/api/v1/functions/vat",
+			body:           `{"definition": "tax(x) = x*0.2"}`,
+			mockBehavior:   func(m *mocks.MockCalcServiceClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]interface{}{"error": "definition is for function tax, not vat"},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			url:    "/api/v1/functions/vat",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetFunction(gomock.Any(), &proto.Function{UserId: 1, Name: "vat"}).Return(nil, status.Error(codes.NotFound, "function not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   map[string]interface{}{"error": "rpc error: code = NotFound desc = function not found"},
+		},
+		{
+			name:   "List",
+			method: http.MethodGet,
+			url:    "/api/v1/functions",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().GetFunctions(gomock.Any(), &proto.Id{Id: 1}).Return(&proto.Functions{
+					Functions: []*proto.Function{{UserId: 1, Name: "answer", Body: "42"}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []interface{}{map[string]interface{}{
+				"user_id": float64(1),
+				"name":    "answer",
+				"params":  []interface{}{},
+				"body":    "42",
+			}},
+		},
+		{
+			name:   "Delete",
+			method: http.MethodDelete,
+			url:    "/api/v1/functions/vat",
+			mockBehavior: func(m *mocks.MockCalcServiceClient) {
+				m.EXPECT().DeleteFunction(gomock.Any(), &proto.Function{UserId: 1, Name: "vat"}).Return(&proto.Empty{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]interface{}{"name": "vat"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+			test.mockBehavior(mockAgent)
+
+			app := &Application{agent: mockAgent}
+			req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+			rr := httptest.NewRecorder()
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/v1/functions", app.functionsHandler).Methods(http.MethodGet)
+			router.HandleFunc("/api/v1/functions", app.setFunctionHandler).Methods(http.MethodPost)
+			router.HandleFunc("/api/v1/functions/{name}", app.functionHandler).Methods(http.MethodGet)
+			router.HandleFunc("/api/v1/functions/{name}", app.setFunctionHandler).Methods(http.MethodPut)
+			router.HandleFunc("/api/v1/functions/{name}", app.deleteFunctionHandler).Methods(http.MethodDelete)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.expectedStatus, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+			var actualBody interface{}
+			err := json.NewDecoder(rr.Body).Decode(&actualBody)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBody, actualBody)
+		})
+	}
+}
+
+func TestQueueHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+	mockAgent.EXPECT().GetQueue(gomock.Any(), &proto.Empty{}).Return(&proto.Queue{Depth: 3, Capacity: 1000, Tasks: 5}, nil)
+
+	app := &Application{agent: mockAgent}
+	rr := httptest.NewRecorder()
+	app.queueHandler(rr, httptest.NewRequest(http.MethodGet, "/api/v1/queue", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/queue", nil)
+	req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+	rr = httptest.NewRecorder()
+	app.queueHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"depth": 3, "capacity": 1000, "tasks": 5}`, rr.Body.String())
+}
+
+func TestWorkersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+	app := &Application{config: &Config{AdminToken: "secret"}, agent: mockAgent}
+
+	tests := []struct {
+		name         string
+		method       string
+		token        string
+		body         string
+		mockBehavior func()
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:   "Get",
+			method: http.MethodGet,
+			token:  "secret",
+			mockBehavior: func() {
+				mockAgent.EXPECT().GetPool(gomock.Any(), &proto.Empty{}).DoAndReturn(func(ctx context.Context, _ *proto.Empty, _ ...grpc.CallOption) (*proto.Pool, error) {
+					// агент проверяет ADMIN_TOKEN сам, поэтому сервер передаёт его в метаданных
+					md, _ := metadata.FromOutgoingContext(ctx)
+					assert.Equal(t, []string{"secret"}, md.Get(agent.AdminTokenKey))
+					return &proto.Pool{Size: 4, Autoscale: true, Min: 1, Max: 8}, nil
+				})
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"size": 4, "autoscale": true, "min": 1, "max": 8}`,
+		},
+		{
+			name:   "Resize",
+			method: http.MethodPut,
+			token:  "secret",
+			body:   `{"size": 2}`,
+			mockBehavior: func() {
+				mockAgent.EXPECT().ResizePool(gomock.Any(), &proto.PoolSize{Size: 2}).Return(&proto.Pool{Size: 2}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"size": 2, "autoscale": false}`,
+		},
+		{
+			name:         "MissingSize",
+			method:       http.MethodPut,
+			token:        "secret",
+			body:         `{}`,
+			mockBehavior: func() {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error": "size is required"}`,
+		},
+		{
+			name:         "InvalidSize",
+			method:       http.MethodPut,
+			token:        "secret",
+			body:         `{"size": -1}`,
+			mockBehavior: func() {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error": "pool size must be from 0 to 1024"}`,
+		},
+		{
+			// 4294967297 = 2^32 + 1 при переводе в int32 стал бы размером 1
+			name:         "SizeOverflow",
+			method:       http.MethodPut,
+			token:        "secret",
+			body:         `{"size": 4294967297}`,
+			mockBehavior: func() {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error": "pool size must be from 0 to 1024"}`,
+		},
+		{
+			name:   "AgentRejectsSize",
+			method: http.MethodPut,
+			token:  "secret",
+			body:   `{"size": 2}`,
+			mockBehavior: func() {
+				mockAgent.EXPECT().ResizePool(gomock.Any(), &proto.PoolSize{Size: 2}).Return(nil, status.Error(codes.Unavailable, "worker pool is not available"))
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"error": "rpc error: code = Unavailable desc = worker pool is not available"}`,
+		},
+		{
+			name:         "WrongToken",
+			method:       http.MethodGet,
+			token:        "guess",
+			mockBehavior: func() {},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error": "invalid admin token"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior()
+			req := httptest.NewRequest(test.method, "/api/v1/admin/workers", strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			rr := httptest.NewRecorder()
+			if test.method == http.MethodGet {
+				app.workersHandler(rr, req)
+			} else {
+				app.resizeWorkersHandler(rr, req)
+			}
+
+			assert.Equal(t, test.expectedCode, rr.Code)
+			assert.JSONEq(t, test.expectedBody, rr.Body.String())
+		})
+	}
+
+	// без ADMIN_TOKEN ручки выключены
+	rr := httptest.NewRecorder()
+	(&Application{config: &Config{}, agent: mockAgent}).workersHandler(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/workers", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestExpressionsStreamHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+	app := &Application{agent: mockAgent}
+
+	t.Run("Events", func(t *testing.T) {
+		stream := mocks.NewMockCalcService_WatchExpressionsClient(ctrl)
+		mockAgent.EXPECT().WatchExpressions(gomock.Any(), &proto.WatchRequest{UserId: 1, After: 41}).Return(stream, nil)
+		gomock.InOrder(
+			stream.EXPECT().Recv().Return(&proto.ExpressionEvent{Type: "reset"}, nil),
+			stream.EXPECT().Recv().Return(&proto.ExpressionEvent{Id: 42, Type: "done", Expression: &proto.Expression{Id: 3, UserId: 1, Status: "Ok", Result: 4}}, nil),
+			stream.EXPECT().Recv().Return(nil, status.Error(codes.Unavailable, "server is shutting down")),
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/expressions/stream", nil)
+		req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+		req.Header.Set("Last-Event-ID", "41")
+		rec := httptest.NewRecorder()
+		app.expressionsStreamHandler(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "retry: 3000\n\n"+
+			"event: reset\ndata: {\"type\":\"reset\"}\n\n"+
+			"id: 42\nevent: done\ndata: {\"id\":42,\"type\":\"done\",\"expression\":{\"user_id\":1,\"id\":3,\"status\":\"Ok\",\"result\":4,\"expression\":\"\"}}\n\n",
+			rec.Body.String())
+	})
+
+	t.Run("InvalidLastEventID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/expressions/stream", nil)
+		req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+		req.Header.Set("Last-Event-ID", "abc")
+		rec := httptest.NewRecorder()
+		app.expressionsStreamHandler(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "invalid Last-Event-ID"}`, rec.Body.String())
+	})
+
+	t.Run("NoCookie", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.expressionsStreamHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expressions/stream", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDeliveriesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgent := mocks.NewMockCalcServiceClient(ctrl)
+	app := &Application{agent: mockAgent}
+
+	mockAgent.EXPECT().GetDeliveries(gomock.Any(), &proto.DeliveryRequest{UserId: 1, ExpressionId: 7}).Return(&proto.Deliveries{
+		Deliveries: []*proto.Delivery{
+			{Id: 2, ExpressionId: 7, Url: "https://example.com/hook", Status: "pending", Attempts: 1, ResponseCode: 502, Error: "unexpected response status 502 Bad Gateway", NextAttemptAt: 1700000001000, UpdatedAt: 1700000000000},
+		},
+	}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/deliveries?expression_id=7", nil)
+	req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+	rec := httptest.NewRecorder()
+	app.deliveriesHandler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id": 2, "expression_id": 7, "url": "https://example.com/hook", "status": "pending", "attempts": 1,
+		"response_code": 502, "error": "unexpected response status 502 Bad Gateway", "next_attempt_at": 1700000001000, "updated_at": 1700000000000}]`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/deliveries?expression_id=x", nil)
+	req.AddCookie(&http.Cookie{Name: "id", Value: "1"})
+	rec = httptest.NewRecorder()
+	app.deliveriesHandler(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamKeepAlive — как часто в молчащий поток событий пишется комментарий,
+// чтобы прокси и балансировщики не закрывали соединение.
+const streamKeepAlive = 15 * time.Second
+
+// streamRetry — через сколько миллисекунд браузер переподключается к оборванному потоку.
+const streamRetry = 3000
+
+// expressionsStreamHandler отправляет изменения выражений пользователя как Server-Sent Events.
+// Браузер при переподключении сам передаёт Last-Event-ID и получает пропущенные события.
+func (a *Application) expressionsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+	var after int64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		after, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || after < 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: "invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stream, err := a.agent.WatchExpressions(ctx, &proto.WatchRequest{UserId: int32(userId), After: after})
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeAgentError(w, err)
+		return
+	}
+	events := make(chan *proto.ExpressionEvent)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				failed <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx иначе копит ответ в буфере и отдаёт события пачками
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	flusher := http.NewResponseController(w)
+	if err := flusher.Flush(); err != nil {
+		log.Println(err)
+		return
+	}
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-events:
+			writeEvent(w, event)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case err := <-failed:
+			// браузер переподключится сам и догонит пропущенное по Last-Event-ID
+			log.Printf("expression stream of user %d closed: %v", userId, err)
+			return
+		case <-ctx.Done():
+			return
+		case <-a.closing:
+			return
+		}
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent пишет событие в формате text/event-stream. Событие reset приходит без ID,
+// чтобы браузер не потерял номер последнего полученного события.
+func writeEvent(w http.ResponseWriter, event *proto.ExpressionEvent) {
+	data, err := json.Marshal(convert.EventToDTO(event))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if event.GetId() != 0 {
+		fmt.Fprintf(w, "id: %d\n", event.GetId())
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.GetType(), data)
+}
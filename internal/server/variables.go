@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"net/http"
+	"strconv"
+)
+
+// userIdFromCookie достаёт идентификатор пользователя из cookie "id".
+func userIdFromCookie(r *http.Request) (int, error) {
+	cookie, err := r.Cookie("id")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(cookie.Value)
+}
+
+func (a *Application) variablesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	variables, err := a.agent.GetVariables(r.Context(), &proto.Id{Id: int32(userId)})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result := make([]*dto.Variable, len(variables.GetVariables()))
+	for i, variable := range variables.GetVariables() {
+		result[i] = convert.VariableToDTO(variable)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (a *Application) setVariableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request := new(dto.Variable)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	variable, err := a.agent.SetVariable(r.Context(), &proto.Variable{
+		UserId: int32(userId),
+		Name:   mux.Vars(r)["name"],
+		Value:  request.Value,
+	})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.VariableToDTO(variable))
+}
+
+func (a *Application) deleteVariableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	_, err = a.agent.DeleteVariable(r.Context(), &proto.Variable{UserId: int32(userId), Name: name})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"name": name})
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/philipslstwoyears/calculator-go/internal/model/convert"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/proto"
+	"net/http"
+	"strconv"
+)
+
+// webhooksHandler возвращает webhooks пользователя. Ключ подписи здесь не показывается:
+// он приходит при создании первого webhook и из rotateSecretHandler.
+func (a *Application) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhooks, err := a.agent.GetWebhooks(r.Context(), &proto.Id{Id: int32(userId)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	result := &dto.Webhooks{
+		Webhooks: make([]*dto.Webhook, len(webhooks.GetWebhooks())),
+	}
+	for i, webhook := range webhooks.GetWebhooks() {
+		result.Webhooks[i] = convert.WebhookToDTO(webhook)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (a *Application) addWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request := new(dto.WebhookRequest)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhook, err := a.agent.AddWebhook(r.Context(), &proto.Webhook{UserId: int32(userId), Url: request.URL})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convert.WebhookToDTO(webhook))
+}
+
+// rotateSecretHandler выдаёт пользователю новый ключ подписи доставок вместо прежнего.
+func (a *Application) rotateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	secret, err := a.agent.RotateWebhookSecret(r.Context(), &proto.Id{Id: int32(userId)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&dto.WebhookSecret{Secret: secret.GetSecret()})
+}
+
+func (a *Application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_, err = a.agent.DeleteWebhook(r.Context(), &proto.Webhook{UserId: int32(userId), Id: int32(id)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+// deliveriesHandler возвращает журнал доставок пользователя; ?expression_id= оставляет
+// доставки одного выражения.
+func (a *Application) deliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userId, err := userIdFromCookie(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var expressionID int
+	if raw := r.URL.Query().Get("expression_id"); raw != "" {
+		expressionID, err = strconv.Atoi(raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&dto.ErrorResponse{Error: "invalid expression_id"})
+			return
+		}
+	}
+
+	deliveries, err := a.agent.GetDeliveries(r.Context(), &proto.DeliveryRequest{UserId: int32(userId), ExpressionId: int32(expressionID)})
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	result := make([]*dto.Delivery, len(deliveries.GetDeliveries()))
+	for i, delivery := range deliveries.GetDeliveries() {
+		result[i] = convert.DeliveryToDTO(delivery)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
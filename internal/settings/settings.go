@@ -0,0 +1,43 @@
+// Package settings читает числовые настройки сервисов из переменных окружения.
+package settings
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Наименьшие допустимые значения для Int. Каждая настройка явно выбирает, допустим ли 0,
+// а что он значит («без ограничения», «выключено»), описывает сама настройка.
+const (
+	// Positive — 0 не имеет смысла и заменяется значением по умолчанию
+	Positive = 1
+	// NonNegative — 0 допустим
+	NonNegative = 0
+)
+
+// Int читает целое число из переменной окружения name. Незаданное, некорректное
+// или меньшее minimum значение заменяется на defaultValue.
+func Int(name string, defaultValue, minimum int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < minimum {
+		return defaultValue
+	}
+	return value
+}
+
+// DefaultShutdownTimeout — сколько остановка ждёт завершения начатой работы,
+// если SHUTDOWN_TIMEOUT_MS не задана.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeout читает из SHUTDOWN_TIMEOUT_MS, сколько вся остановка ждёт
+// завершения начатой работы.
+func ShutdownTimeout() time.Duration {
+	return Duration("SHUTDOWN_TIMEOUT_MS", DefaultShutdownTimeout)
+}
+
+// Duration читает положительную длительность в миллисекундах из переменной окружения name.
+// Незаданное, некорректное или неположительное значение заменяется на defaultValue.
+func Duration(name string, defaultValue time.Duration) time.Duration {
+	return time.Duration(Int(name, int(defaultValue.Milliseconds()), Positive)) * time.Millisecond
+}
@@ -1,15 +1,11 @@
 package stack
 
-type element interface {
-	int | float64 | string
-}
-
-type Stack[T element] struct {
+type Stack[T any] struct {
 	items    []T
 	topIndex int
 }
 
-func New[T element]() *Stack[T] {
+func New[T any]() *Stack[T] {
 	return &Stack[T]{
 		items:    make([]T, 0),
 		topIndex: -1,
@@ -6,98 +6,495 @@ import (
 	"fmt"
 	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
 	"sort"
+	"strings"
+	"time"
 )
 
 type Storage interface {
-	AddExpression(e dto.Expression) (int, error)
-	GetExpression(id int) (dto.Expression, bool)
+	AddExpression(e dto.Expression, maxDepth int) (int, error)
+	AddExpressions(es []dto.Expression, maxDepth int) ([]int, error)
+	GetExpression(id int) (dto.Expression, bool, error)
 	UpdateExpression(e dto.Expression) error
+	ClaimJob(lease time.Duration, userLimit int) (dto.Expression, bool, error)
+	RenewJob(id int, lease time.Duration) error
+	ReleaseJob(id int) error
+	FinishJob(e dto.Expression) error
+	RecoverJobs(maxAttempts int) (int, []dto.Expression, error)
+	QueueDepth() (int, error)
+	CancelJob(e dto.Expression) (bool, error)
 	GetExpressions(userID int) ([]dto.Expression, error)
+	QueuePositions(userID int) (map[int]int, error)
+	LastExpressionID(userID int) (int, error)
 	AddUser(e dto.User) (int, error)
 	GetUser(login string) (dto.User, bool)
+	GetVariables(userID int) ([]dto.Variable, error)
+	SetVariable(v dto.Variable) error
+	DeleteVariable(userID int, name string) (bool, error)
+	GetFunctions(userID int) ([]dto.Function, error)
+	GetFunction(userID int, name string) (dto.Function, bool)
+	SetFunction(f dto.Function) error
+	DeleteFunction(userID int, name string) (bool, error)
+	AddWebhook(w dto.Webhook) (int, error)
+	GetWebhooks(userID int) ([]dto.Webhook, error)
+	DeleteWebhook(userID, id int) (bool, error)
+	WebhookSecret(userID int) (string, error)
+	RevealWebhookSecret(userID int) (string, bool, error)
+	RotateWebhookSecret(userID int) (string, error)
+	GetDeliveries(userID, expressionID int) ([]dto.Delivery, error)
+	DueDeliveries(now time.Time, limit int) ([]dto.Delivery, error)
+	NextDeliveryAt(after time.Time) (int64, error)
+	UpdateDelivery(d dto.Delivery) error
 }
 
 type DbStorage struct {
 	db *sql.DB
+	// PriorityAging — за сколько ожидания задание в очереди поднимается на один уровень приоритета
+	PriorityAging time.Duration
 }
 
 func New(db *sql.DB) *DbStorage {
 	return &DbStorage{
-		db: db,
+		db:            db,
+		PriorityAging: dto.DefaultPriorityAging,
 	}
 }
-func (s *DbStorage) AddExpression(e dto.Expression) (int, error) {
-	var q = `
-	INSERT INTO expressions (expression, user_id, result, status) values ($1, $2, $3, $4)
-	`
-	result, err := s.db.Exec(q, e.Expression, e.UserID, e.Result, e.Status)
+
+// ErrQueueFull возвращается, когда в очереди jobs уже maxDepth выражений.
+var ErrQueueFull = errors.New("queue is full")
+
+// AddExpression сохраняет выражение и в той же транзакции ставит его в очередь jobs.
+// Если в очереди уже maxDepth выражений, выражение не сохраняется и возвращается ErrQueueFull.
+func (s *DbStorage) AddExpression(e dto.Expression, maxDepth int) (int, error) {
+	ids, err := s.AddExpressions([]dto.Expression{e}, maxDepth)
 	if err != nil {
 		return 0, err
 	}
-	id, err := result.LastInsertId()
+	if len(ids) == 0 {
+		return 0, ErrQueueFull
+	}
+	return ids[0], nil
+}
+
+// AddExpressions сохраняет выражения и ставит их в очередь jobs одной транзакцией.
+// Глубина очереди проверяется тем же INSERT, который сохраняет выражение, поэтому параллельные
+// запросы не могут вместе превысить maxDepth; maxDepth <= 0 — без ограничения.
+// Сохраняются выражения с начала es, пока в очереди есть место: возвращаются их номера,
+// а если номеров меньше, чем выражений, на остальные места не хватило.
+// Ссылка из References с номером 0 — это $prev: она указывает на предыдущее выражение пользователя,
+// в пакете — на предыдущее выражение пакета, и найденный номер записывается в References.
+func (s *DbStorage) AddExpressions(es []dto.Expression, maxDepth int) ([]int, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	return int(id), nil
+	insertExpression, err := tx.Prepare(`
+	INSERT INTO expressions (expression, user_id, result, status, mode, precision, value, inexact, error_code, error_message, deadline, priority, callback_url, created_at)
+	SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+	WHERE $15 <= 0 OR (SELECT COUNT(*) FROM jobs) < $15
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer insertExpression.Close()
+	insertJob, err := tx.Prepare(`INSERT INTO jobs (expression_id, user_id, state, priority, queued_at) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+	defer insertJob.Close()
+	insertDependency, err := tx.Prepare(`INSERT INTO dependencies (expression_id, name, depends_on) VALUES (?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+	defer insertDependency.Close()
+
+	now := time.Now().UnixMilli()
+	ids := make([]int, 0, len(es))
+	for _, e := range es {
+		result, err := insertExpression.Exec(e.Expression, e.UserID, e.Result, e.Status, e.Mode, e.Precision, e.Value, e.Inexact, e.ErrorCode, e.ErrorMessage, e.Deadline, dto.PriorityRank(e.Priority), e.CallbackURL, now, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		if inserted, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if inserted == 0 {
+			// очередь заполнена, и следующим выражениям места тоже не будет
+			break
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := insertJob.Exec(id, e.UserID, JobQueued, dto.PriorityRank(e.Priority), now); err != nil {
+			return nil, err
+		}
+		for name, dependsOn := range e.References {
+			if dependsOn == 0 {
+				q := `SELECT COALESCE(MAX(id), 0) FROM expressions WHERE user_id = ? AND id < ?`
+				if err := tx.QueryRow(q, e.UserID, id).Scan(&dependsOn); err != nil {
+					return nil, err
+				}
+				if dependsOn == 0 {
+					return nil, fmt.Errorf("%s: user %d has no previous expression", name, e.UserID)
+				}
+				e.References[name] = dependsOn
+			}
+			if _, err := insertDependency.Exec(id, name, dependsOn); err != nil {
+				return nil, err
+			}
+		}
+		ids = append(ids, int(id))
+	}
+
+	return ids, tx.Commit()
 }
 
 func (s *DbStorage) UpdateExpression(e dto.Expression) error {
+	return updateExpression(s.db, e)
+}
+
+func updateExpression(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, e dto.Expression) error {
 	q := `
 	UPDATE expressions
-	SET expression = ?, user_id = ?, result = ?, status = ?
+	SET expression = ?, user_id = ?, result = ?, status = ?, mode = ?, precision = ?, value = ?, inexact = ?, error_code = ?, error_message = ?, deadline = ?, priority = ?, callback_url = ?
 	WHERE id = ?
 	`
-	_, err := s.db.Exec(q, e.Expression, e.UserID, e.Result, e.Status, e.ID)
+	_, err := db.Exec(q, e.Expression, e.UserID, e.Result, e.Status, e.Mode, e.Precision, e.Value, e.Inexact, e.ErrorCode, e.ErrorMessage, e.Deadline, dto.PriorityRank(e.Priority), e.CallbackURL, e.ID)
 	return err
 }
 
-func (s *DbStorage) GetExpression(id int) (dto.Expression, bool) {
-	var e dto.Expression
-	q := `
-	SELECT id, expression, user_id, result, status
-	FROM expressions
-	WHERE id = ?
+// Состояния задания в очереди jobs. Задание удаляется из очереди вместе с записью результата.
+const (
+	// JobQueued — выражение ждёт вычислителя
+	JobQueued = "queued"
+	// JobRunning — выражение вычисляется, пока не истечёт lease_until
+	JobRunning = "running"
+)
+
+// fairQueue нумерует ожидающие задания — и задания с истёкшей арендой — в порядке выдачи
+// (level, turn, expression_id).
+// level — уровень приоритета, который поднимается на единицу за каждые PriorityAging ожидания,
+// поэтому более срочные задания выдаются раньше, но и batch не ждёт бесконечно.
+// Внутри уровня пользователи обслуживаются по кругу: очередь пользователя получает номер
+// turn = число его вычисляемых выражений + порядковый номер задания среди его ожидающих.
+// Поэтому тысяча выражений одного пользователя не задерживает первое выражение другого
+// дольше, чем на один круг.
+// Задания, которые ссылаются на ещё не вычисленные выражения (см. таблицу dependencies),
+// в очередь не попадают, пока у зависимостей есть задание.
+const fairQueue = `
+	WITH active AS (
+		SELECT user_id, COUNT(*) AS running FROM jobs
+		WHERE state = ? AND lease_until >= ?
+		GROUP BY user_id
+	), waiting AS (
+		SELECT expression_id, user_id, MAX(0, priority - (? - queued_at) / ?) AS level
+		FROM jobs
+		WHERE (state = ? OR (state = ? AND lease_until < ?)) AND NOT EXISTS (
+			SELECT 1 FROM dependencies JOIN jobs pending ON pending.expression_id = dependencies.depends_on
+			WHERE dependencies.expression_id = jobs.expression_id
+		)
+	), queue AS (
+		SELECT waiting.expression_id, waiting.user_id, waiting.level,
+			COALESCE(active.running, 0) + ROW_NUMBER() OVER (PARTITION BY waiting.user_id ORDER BY waiting.level, waiting.expression_id) AS turn
+		FROM waiting LEFT JOIN active ON active.user_id = waiting.user_id
+	)`
+
+func (s *DbStorage) fairQueueArgs(now time.Time) []any {
+	aging := max(s.PriorityAging.Milliseconds(), 1)
+	return []any{JobRunning, now.UnixMilli(), now.UnixMilli(), aging, JobQueued, JobRunning, now.UnixMilli()}
+}
+
+// ClaimJob забирает из очереди самое приоритетное задание, а среди равных по приоритету —
+// следующее по кругу пользователей, и выдаёт его в аренду на lease.
+// Пользователь, у которого уже вычисляется userLimit выражений, пропускается; 0 — без ограничения.
+// Если подходящих заданий нет, возвращается false.
+func (s *DbStorage) ClaimJob(lease time.Duration, userLimit int) (dto.Expression, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dto.Expression{}, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	q := fairQueue + `
+	UPDATE jobs
+	SET state = ?, lease_until = ?, attempts = attempts + 1
+	WHERE expression_id = (
+		SELECT expression_id FROM queue
+		WHERE ? = 0 OR turn <= ?
+		ORDER BY level, turn, expression_id
+		LIMIT 1
+	)
+	RETURNING expression_id
 	`
-	err := s.db.QueryRow(q, id).Scan(&e.ID, &e.Expression, &e.UserID, &e.Result, &e.Status)
+	args := append(s.fairQueueArgs(now), JobRunning, now.Add(lease).UnixMilli(), userLimit, userLimit)
+	var id int
+	err = tx.QueryRow(q, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Expression{}, false, nil
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return dto.Expression{}, false
+		return dto.Expression{}, false, err
+	}
+	e, err := scanExpression(tx.QueryRow(selectExpression+" WHERE id = ?", id))
+	if err != nil {
+		return dto.Expression{}, false, err
+	}
+	references, err := loadReferences(tx, "dependencies.expression_id = ?", id)
+	if err != nil {
+		return dto.Expression{}, false, err
+	}
+	e.References = references[id]
+	return e, true, tx.Commit()
+}
+
+// QueuePositions возвращает места в очереди ожидающих выражений пользователя userID, считая с единицы.
+// Место примерное: выражения, которые вычисляются сейчас, могут закончиться в любом порядке.
+// Очередь нумеруется одним проходом, поэтому места стоит запрашивать только там, где их показывают.
+func (s *DbStorage) QueuePositions(userID int) (map[int]int, error) {
+	q := fairQueue + `, positions AS (
+		SELECT expression_id, user_id, ROW_NUMBER() OVER (ORDER BY level, turn, expression_id) AS position
+		FROM queue
+	)
+	SELECT expression_id, position FROM positions WHERE user_id = ?
+	`
+	rows, err := s.db.Query(q, append(s.fairQueueArgs(time.Now()), userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	positions := make(map[int]int)
+	for rows.Next() {
+		var id, position int
+		if err := rows.Scan(&id, &position); err != nil {
+			return nil, err
 		}
-		return dto.Expression{}, false
+		positions[id] = position
 	}
-	return e, true
+	return positions, rows.Err()
 }
 
-func (s *DbStorage) GetExpressions(userID int) ([]dto.Expression, error) {
-	var expressions []dto.Expression
+// RenewJob продлевает аренду задания, которое ещё вычисляется.
+func (s *DbStorage) RenewJob(id int, lease time.Duration) error {
+	q := `UPDATE jobs SET lease_until = ? WHERE expression_id = ? AND state = ?`
+	_, err := s.db.Exec(q, time.Now().Add(lease).UnixMilli(), id, JobRunning)
+	return err
+}
 
+// ReleaseJob возвращает в очередь задание, которое не успело вычислиться до остановки сервера.
+// Такая попытка не засчитывается: выражение не виновато в том, что его прервали.
+func (s *DbStorage) ReleaseJob(id int) error {
+	q := `UPDATE jobs SET state = ?, lease_until = 0, attempts = MAX(attempts - 1, 0) WHERE expression_id = ? AND state = ?`
+	_, err := s.db.Exec(q, JobQueued, id, JobRunning)
+	return err
+}
+
+// FinishJob записывает результат выражения, убирает его задание из очереди
+// и ставит в очередь доставки выражения на webhooks пользователя.
+func (s *DbStorage) FinishJob(e dto.Expression) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateExpression(tx, e); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM jobs WHERE expression_id = ?`, e.ID); err != nil {
+		return err
+	}
+	if err := queueDeliveries(tx, e); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelJob убирает из очереди выражение, которое ещё ждёт вычислителя, и записывает e.
+// Если выражение уже вычисляется или вычислено, ничего не меняется и возвращается false.
+func (s *DbStorage) CancelJob(e dto.Expression) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM jobs WHERE expression_id = ? AND state = ?`, e.ID, JobQueued)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err := updateExpression(tx, e); err != nil {
+		return false, err
+	}
+	if err := queueDeliveries(tx, e); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RecoverJobs вызывается при запуске, пока вычислители ещё не работают.
+// Задания, прерванные остановкой процесса, возвращаются в очередь; принятые выражения
+// без задания (например, сохранённые до появления очереди) ставятся в очередь заново.
+// Выражения, которые прерывались уже maxAttempts раз, не возвращаются в очередь,
+// а отдаются вызывающему, чтобы он записал им ошибку.
+func (s *DbStorage) RecoverJobs(maxAttempts int) (int, []dto.Expression, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE jobs SET state = ?, lease_until = 0 WHERE state = ? AND attempts < ?`, JobQueued, JobRunning, maxAttempts)
+	if err != nil {
+		return 0, nil, err
+	}
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
 	q := `
-	SELECT id, expression, user_id, result, status
-	FROM expressions
-	WHERE user_id = ?
+	INSERT INTO jobs (expression_id, user_id, state, priority, queued_at)
+	SELECT id, user_id, ?, priority, COALESCE(NULLIF(created_at, 0), ?) FROM expressions
+	WHERE status = ? AND id NOT IN (SELECT expression_id FROM jobs)
 	`
+	result, err = tx.Exec(q, JobQueued, time.Now().UnixMilli(), dto.StatusAccepted)
+	if err != nil {
+		return 0, nil, err
+	}
+	orphaned, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
 
-	rows, err := s.db.Query(q, userID)
+	rows, err := tx.Query(selectExpression+" WHERE id IN (SELECT expression_id FROM jobs WHERE state = ?) ORDER BY id", JobRunning)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+	var failed []dto.Expression
+	for rows.Next() {
+		e, err := scanExpression(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+		failed = append(failed, e)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	return int(requeued + orphaned), failed, tx.Commit()
+}
+
+// QueueDepth возвращает число принятых, но ещё не вычисленных выражений.
+func (s *DbStorage) QueueDepth() (int, error) {
+	var depth int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&depth)
+	return depth, err
+}
+
+const selectExpression = `
+	SELECT id, expression, user_id, result, status, mode, precision, value, inexact, error_code, error_message, deadline, priority, callback_url
+	FROM expressions`
+
+func scanExpression(row interface{ Scan(dest ...any) error }) (dto.Expression, error) {
+	var e dto.Expression
+	var priority int
+	err := row.Scan(&e.ID, &e.Expression, &e.UserID, &e.Result, &e.Status, &e.Mode, &e.Precision, &e.Value, &e.Inexact, &e.ErrorCode, &e.ErrorMessage, &e.Deadline, &priority, &e.CallbackURL)
+	e.Priority = dto.PriorityName(priority)
+	return e, err
+}
+
+// loadReferences возвращает ссылки выражений, отобранных условием where, по номерам выражений.
+func loadReferences(db interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, where string, args ...any) (map[int]map[string]int, error) {
+	rows, err := db.Query(`
+	SELECT dependencies.expression_id, dependencies.name, dependencies.depends_on
+	FROM dependencies JOIN expressions ON expressions.id = dependencies.expression_id
+	WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	references := make(map[int]map[string]int)
+	for rows.Next() {
+		var id, dependsOn int
+		var name string
+		if err := rows.Scan(&id, &name, &dependsOn); err != nil {
+			return nil, err
+		}
+		if references[id] == nil {
+			references[id] = make(map[string]int)
+		}
+		references[id][name] = dependsOn
+	}
+	return references, rows.Err()
+}
+
+// GetExpression возвращает выражение id или false, если его нет. Место в очереди не заполняется,
+// см. QueuePositions.
+func (s *DbStorage) GetExpression(id int) (dto.Expression, bool, error) {
+	e, err := scanExpression(s.db.QueryRow(selectExpression+" WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Expression{}, false, nil
+	}
+	if err != nil {
+		return dto.Expression{}, false, err
+	}
+	references, err := loadReferences(s.db, "dependencies.expression_id = ?", e.ID)
+	if err != nil {
+		return dto.Expression{}, false, err
+	}
+	e.References = references[e.ID]
+	return e, true, nil
+}
+
+func (s *DbStorage) GetExpressions(userID int) ([]dto.Expression, error) {
+	var expressions []dto.Expression
+
+	rows, err := s.db.Query(selectExpression+" WHERE user_id = ?", userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var e dto.Expression
-		err := rows.Scan(&e.ID, &e.Expression, &e.UserID, &e.Result, &e.Status)
+		e, err := scanExpression(rows)
 		if err != nil {
 			return nil, err
 		}
 		expressions = append(expressions, e)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	references, err := loadReferences(s.db, "expressions.user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range expressions {
+		expressions[i].References = references[expressions[i].ID]
+	}
 	sort.Slice(expressions, func(i, j int) bool {
 		return expressions[i].ID < expressions[j].ID
 	})
 	return expressions, nil
 }
 
+// LastExpressionID возвращает номер последнего выражения пользователя или 0, если выражений нет.
+func (s *DbStorage) LastExpressionID(userID int) (int, error) {
+	var id int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM expressions WHERE user_id = ?`, userID).Scan(&id)
+	return id, err
+}
+
 func (s *DbStorage) AddUser(e dto.User) (int, error) {
 	// Используем плейсхолдеры SQLite
 	query := `INSERT INTO users (login, password) VALUES (?, ?)`
@@ -135,3 +532,125 @@ func (s *DbStorage) GetUser(login string) (dto.User, bool) {
 
 	return e, true
 }
+
+func (s *DbStorage) GetVariables(userID int) ([]dto.Variable, error) {
+	var variables []dto.Variable
+
+	q := `
+	SELECT user_id, name, value
+	FROM variables
+	WHERE user_id = ?
+	ORDER BY name
+	`
+
+	rows, err := s.db.Query(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v dto.Variable
+		if err := rows.Scan(&v.UserID, &v.Name, &v.Value); err != nil {
+			return nil, err
+		}
+		variables = append(variables, v)
+	}
+	return variables, rows.Err()
+}
+
+func (s *DbStorage) SetVariable(v dto.Variable) error {
+	q := `
+	INSERT INTO variables (user_id, name, value) VALUES (?, ?, ?)
+	ON CONFLICT (user_id, name) DO UPDATE SET value = excluded.value
+	`
+	_, err := s.db.Exec(q, v.UserID, v.Name, v.Value)
+	return err
+}
+
+func (s *DbStorage) DeleteVariable(userID int, name string) (bool, error) {
+	q := `DELETE FROM variables WHERE user_id = ? AND name = ?`
+	result, err := s.db.Exec(q, userID, name)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *DbStorage) GetFunctions(userID int) ([]dto.Function, error) {
+	var functions []dto.Function
+
+	q := `
+	SELECT user_id, name, params, body
+	FROM functions
+	WHERE user_id = ?
+	ORDER BY name
+	`
+
+	rows, err := s.db.Query(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f dto.Function
+		var params string
+		if err := rows.Scan(&f.UserID, &f.Name, &params, &f.Body); err != nil {
+			return nil, err
+		}
+		f.Params = splitParams(params)
+		functions = append(functions, f)
+	}
+	return functions, rows.Err()
+}
+
+func (s *DbStorage) GetFunction(userID int, name string) (dto.Function, bool) {
+	var f dto.Function
+	var params string
+	q := `
+	SELECT user_id, name, params, body
+	FROM functions
+	WHERE user_id = ? AND name = ?
+	`
+	err := s.db.QueryRow(q, userID, name).Scan(&f.UserID, &f.Name, &params, &f.Body)
+	if err != nil {
+		return dto.Function{}, false
+	}
+	f.Params = splitParams(params)
+	return f, true
+}
+
+func (s *DbStorage) SetFunction(f dto.Function) error {
+	q := `
+	INSERT INTO functions (user_id, name, params, body) VALUES (?, ?, ?, ?)
+	ON CONFLICT (user_id, name) DO UPDATE SET params = excluded.params, body = excluded.body
+	`
+	_, err := s.db.Exec(q, f.UserID, f.Name, strings.Join(f.Params, ","), f.Body)
+	return err
+}
+
+func (s *DbStorage) DeleteFunction(userID int, name string) (bool, error) {
+	q := `DELETE FROM functions WHERE user_id = ? AND name = ?`
+	result, err := s.db.Exec(q, userID, name)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// splitParams восстанавливает список параметров, сохранённый через запятую.
+func splitParams(params string) []string {
+	if params == "" {
+		return []string{}
+	}
+	return strings.Split(params, ",")
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"time"
+)
+
+// MaxDeliveries — сколько последних доставок возвращает GetDeliveries.
+const MaxDeliveries = 100
+
+// queueDeliveries ставит в очередь доставку вычисленного выражения на каждый webhook пользователя
+// и на callback_url выражения. Вызывается в транзакции, которая записывает результат,
+// поэтому доставка не теряется, даже если процесс остановится сразу после записи.
+func queueDeliveries(tx *sql.Tx, e dto.Expression) error {
+	now := time.Now().UnixMilli()
+	// UNION убирает повтор, если callback_url совпадает с одним из webhooks
+	q := `
+	INSERT INTO deliveries (expression_id, user_id, url, status, next_attempt_at, updated_at)
+	SELECT ?, user_id, url, ?, ?, ? FROM webhooks WHERE user_id = ?
+	UNION
+	SELECT id, user_id, callback_url, ?, ?, ? FROM expressions WHERE id = ? AND callback_url != ''
+	`
+	_, err := tx.Exec(q,
+		e.ID, dto.DeliveryPending, now, now, e.UserID,
+		dto.DeliveryPending, now, now, e.ID,
+	)
+	return err
+}
+
+// AddWebhook регистрирует адрес пользователя. Если адрес уже зарегистрирован,
+// возвращается номер существующего webhook.
+func (s *DbStorage) AddWebhook(w dto.Webhook) (int, error) {
+	q := `INSERT INTO webhooks (user_id, url) VALUES (?, ?) ON CONFLICT (user_id, url) DO NOTHING`
+	if _, err := s.db.Exec(q, w.UserID, w.URL); err != nil {
+		return 0, err
+	}
+	var id int
+	err := s.db.QueryRow(`SELECT id FROM webhooks WHERE user_id = ? AND url = ?`, w.UserID, w.URL).Scan(&id)
+	return id, err
+}
+
+func (s *DbStorage) GetWebhooks(userID int) ([]dto.Webhook, error) {
+	var webhooks []dto.Webhook
+
+	rows, err := s.db.Query(`SELECT id, user_id, url FROM webhooks WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w dto.Webhook
+		if err := rows.Scan(&w.ID, &w.UserID, &w.URL); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook удаляет webhook пользователя. Уже поставленные в очередь доставки на этот адрес
+// продолжают отправляться.
+func (s *DbStorage) DeleteWebhook(userID, id int) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// WebhookSecret возвращает ключ, которым подписываются доставки пользователя,
+// и создаёт его при первом обращении.
+func (s *DbStorage) WebhookSecret(userID int) (string, error) {
+	key, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	q := `UPDATE users SET webhook_secret = ? WHERE id = ? AND webhook_secret = ''`
+	if _, err := s.db.Exec(q, key, userID); err != nil {
+		return "", err
+	}
+	var secret string
+	err = s.db.QueryRow(`SELECT webhook_secret FROM users WHERE id = ?`, userID).Scan(&secret)
+	return secret, err
+}
+
+// RevealWebhookSecret возвращает ключ пользователя так же, как WebhookSecret, и отмечает,
+// что он показан; first сообщает, что ключ показывается впервые. Ключ мог появиться раньше,
+// при доставке на callback_url, поэтому «создан» и «показан» хранятся отдельно.
+func (s *DbStorage) RevealWebhookSecret(userID int) (secret string, first bool, err error) {
+	secret, err = s.WebhookSecret(userID)
+	if err != nil {
+		return "", false, err
+	}
+	q := `UPDATE users SET webhook_secret_shown = 1 WHERE id = ? AND webhook_secret_shown = 0`
+	result, err := s.db.Exec(q, userID)
+	if err != nil {
+		return "", false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return "", false, err
+	}
+	return secret, affected > 0, nil
+}
+
+// RotateWebhookSecret заменяет ключ пользователя новым и возвращает его. Доставки,
+// которые отправятся после этого, подписываются новым ключом.
+func (s *DbStorage) RotateWebhookSecret(userID int) (string, error) {
+	key, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec(`UPDATE users SET webhook_secret = ?, webhook_secret_shown = 1 WHERE id = ?`, key, userID)
+	return key, err
+}
+
+func newSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+const selectDelivery = `
+	SELECT id, expression_id, user_id, url, status, attempts, response_code, error, next_attempt_at, updated_at
+	FROM deliveries`
+
+func scanDeliveries(rows *sql.Rows) ([]dto.Delivery, error) {
+	defer rows.Close()
+	var deliveries []dto.Delivery
+	for rows.Next() {
+		var d dto.Delivery
+		err := rows.Scan(&d.ID, &d.ExpressionID, &d.UserID, &d.URL, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &d.NextAttemptAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDeliveries возвращает последние MaxDeliveries доставок пользователя, новые первыми.
+// Если expressionID не ноль, возвращаются только доставки этого выражения.
+func (s *DbStorage) GetDeliveries(userID, expressionID int) ([]dto.Delivery, error) {
+	rows, err := s.db.Query(selectDelivery+`
+	WHERE user_id = ? AND (? = 0 OR expression_id = ?)
+	ORDER BY id DESC LIMIT ?`, userID, expressionID, expressionID, MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// DueDeliveries возвращает до limit доставок, время попытки которых уже наступило.
+func (s *DbStorage) DueDeliveries(now time.Time, limit int) ([]dto.Delivery, error) {
+	rows, err := s.db.Query(selectDelivery+`
+	WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY next_attempt_at, id LIMIT ?`, dto.DeliveryPending, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// NextDeliveryAt возвращает время ближайшей попытки доставки позже after в миллисекундах Unix
+// или 0, если таких доставок нет.
+func (s *DbStorage) NextDeliveryAt(after time.Time) (int64, error) {
+	var next sql.NullInt64
+	err := s.db.QueryRow(`SELECT MIN(next_attempt_at) FROM deliveries WHERE status = ? AND next_attempt_at > ?`, dto.DeliveryPending, after.UnixMilli()).Scan(&next)
+	return next.Int64, err
+}
+
+// UpdateDelivery записывает результат попытки доставки.
+func (s *DbStorage) UpdateDelivery(d dto.Delivery) error {
+	q := `
+	UPDATE deliveries
+	SET status = ?, attempts = ?, response_code = ?, error = ?, next_attempt_at = ?, updated_at = ?
+	WHERE id = ?
+	`
+	_, err := s.db.Exec(q, d.Status, d.Attempts, d.ResponseCode, d.Error, d.NextAttemptAt, d.UpdatedAt, d.ID)
+	return err
+}
@@ -0,0 +1,383 @@
+// Package webhook доставляет вычисленные выражения на адреса пользователей.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	calc "github.com/philipslstwoyears/calculator-go/internal/calculator"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/philipslstwoyears/calculator-go/internal/settings"
+	"github.com/philipslstwoyears/calculator-go/internal/storage"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Заголовки доставки. Получатель проверяет подпись: HMAC-SHA256 тела запроса
+// с ключом пользователя, в шестнадцатеричном виде после "sha256=".
+const (
+	SignatureHeader = "X-Calculator-Signature"
+	EventHeader     = "X-Calculator-Event"
+	DeliveryHeader  = "X-Calculator-Delivery"
+)
+
+// Значения по умолчанию.
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = time.Second
+	DefaultMaxBackoff  = time.Hour
+	DefaultTimeout     = 10 * time.Second
+)
+
+// batchSize — сколько доставок отправляется одновременно.
+const batchSize = 32
+
+// ErrBlockedAddress возвращается, когда адрес доставки указывает во внутреннюю сеть:
+// на loopback, частные, link-local, multicast или неуказанные адреса.
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// idlePoll — как часто очередь доставок проверяется, даже если о новых доставках не сообщали.
+const idlePoll = time.Minute
+
+type Config struct {
+	// MaxAttempts — после стольких неудачных попыток доставка помечается failed
+	MaxAttempts int
+	// Backoff — пауза перед второй попыткой; каждая следующая пауза вдвое длиннее, но не длиннее MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout — сколько ждать ответа получателя
+	Timeout time.Duration
+	// AllowedNetworks — внутренние сети, в которые всё же можно доставлять, например адрес
+	// сервиса в той же локальной сети; по умолчанию внутренние адреса запрещены
+	AllowedNetworks []netip.Prefix
+}
+
+// ConfigFromEnv читает настройки из WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_MS,
+// WEBHOOK_MAX_BACKOFF_MS, WEBHOOK_TIMEOUT_MS и WEBHOOK_ALLOWED_NETWORKS — списка сетей
+// через запятую, например "10.1.2.0/24, 192.168.0.10/32".
+func ConfigFromEnv() (Config, error) {
+	networks, err := parseNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("WEBHOOK_ALLOWED_NETWORKS: %w", err)
+	}
+	return Config{
+		MaxAttempts:     settings.Int("WEBHOOK_MAX_ATTEMPTS", DefaultMaxAttempts, settings.Positive),
+		Backoff:         settings.Duration("WEBHOOK_BACKOFF_MS", DefaultBackoff),
+		MaxBackoff:      settings.Duration("WEBHOOK_MAX_BACKOFF_MS", DefaultMaxBackoff),
+		Timeout:         settings.Duration("WEBHOOK_TIMEOUT_MS", DefaultTimeout),
+		AllowedNetworks: networks,
+	}, nil
+}
+
+func parseNetworks(raw string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		network, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
+
+// allowed проверяет адрес, к которому подключается доставка, уже после разрешения имени,
+// поэтому имя, указывающее во внутреннюю сеть, не обходит проверку.
+func (c Config) allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, network := range c.AllowedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return !(addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		addr.IsUnspecified())
+}
+
+// control не даёт открыть соединение с запрещённым адресом, см. Config.allowed.
+func (c Config) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !c.allowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// client возвращает HTTP-клиент доставок. Он подключается только к разрешённым адресам,
+// не ходит через прокси из окружения — иначе проверялся бы адрес прокси, а не получателя —
+// и не следует редиректам: ответ 3xx считается неудачной попыткой.
+func (c Config) client() *http.Client {
+	dialer := &net.Dialer{Timeout: c.Timeout, Control: c.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   c.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// backoff возвращает паузу после attempts неудачных попыток.
+func (c Config) backoff(attempts int) time.Duration {
+	delay := c.Backoff
+	for i := 1; i < attempts && delay < c.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxBackoff)
+}
+
+// ValidateURL проверяет адрес webhook или callback_url. Адреса во внутренних сетях
+// отклоняются уже при отправке, когда известен адрес, к которому подключается доставка.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return errors.New("url has no host")
+	}
+	return nil
+}
+
+// Sign возвращает значение заголовка SignatureHeader для тела body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender отправляет доставки, которые storage ставит в очередь вместе с результатом выражения.
+// Неудачные попытки повторяются с экспоненциальной паузой; доставки ждут в базе,
+// поэтому повторы продолжаются и после перезапуска.
+type Sender struct {
+	config  Config
+	storage storage.Storage
+	client  *http.Client
+	// Events будит отправку, как только выражение вычислено; nil — очередь проверяется раз в idlePoll
+	Events *calc.Events
+
+	// stop перестаёт начинать новые доставки, abort прерывает начатые
+	stop  context.CancelFunc
+	abort context.CancelFunc
+	done  chan struct{}
+}
+
+func New(storage storage.Storage, config Config) *Sender {
+	return &Sender{
+		config:  config,
+		storage: storage,
+		client:  config.client(),
+	}
+}
+
+// Start запускает отправку доставок.
+func (s *Sender) Start() {
+	ctx, stop := context.WithCancel(context.Background())
+	sending, abort := context.WithCancel(context.Background())
+	s.stop = stop
+	s.abort = abort
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx, sending)
+	}()
+}
+
+// Shutdown останавливает отправку: новые доставки не начинаются, а начатые отправляются
+// до отмены ctx. Затем оставшиеся попытки прерываются, и Shutdown ждёт, пока они закончатся,
+// чтобы ни одна не обращалась к базе после её закрытия. Прерванные попытки не засчитываются:
+// такие доставки отправятся заново после запуска.
+func (s *Sender) Shutdown(ctx context.Context) {
+	if s.stop == nil {
+		return
+	}
+	s.stop()
+	select {
+	case <-s.done:
+		return
+	case <-ctx.Done():
+	}
+	s.abort()
+	<-s.done
+}
+
+// run отправляет доставки, пока не отменён ctx; сами попытки выполняются с контекстом sending.
+// Одновременно отправляется до batchSize доставок, и освободившееся место сразу занимает
+// следующая, поэтому медленный получатель не задерживает остальные доставки.
+func (s *Sender) run(ctx, sending context.Context) {
+	finished, unsubscribe := s.subscribe()
+	defer func() { unsubscribe() }()
+	inFlight := make(map[int]bool)
+	// буфер на все места: закончившаяся доставка не ждёт, пока run её заберёт
+	delivered := make(chan int, batchSize)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		wait, err := s.startDue(sending, inFlight, delivered, &wg)
+		if err != nil {
+			log.Println("[WEBHOOK]", err)
+			wait = s.config.Backoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+		case id := <-delivered:
+			delete(inFlight, id)
+		case _, ok := <-finished:
+			if !ok {
+				// подписка отстала и отключена; очередь всё равно перечитывается из базы
+				finished, unsubscribe = s.subscribe()
+			}
+		case <-timer.C:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// subscribe подписывается на завершение выражений: после него в очереди могут появиться доставки.
+func (s *Sender) subscribe() (<-chan calc.Event, func()) {
+	return s.Events.Subscribe(calc.Event.Final, math.MaxInt64)
+}
+
+// startDue начинает доставки, время которых наступило, на свободных из batchSize местах
+// и возвращает, сколько ждать следующих. inFlight — начатые доставки: закончив, доставка
+// отправляет свой номер в delivered, и run освобождает её место.
+func (s *Sender) startDue(ctx context.Context, inFlight map[int]bool, delivered chan<- int, wg *sync.WaitGroup) (time.Duration, error) {
+	if len(inFlight) == batchSize {
+		return idlePoll, nil
+	}
+	now := time.Now()
+	// начатые доставки остаются в очереди, пока не записан результат попытки, поэтому
+	// запрашивается batchSize доставок: кроме начатых, среди них хватит на все свободные места
+	deliveries, err := s.storage.DueDeliveries(now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		if inFlight[delivery.ID] || len(inFlight) == batchSize {
+			continue
+		}
+		inFlight[delivery.ID] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.deliver(ctx, delivery)
+			delivered <- delivery.ID
+		}()
+	}
+	if len(deliveries) == batchSize {
+		// наступивших доставок может быть больше: следующие начнутся, когда освободится место
+		return idlePoll, nil
+	}
+	next, err := s.storage.NextDeliveryAt(now)
+	if err != nil {
+		return 0, err
+	}
+	if next == 0 {
+		return idlePoll, nil
+	}
+	return min(max(time.Until(time.UnixMilli(next)), 0), idlePoll), nil
+}
+
+// deliver делает одну попытку доставки и записывает её результат.
+func (s *Sender) deliver(ctx context.Context, delivery dto.Delivery) {
+	code, err := s.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// сервер останавливается; попытка повторится после запуска
+		return
+	}
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.Error = ""
+	delivery.NextAttemptAt = 0
+	delivery.UpdatedAt = now.UnixMilli()
+	switch {
+	case err == nil:
+		delivery.Status = dto.DeliveryDelivered
+	case delivery.Attempts >= s.config.MaxAttempts:
+		delivery.Status = dto.DeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(s.config.backoff(delivery.Attempts)).UnixMilli()
+	}
+	if err != nil {
+		log.Printf("[WEBHOOK] delivery %d of expression %d to %s, attempt %d: %v", delivery.ID, delivery.ExpressionID, delivery.URL, delivery.Attempts, err)
+	}
+	if err := s.storage.UpdateDelivery(delivery); err != nil {
+		log.Println("[WEBHOOK]", err)
+	}
+}
+
+// send отправляет выражение доставки и возвращает HTTP-код ответа.
+// Ошибкой считается и ответ с кодом не из 2xx.
+func (s *Sender) send(ctx context.Context, delivery dto.Delivery) (int, error) {
+	expression, ok, err := s.storage.GetExpression(delivery.ExpressionID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errors.New("expression not found")
+	}
+	body, err := json.Marshal(expression)
+	if err != nil {
+		return 0, err
+	}
+	secret, err := s.storage.WebhookSecret(delivery.UserID)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+	req.Header.Set(EventHeader, calc.EventType(expression))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// тело дочитывается, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/philipslstwoyears/calculator-go/internal/mocks"
+	"github.com/philipslstwoyears/calculator-go/internal/model/dto"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loopback разрешает доставки на httptest-серверы: по умолчанию внутренние адреса запрещены.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func TestBackoff(t *testing.T) {
+	config := Config{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	var delays []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		delays = append(delays, config.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://example.com/hook"))
+	assert.Error(t, ValidateURL("ftp://example.com/hook"))
+	assert.Error(t, ValidateURL("http:///hook"))
+	assert.Error(t, ValidateURL("example.com"))
+}
+
+func TestConfigAllowed(t *testing.T) {
+	blocked := []string{"127.0.0.1", "::1", "10.0.0.1", "172.16.5.4", "192.168.1.1", "169.254.169.254",
+		"fe80::1", "fc00::1", "224.0.0.1", "ff02::1", "0.0.0.0", "::", "::ffff:127.0.0.1"}
+	for _, raw := range blocked {
+		assert.False(t, Config{}.allowed(netip.MustParseAddr(raw)), raw)
+	}
+	assert.True(t, Config{}.allowed(netip.MustParseAddr("93.184.216.34")))
+	assert.True(t, Config{}.allowed(netip.MustParseAddr("2606:2800:220:1::1")))
+
+	// внутренние сети открываются только явно
+	config := Config{AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.1.2.0/24")}}
+	assert.True(t, config.allowed(netip.MustParseAddr("10.1.2.3")))
+	assert.False(t, config.allowed(netip.MustParseAddr("10.1.3.3")))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOWED_NETWORKS", "10.1.2.3/24, 192.168.0.10/32")
+	config, err := ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.2.0/24"), netip.MustParsePrefix("192.168.0.10/32")}, config.AllowedNetworks)
+
+	t.Setenv("WEBHOOK_ALLOWED_NETWORKS", "10.1.2.3")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestDeliver(t *testing.T) {
+	expression := dto.Expression{ID: 7, UserID: 1, Expression: "2+2", Status: "Ok", Result: 4}
+	body := `{"user_id":1,"id":7,"status":"Ok","result":4,"expression":"2+2"}`
+	var status int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		assert.Equal(t, body, string(got))
+		assert.Equal(t, Sign("secret", got), r.Header.Get(SignatureHeader))
+		assert.Equal(t, "done", r.Header.Get(EventHeader))
+		assert.Equal(t, "3", r.Header.Get(DeliveryHeader))
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		check    func(t *testing.T, d dto.Delivery)
+	}{
+		{
+			name:   "Delivered",
+			status: http.StatusNoContent,
+			check: func(t *testing.T, d dto.Delivery) {
+				assert.Equal(t, dto.DeliveryDelivered, d.Status)
+				assert.Equal(t, 1, d.Attempts)
+				assert.Equal(t, http.StatusNoContent, d.ResponseCode)
+				assert.Zero(t, d.NextAttemptAt)
+			},
+		},
+		{
+			name:   "Retry",
+			status: http.StatusBadGateway,
+			check: func(t *testing.T, d dto.Delivery) {
+				assert.Equal(t, dto.DeliveryPending, d.Status)
+				assert.Equal(t, http.StatusBadGateway, d.ResponseCode)
+				assert.Equal(t, "unexpected response status 502 Bad Gateway", d.Error)
+				assert.InDelta(t, time.Now().Add(time.Second).UnixMilli(), d.NextAttemptAt, 500)
+			},
+		},
+		{
+			name:     "Failed",
+			status:   http.StatusInternalServerError,
+			attempts: 2,
+			check: func(t *testing.T, d dto.Delivery) {
+				assert.Equal(t, dto.DeliveryFailed, d.Status)
+				assert.Equal(t, 3, d.Attempts)
+				assert.Zero(t, d.NextAttemptAt)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			storage.EXPECT().GetExpression(7).Return(expression, true, nil)
+			storage.EXPECT().WebhookSecret(1).Return("secret", nil)
+			var updated dto.Delivery
+			storage.EXPECT().UpdateDelivery(gomock.Any()).Do(func(d dto.Delivery) { updated = d }).Return(nil)
+			status = test.status
+
+			sender := New(storage, Config{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute, Timeout: time.Second, AllowedNetworks: loopback})
+			sender.deliver(context.Background(), dto.Delivery{
+				ID:           3,
+				ExpressionID: 7,
+				UserID:       1,
+				URL:          receiver.URL,
+				Status:       dto.DeliveryPending,
+				Attempts:     test.attempts,
+			})
+			test.check(t, updated)
+		})
+	}
+}
+
+func TestDeliverShutdown(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	storage.EXPECT().GetExpression(7).Return(dto.Expression{ID: 7, UserID: 1, Status: "Ok"}, true, nil)
+	storage.EXPECT().WebhookSecret(1).Return("secret", nil)
+	// прерванная остановкой попытка не записывается
+	storage.EXPECT().UpdateDelivery(gomock.Any()).Times(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// сервер замечает обрыв соединения, только когда тело запроса прочитано
+		io.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer receiver.Close()
+
+	sender := New(storage, Config{MaxAttempts: 3, Backoff: time.Second, Timeout: time.Minute, AllowedNetworks: loopback})
+	sender.deliver(ctx, dto.Delivery{ID: 3, ExpressionID: 7, UserID: 1, URL: receiver.URL})
+}
+
+func TestDeliverRefusesInternalAddresses(t *testing.T) {
+	received := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer internal.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		config Config
+		err    string
+	}{
+		{
+			name:   "Loopback",
+			url:    internal.URL,
+			config: Config{},
+			err:    ErrBlockedAddress.Error(),
+		},
+		{
+			// даже если сам получатель разрешён, его редирект не выполняется
+			name:   "Redirect",
+			url:    redirect.URL,
+			config: Config{AllowedNetworks: []netip.Prefix{netip.MustParsePrefix(netip.MustParseAddrPort(redirect.Listener.Addr().String()).Addr().String() + "/32")}},
+			err:    "unexpected response status 302 Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			storage := mocks.NewMockStorage(c)
+			storage.EXPECT().GetExpression(7).Return(dto.Expression{ID: 7, UserID: 1, Status: "Ok"}, true, nil)
+			storage.EXPECT().WebhookSecret(1).Return("secret", nil)
+			var updated dto.Delivery
+			storage.EXPECT().UpdateDelivery(gomock.Any()).Do(func(d dto.Delivery) { updated = d }).Return(nil)
+
+			test.config.MaxAttempts = 3
+			test.config.Backoff = time.Second
+			test.config.MaxBackoff = time.Minute
+			test.config.Timeout = time.Second
+			New(storage, test.config).deliver(context.Background(), dto.Delivery{ID: 3, ExpressionID: 7, UserID: 1, URL: test.url, Status: dto.DeliveryPending})
+			assert.Contains(t, updated.Error, test.err)
+			assert.Equal(t, dto.DeliveryPending, updated.Status)
+			assert.False(t, received, "internal address received a delivery")
+		})
+	}
+}
+
+func TestStartDue(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	storage := mocks.NewMockStorage(c)
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	storage.EXPECT().GetExpression(7).Return(dto.Expression{ID: 7, UserID: 1, Status: "Ok"}, true, nil).AnyTimes()
+	storage.EXPECT().WebhookSecret(1).Return("secret", nil).AnyTimes()
+	storage.EXPECT().UpdateDelivery(gomock.Any()).Return(nil).AnyTimes()
+	slow := dto.Delivery{ID: 1, ExpressionID: 7, UserID: 1, URL: receiver.URL + "/slow"}
+	fast := dto.Delivery{ID: 2, ExpressionID: 7, UserID: 1, URL: receiver.URL + "/fast"}
+	next := dto.Delivery{ID: 3, ExpressionID: 7, UserID: 1, URL: receiver.URL + "/fast"}
+	storage.EXPECT().DueDeliveries(gomock.Any(), batchSize).Return([]dto.Delivery{slow, fast}, nil)
+	// медленная доставка ещё не записана и снова приходит из очереди, но повторно не начинается
+	storage.EXPECT().DueDeliveries(gomock.Any(), batchSize).Return([]dto.Delivery{slow, next}, nil)
+	storage.EXPECT().NextDeliveryAt(gomock.Any()).Return(int64(0), nil).Times(2)
+
+	sender := New(storage, Config{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute, Timeout: time.Minute, AllowedNetworks: loopback})
+	inFlight := make(map[int]bool)
+	delivered := make(chan int, batchSize)
+	var wg sync.WaitGroup
+	wait, err := sender.startDue(context.Background(), inFlight, delivered, &wg)
+	assert.NoError(t, err)
+	assert.Equal(t, idlePoll, wait)
+
+	// быстрая доставка заканчивается, пока медленная ещё ждёт ответа
+	assert.Equal(t, 2, <-delivered)
+	delete(inFlight, 2)
+	_, err = sender.startDue(context.Background(), inFlight, delivered, &wg)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, <-delivered)
+	assert.Equal(t, map[int]bool{1: true, 3: true}, inFlight)
+
+	release <- struct{}{}
+	assert.Equal(t, 1, <-delivered)
+	wg.Wait()
+}
@@ -25,6 +25,11 @@ type Request struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
 	UserId        int32                  `protobuf:"varint,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	Mode          string                 `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`               // float (по умолчанию), rational или decimal
+	Precision     int32                  `protobuf:"varint,4,opt,name=precision,proto3" json:"precision,omitempty"`    // знаков после запятой в режиме decimal
+	Deadline      int64                  `protobuf:"varint,5,opt,name=deadline,proto3" json:"deadline,omitempty"`      // срок вычисления в миллисекундах Unix, 0 — без срока
+	Priority      string                 `protobuf:"bytes,6,opt,name=priority,proto3" json:"priority,omitempty"`       // interactive, normal (по умолчанию) или batch
+	CallbackUrl   string                 `protobuf:"bytes,7,opt,name=callbackUrl,proto3" json:"callbackUrl,omitempty"` // куда отправить выражение после вычисления
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,6 +78,41 @@ func (x *Request) GetUserId() int32 {
 	return 0
 }
 
+func (x *Request) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Request) GetPrecision() int32 {
+	if x != nil {
+		return x.Precision
+	}
+	return 0
+}
+
+func (x *Request) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+func (x *Request) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *Request) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
 type Id struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -121,9 +161,20 @@ type Expression struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
-	Result        float32                `protobuf:"fixed32,3,opt,name=result,proto3" json:"result,omitempty"`
+	Result        float64                `protobuf:"fixed64,17,opt,name=result,proto3" json:"result,omitempty"` // double, а не float: float32 теряет точность уже на 123456789
 	Expression    string                 `protobuf:"bytes,4,opt,name=expression,proto3" json:"expression,omitempty"`
 	UserId        int32                  `protobuf:"varint,5,opt,name=userId,proto3" json:"userId,omitempty"`
+	Mode          string                 `protobuf:"bytes,6,opt,name=mode,proto3" json:"mode,omitempty"`
+	Precision     int32                  `protobuf:"varint,7,opt,name=precision,proto3" json:"precision,omitempty"`
+	Value         string                 `protobuf:"bytes,8,opt,name=value,proto3" json:"value,omitempty"`         // точный результат в режимах rational и decimal
+	ErrorCode     string                 `protobuf:"bytes,9,opt,name=errorCode,proto3" json:"errorCode,omitempty"` // код ошибки вычисления, например division_by_zero
+	ErrorMessage  string                 `protobuf:"bytes,10,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	Deadline      int64                  `protobuf:"varint,11,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	Position      int32                  `protobuf:"varint,12,opt,name=position,proto3" json:"position,omitempty"` // место ожидающего выражения в очереди
+	Priority      string                 `protobuf:"bytes,13,opt,name=priority,proto3" json:"priority,omitempty"`
+	CallbackUrl   string                 `protobuf:"bytes,14,opt,name=callbackUrl,proto3" json:"callbackUrl,omitempty"`
+	References    []*Reference           `protobuf:"bytes,15,rep,name=references,proto3" json:"references,omitempty"`
+	Inexact       bool                   `protobuf:"varint,16,opt,name=inexact,proto3" json:"inexact,omitempty"` // часть точного вычисления выполнена в float64
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -172,7 +223,7 @@ func (x *Expression) GetStatus() string {
 	return ""
 }
 
-func (x *Expression) GetResult() float32 {
+func (x *Expression) GetResult() float64 {
 	if x != nil {
 		return x.Result
 	}
@@ -193,6 +244,221 @@ func (x *Expression) GetUserId() int32 {
 	return 0
 }
 
+func (x *Expression) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Expression) GetPrecision() int32 {
+	if x != nil {
+		return x.Precision
+	}
+	return 0
+}
+
+func (x *Expression) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Expression) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *Expression) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *Expression) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+func (x *Expression) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Expression) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *Expression) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+func (x *Expression) GetReferences() []*Reference {
+	if x != nil {
+		return x.References
+	}
+	return nil
+}
+
+func (x *Expression) GetInexact() bool {
+	if x != nil {
+		return x.Inexact
+	}
+	return false
+}
+
+// Reference — ссылка выражения на результат другого выражения пользователя.
+type Reference struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // как записана в выражении: #42 или $prev
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`    // номер выражения, на которое указывает ссылка
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reference) Reset() {
+	*x = Reference{}
+	mi := &file_proto_messages_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reference) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reference) ProtoMessage() {}
+
+func (x *Reference) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reference.ProtoReflect.Descriptor instead.
+func (*Reference) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Reference) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Reference) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// SyntaxError передаётся в деталях статуса InvalidArgument, когда в выражении синтаксическая ошибка.
+type SyntaxError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int32                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	Column        int32                  `protobuf:"varint,2,opt,name=column,proto3" json:"column,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	Expected      string                 `protobuf:"bytes,4,opt,name=expected,proto3" json:"expected,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	Code          string                 `protobuf:"bytes,6,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyntaxError) Reset() {
+	*x = SyntaxError{}
+	mi := &file_proto_messages_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyntaxError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyntaxError) ProtoMessage() {}
+
+func (x *SyntaxError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyntaxError.ProtoReflect.Descriptor instead.
+func (*SyntaxError) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SyntaxError) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *SyntaxError) GetColumn() int32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *SyntaxError) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *SyntaxError) GetExpected() string {
+	if x != nil {
+		return x.Expected
+	}
+	return ""
+}
+
+func (x *SyntaxError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SyntaxError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
 type Expressions struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Expressions   []*Expression          `protobuf:"bytes,1,rep,name=expressions,proto3" json:"expressions,omitempty"`
@@ -202,7 +468,7 @@ type Expressions struct {
 
 func (x *Expressions) Reset() {
 	*x = Expressions{}
-	mi := &file_proto_messages_proto_msgTypes[3]
+	mi := &file_proto_messages_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -214,7 +480,7 @@ func (x *Expressions) String() string {
 func (*Expressions) ProtoMessage() {}
 
 func (x *Expressions) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_messages_proto_msgTypes[3]
+	mi := &file_proto_messages_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -227,7 +493,7 @@ func (x *Expressions) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Expressions.ProtoReflect.Descriptor instead.
 func (*Expressions) Descriptor() ([]byte, []int) {
-	return file_proto_messages_proto_rawDescGZIP(), []int{3}
+	return file_proto_messages_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Expressions) GetExpressions() []*Expression {
@@ -237,29 +503,29 @@ func (x *Expressions) GetExpressions() []*Expression {
 	return nil
 }
 
-type User struct {
+type BatchRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Login         string                 `protobuf:"bytes,1,opt,name=login,proto3" json:"login,omitempty"`
-	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"` // заменяет userId каждого запроса
+	Requests      []*Request             `protobuf:"bytes,2,rep,name=requests,proto3" json:"requests,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *User) Reset() {
-	*x = User{}
-	mi := &file_proto_messages_proto_msgTypes[4]
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_proto_messages_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *User) String() string {
+func (x *BatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*User) ProtoMessage() {}
+func (*BatchRequest) ProtoMessage() {}
 
-func (x *User) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_messages_proto_msgTypes[4]
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -270,59 +536,1641 @@ func (x *User) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use User.ProtoReflect.Descriptor instead.
-func (*User) Descriptor() ([]byte, []int) {
-	return file_proto_messages_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *User) GetLogin() string {
+func (x *BatchRequest) GetUserId() int32 {
 	if x != nil {
-		return x.Login
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *BatchRequest) GetRequests() []*Request {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+// BatchItem — результат одного запроса пакета: номер выражения или ошибка.
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Syntax        *SyntaxError           `protobuf:"bytes,3,opt,name=syntax,proto3" json:"syntax,omitempty"`
+	Code          string                 `protobuf:"bytes,4,opt,name=code,proto3" json:"code,omitempty"`                  // код gRPC-статуса ошибки, например ResourceExhausted
+	RetryAfterMs  int64                  `protobuf:"varint,5,opt,name=retryAfterMs,proto3" json:"retryAfterMs,omitempty"` // для ResourceExhausted — через сколько повторить это выражение
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_proto_messages_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchItem) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BatchItem) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func (x *User) GetPassword() string {
+func (x *BatchItem) GetSyntax() *SyntaxError {
 	if x != nil {
-		return x.Password
+		return x.Syntax
+	}
+	return nil
+}
+
+func (x *BatchItem) GetCode() string {
+	if x != nil {
+		return x.Code
 	}
 	return ""
 }
 
-var File_proto_messages_proto protoreflect.FileDescriptor
+func (x *BatchItem) GetRetryAfterMs() int64 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
 
-const file_proto_messages_proto_rawDesc = "" +
-	"\n" +
-	"\x14proto/messages.proto\x12\x04calc\"A\n" +
-	"\aRequest\x12\x1e\n" +
-	"\n" +
-	"expression\x18\x01 \x01(\tR\n" +
-	"expression\x12\x16\n" +
-	"\x06userId\x18\x02 \x01(\x05R\x06userId\"\x14\n" +
-	"\x02Id\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x05R\x02id\"\x84\x01\n" +
+type BatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"` // в порядке запросов
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResult) Reset() {
+	*x = BatchResult{}
+	mi := &file_proto_messages_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResult) ProtoMessage() {}
+
+func (x *BatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResult.ProtoReflect.Descriptor instead.
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchResult) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Login         string                 `protobuf:"bytes,1,opt,name=login,proto3" json:"login,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_proto_messages_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *User) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+func (x *User) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type Variable struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variable) Reset() {
+	*x = Variable{}
+	mi := &file_proto_messages_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variable) ProtoMessage() {}
+
+func (x *Variable) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variable.ProtoReflect.Descriptor instead.
+func (*Variable) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Variable) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Variable) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variable) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type Variables struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variables     []*Variable            `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variables) Reset() {
+	*x = Variables{}
+	mi := &file_proto_messages_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variables) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variables) ProtoMessage() {}
+
+func (x *Variables) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variables.ProtoReflect.Descriptor instead.
+func (*Variables) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Variables) GetVariables() []*Variable {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+type Function struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Params        []string               `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	Body          string                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Function) Reset() {
+	*x = Function{}
+	mi := &file_proto_messages_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Function) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Function) ProtoMessage() {}
+
+func (x *Function) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Function.ProtoReflect.Descriptor instead.
+func (*Function) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Function) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Function) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Function) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *Function) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+type Functions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Functions     []*Function            `protobuf:"bytes,1,rep,name=functions,proto3" json:"functions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Functions) Reset() {
+	*x = Functions{}
+	mi := &file_proto_messages_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Functions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Functions) ProtoMessage() {}
+
+func (x *Functions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Functions.ProtoReflect.Descriptor instead.
+func (*Functions) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Functions) GetFunctions() []*Function {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_proto_messages_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{14}
+}
+
+// Queue — загрузка очередей для операторов.
+type Queue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Depth         int32                  `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`       // выражений принято и ещё не вычислено
+	Capacity      int32                  `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"` // сколько выражений может ждать, прежде чем Calc ответит ResourceExhausted
+	Tasks         int32                  `protobuf:"varint,3,opt,name=tasks,proto3" json:"tasks,omitempty"`       // операций ждут вычислителя
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Queue) Reset() {
+	*x = Queue{}
+	mi := &file_proto_messages_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Queue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Queue) ProtoMessage() {}
+
+func (x *Queue) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Queue.ProtoReflect.Descriptor instead.
+func (*Queue) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Queue) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *Queue) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *Queue) GetTasks() int32 {
+	if x != nil {
+		return x.Tasks
+	}
+	return 0
+}
+
+// TaskRequest — запрос агента на новую задачу.
+type TaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agentId,proto3" json:"agentId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskRequest) Reset() {
+	*x = TaskRequest{}
+	mi := &file_proto_messages_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskRequest) ProtoMessage() {}
+
+func (x *TaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskRequest.ProtoReflect.Descriptor instead.
+func (*TaskRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *TaskRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+// Task — одна операция выражения, выданная агенту в аренду.
+// Результат принимается только с теми же id и lease.
+type Task struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Lease         int64                  `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	Operation     string                 `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	Arg1          string                 `protobuf:"bytes,4,opt,name=arg1,proto3" json:"arg1,omitempty"`
+	Arg2          string                 `protobuf:"bytes,5,opt,name=arg2,proto3" json:"arg2,omitempty"`
+	Mode          string                 `protobuf:"bytes,6,opt,name=mode,proto3" json:"mode,omitempty"`
+	LeaseMs       int64                  `protobuf:"varint,7,opt,name=leaseMs,proto3" json:"leaseMs,omitempty"` // сколько миллисекунд у агента есть на результат
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_proto_messages_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Task) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Task) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
+func (x *Task) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *Task) GetArg1() string {
+	if x != nil {
+		return x.Arg1
+	}
+	return ""
+}
+
+func (x *Task) GetArg2() string {
+	if x != nil {
+		return x.Arg2
+	}
+	return ""
+}
+
+func (x *Task) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Task) GetLeaseMs() int64 {
+	if x != nil {
+		return x.LeaseMs
+	}
+	return 0
+}
+
+type TaskResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Lease         int64                  `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ErrorCode     string                 `protobuf:"bytes,4,opt,name=errorCode,proto3" json:"errorCode,omitempty"` // пустой, если операция выполнена успешно
+	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	AgentId       string                 `protobuf:"bytes,6,opt,name=agentId,proto3" json:"agentId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskResult) Reset() {
+	*x = TaskResult{}
+	mi := &file_proto_messages_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskResult) ProtoMessage() {}
+
+func (x *TaskResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskResult.ProtoReflect.Descriptor instead.
+func (*TaskResult) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *TaskResult) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TaskResult) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
+func (x *TaskResult) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *TaskResult) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *TaskResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *TaskResult) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+// Pool — локальные вычислители агента.
+type Pool struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int32                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`           // сколько вычислителей работает сейчас
+	Autoscale     bool                   `protobuf:"varint,2,opt,name=autoscale,proto3" json:"autoscale,omitempty"` // меняется ли размер автоматически
+	Min           int32                  `protobuf:"varint,3,opt,name=min,proto3" json:"min,omitempty"`             // границы автоматического изменения
+	Max           int32                  `protobuf:"varint,4,opt,name=max,proto3" json:"max,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pool) Reset() {
+	*x = Pool{}
+	mi := &file_proto_messages_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pool) ProtoMessage() {}
+
+func (x *Pool) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pool.ProtoReflect.Descriptor instead.
+func (*Pool) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Pool) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Pool) GetAutoscale() bool {
+	if x != nil {
+		return x.Autoscale
+	}
+	return false
+}
+
+func (x *Pool) GetMin() int32 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *Pool) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+type PoolSize struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int32                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PoolSize) Reset() {
+	*x = PoolSize{}
+	mi := &file_proto_messages_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PoolSize) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolSize) ProtoMessage() {}
+
+func (x *PoolSize) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolSize.ProtoReflect.Descriptor instead.
+func (*PoolSize) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PoolSize) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// ExpressionEvent — изменение состояния выражения.
+type ExpressionEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`    // растёт с каждым событием
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // queued, running, progress, done, error, cancelled или reset
+	Expression    *Expression            `protobuf:"bytes,3,opt,name=expression,proto3" json:"expression,omitempty"`
+	Done          int32                  `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`   // для progress: сколько операций выполнено
+	Total         int32                  `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"` // и сколько их всего
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpressionEvent) Reset() {
+	*x = ExpressionEvent{}
+	mi := &file_proto_messages_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpressionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpressionEvent) ProtoMessage() {}
+
+func (x *ExpressionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpressionEvent.ProtoReflect.Descriptor instead.
+func (*ExpressionEvent) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ExpressionEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ExpressionEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ExpressionEvent) GetExpression() *Expression {
+	if x != nil {
+		return x.Expression
+	}
+	return nil
+}
+
+func (x *ExpressionEvent) GetDone() int32 {
+	if x != nil {
+		return x.Done
+	}
+	return 0
+}
+
+func (x *ExpressionEvent) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type WatchExpressionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        int32                  `protobuf:"varint,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchExpressionRequest) Reset() {
+	*x = WatchExpressionRequest{}
+	mi := &file_proto_messages_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchExpressionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchExpressionRequest) ProtoMessage() {}
+
+func (x *WatchExpressionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchExpressionRequest.ProtoReflect.Descriptor instead.
+func (*WatchExpressionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WatchExpressionRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WatchExpressionRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type WaitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        int32                  `protobuf:"varint,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	WaitMs        int64                  `protobuf:"varint,3,opt,name=waitMs,proto3" json:"waitMs,omitempty"` // сколько ждать завершения выражения
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WaitRequest) Reset() {
+	*x = WaitRequest{}
+	mi := &file_proto_messages_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WaitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitRequest) ProtoMessage() {}
+
+func (x *WaitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitRequest.ProtoReflect.Descriptor instead.
+func (*WaitRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WaitRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WaitRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WaitRequest) GetWaitMs() int64 {
+	if x != nil {
+		return x.WaitMs
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	After         int64                  `protobuf:"varint,2,opt,name=after,proto3" json:"after,omitempty"` // ID последнего полученного события; 0 — только новые события
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_proto_messages_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *WatchRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetAfter() int64 {
+	if x != nil {
+		return x.After
+	}
+	return 0
+}
+
+// Webhook — адрес, на который отправляются вычисленные выражения пользователя.
+type Webhook struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId int32                  `protobuf:"varint,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	Url    string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	// ключ HMAC, которым подписываются доставки пользователя; приходит только в ответе
+	// первого AddWebhook пользователя, даже если ключ уже создан доставкой на callbackUrl
+	Secret        string `protobuf:"bytes,4,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Webhook) Reset() {
+	*x = Webhook{}
+	mi := &file_proto_messages_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Webhook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Webhook) ProtoMessage() {}
+
+func (x *Webhook) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Webhook.ProtoReflect.Descriptor instead.
+func (*Webhook) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *Webhook) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Webhook) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Webhook) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Webhook) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type Webhooks struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Webhooks      []*Webhook             `protobuf:"bytes,1,rep,name=webhooks,proto3" json:"webhooks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Webhooks) Reset() {
+	*x = Webhooks{}
+	mi := &file_proto_messages_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Webhooks) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Webhooks) ProtoMessage() {}
+
+func (x *Webhooks) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Webhooks.ProtoReflect.Descriptor instead.
+func (*Webhooks) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Webhooks) GetWebhooks() []*Webhook {
+	if x != nil {
+		return x.Webhooks
+	}
+	return nil
+}
+
+type WebhookSecret struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secret        string                 `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebhookSecret) Reset() {
+	*x = WebhookSecret{}
+	mi := &file_proto_messages_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebhookSecret) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookSecret) ProtoMessage() {}
+
+func (x *WebhookSecret) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookSecret.ProtoReflect.Descriptor instead.
+func (*WebhookSecret) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *WebhookSecret) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type DeliveryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	ExpressionId  int32                  `protobuf:"varint,2,opt,name=expressionId,proto3" json:"expressionId,omitempty"` // 0 — доставки всех выражений
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryRequest) Reset() {
+	*x = DeliveryRequest{}
+	mi := &file_proto_messages_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryRequest) ProtoMessage() {}
+
+func (x *DeliveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryRequest.ProtoReflect.Descriptor instead.
+func (*DeliveryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *DeliveryRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeliveryRequest) GetExpressionId() int32 {
+	if x != nil {
+		return x.ExpressionId
+	}
+	return 0
+}
+
+// Delivery — отправка вычисленного выражения на webhook или callbackUrl.
+type Delivery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ExpressionId  int32                  `protobuf:"varint,2,opt,name=expressionId,proto3" json:"expressionId,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"` // pending, delivered или failed
+	Attempts      int32                  `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	ResponseCode  int32                  `protobuf:"varint,6,opt,name=responseCode,proto3" json:"responseCode,omitempty"`   // HTTP-код последней попытки
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`                  // ошибка последней попытки
+	NextAttemptAt int64                  `protobuf:"varint,8,opt,name=nextAttemptAt,proto3" json:"nextAttemptAt,omitempty"` // время следующей попытки в миллисекундах Unix
+	UpdatedAt     int64                  `protobuf:"varint,9,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Delivery) Reset() {
+	*x = Delivery{}
+	mi := &file_proto_messages_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Delivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delivery) ProtoMessage() {}
+
+func (x *Delivery) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delivery.ProtoReflect.Descriptor instead.
+func (*Delivery) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *Delivery) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Delivery) GetExpressionId() int32 {
+	if x != nil {
+		return x.ExpressionId
+	}
+	return 0
+}
+
+func (x *Delivery) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Delivery) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Delivery) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *Delivery) GetResponseCode() int32 {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return 0
+}
+
+func (x *Delivery) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Delivery) GetNextAttemptAt() int64 {
+	if x != nil {
+		return x.NextAttemptAt
+	}
+	return 0
+}
+
+func (x *Delivery) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type Deliveries struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deliveries    []*Delivery            `protobuf:"bytes,1,rep,name=deliveries,proto3" json:"deliveries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Deliveries) Reset() {
+	*x = Deliveries{}
+	mi := &file_proto_messages_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Deliveries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Deliveries) ProtoMessage() {}
+
+func (x *Deliveries) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Deliveries.ProtoReflect.Descriptor instead.
+func (*Deliveries) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Deliveries) GetDeliveries() []*Delivery {
+	if x != nil {
+		return x.Deliveries
+	}
+	return nil
+}
+
+var File_proto_messages_proto protoreflect.FileDescriptor
+
+const file_proto_messages_proto_rawDesc = "" +
+	"\n" +
+	"\x14proto/messages.proto\x12\x04calc\"\xcd\x01\n" +
+	"\aRequest\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\x05R\x06userId\x12\x12\n" +
+	"\x04mode\x18\x03 \x01(\tR\x04mode\x12\x1c\n" +
+	"\tprecision\x18\x04 \x01(\x05R\tprecision\x12\x1a\n" +
+	"\bdeadline\x18\x05 \x01(\x03R\bdeadline\x12\x1a\n" +
+	"\bpriority\x18\x06 \x01(\tR\bpriority\x12 \n" +
+	"\vcallbackUrl\x18\a \x01(\tR\vcallbackUrl\"\x14\n" +
+	"\x02Id\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\xd5\x03\n" +
 	"\n" +
 	"Expression\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
 	"\x06status\x18\x02 \x01(\tR\x06status\x12\x16\n" +
-	"\x06result\x18\x03 \x01(\x02R\x06result\x12\x1e\n" +
+	"\x06result\x18\x11 \x01(\x01R\x06result\x12\x1e\n" +
 	"\n" +
 	"expression\x18\x04 \x01(\tR\n" +
 	"expression\x12\x16\n" +
-	"\x06userId\x18\x05 \x01(\x05R\x06userId\"A\n" +
+	"\x06userId\x18\x05 \x01(\x05R\x06userId\x12\x12\n" +
+	"\x04mode\x18\x06 \x01(\tR\x04mode\x12\x1c\n" +
+	"\tprecision\x18\a \x01(\x05R\tprecision\x12\x14\n" +
+	"\x05value\x18\b \x01(\tR\x05value\x12\x1c\n" +
+	"\terrorCode\x18\t \x01(\tR\terrorCode\x12\"\n" +
+	"\ferrorMessage\x18\n" +
+	" \x01(\tR\ferrorMessage\x12\x1a\n" +
+	"\bdeadline\x18\v \x01(\x03R\bdeadline\x12\x1a\n" +
+	"\bposition\x18\f \x01(\x05R\bposition\x12\x1a\n" +
+	"\bpriority\x18\r \x01(\tR\bpriority\x12 \n" +
+	"\vcallbackUrl\x18\x0e \x01(\tR\vcallbackUrl\x12/\n" +
+	"\n" +
+	"references\x18\x0f \x03(\v2\x0f.calc.ReferenceR\n" +
+	"references\x12\x18\n" +
+	"\ainexact\x18\x10 \x01(\bR\ainexactJ\x04\b\x03\x10\x04\"/\n" +
+	"\tReference\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"\x99\x01\n" +
+	"\vSyntaxError\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x05R\x04line\x12\x16\n" +
+	"\x06column\x18\x02 \x01(\x05R\x06column\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\x12\x1a\n" +
+	"\bexpected\x18\x04 \x01(\tR\bexpected\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12\x12\n" +
+	"\x04code\x18\x06 \x01(\tR\x04code\"A\n" +
 	"\vExpressions\x122\n" +
-	"\vexpressions\x18\x01 \x03(\v2\x10.calc.ExpressionR\vexpressions\"8\n" +
+	"\vexpressions\x18\x01 \x03(\v2\x10.calc.ExpressionR\vexpressions\"Q\n" +
+	"\fBatchRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12)\n" +
+	"\brequests\x18\x02 \x03(\v2\r.calc.RequestR\brequests\"\x94\x01\n" +
+	"\tBatchItem\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12)\n" +
+	"\x06syntax\x18\x03 \x01(\v2\x11.calc.SyntaxErrorR\x06syntax\x12\x12\n" +
+	"\x04code\x18\x04 \x01(\tR\x04code\x12\"\n" +
+	"\fretryAfterMs\x18\x05 \x01(\x03R\fretryAfterMs\"4\n" +
+	"\vBatchResult\x12%\n" +
+	"\x05items\x18\x01 \x03(\v2\x0f.calc.BatchItemR\x05items\"8\n" +
 	"\x04User\x12\x14\n" +
 	"\x05login\x18\x01 \x01(\tR\x05login\x12\x1a\n" +
-	"\bpassword\x18\x02 \x01(\tR\bpassword2\xcb\x01\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"L\n" +
+	"\bVariable\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\"9\n" +
+	"\tVariables\x12,\n" +
+	"\tvariables\x18\x01 \x03(\v2\x0e.calc.VariableR\tvariables\"b\n" +
+	"\bFunction\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06params\x18\x03 \x03(\tR\x06params\x12\x12\n" +
+	"\x04body\x18\x04 \x01(\tR\x04body\"9\n" +
+	"\tFunctions\x12,\n" +
+	"\tfunctions\x18\x01 \x03(\v2\x0e.calc.FunctionR\tfunctions\"\a\n" +
+	"\x05Empty\"O\n" +
+	"\x05Queue\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\x05R\x05depth\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x05R\bcapacity\x12\x14\n" +
+	"\x05tasks\x18\x03 \x01(\x05R\x05tasks\"'\n" +
+	"\vTaskRequest\x12\x18\n" +
+	"\aagentId\x18\x01 \x01(\tR\aagentId\"\xa0\x01\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05lease\x18\x02 \x01(\x03R\x05lease\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x12\n" +
+	"\x04arg1\x18\x04 \x01(\tR\x04arg1\x12\x12\n" +
+	"\x04arg2\x18\x05 \x01(\tR\x04arg2\x12\x12\n" +
+	"\x04mode\x18\x06 \x01(\tR\x04mode\x12\x18\n" +
+	"\aleaseMs\x18\a \x01(\x03R\aleaseMs\"\xa4\x01\n" +
+	"\n" +
+	"TaskResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05lease\x18\x02 \x01(\x03R\x05lease\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x1c\n" +
+	"\terrorCode\x18\x04 \x01(\tR\terrorCode\x12\"\n" +
+	"\ferrorMessage\x18\x05 \x01(\tR\ferrorMessage\x12\x18\n" +
+	"\aagentId\x18\x06 \x01(\tR\aagentId\"\\\n" +
+	"\x04Pool\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x05R\x04size\x12\x1c\n" +
+	"\tautoscale\x18\x02 \x01(\bR\tautoscale\x12\x10\n" +
+	"\x03min\x18\x03 \x01(\x05R\x03min\x12\x10\n" +
+	"\x03max\x18\x04 \x01(\x05R\x03max\"\x1e\n" +
+	"\bPoolSize\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x05R\x04size\"\x91\x01\n" +
+	"\x0fExpressionEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x120\n" +
+	"\n" +
+	"expression\x18\x03 \x01(\v2\x10.calc.ExpressionR\n" +
+	"expression\x12\x12\n" +
+	"\x04done\x18\x04 \x01(\x05R\x04done\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x05R\x05total\"@\n" +
+	"\x16WatchExpressionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\x05R\x06userId\"M\n" +
+	"\vWaitRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\x05R\x06userId\x12\x16\n" +
+	"\x06waitMs\x18\x03 \x01(\x03R\x06waitMs\"<\n" +
+	"\fWatchRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x14\n" +
+	"\x05after\x18\x02 \x01(\x03R\x05after\"[\n" +
+	"\aWebhook\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\x05R\x06userId\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x16\n" +
+	"\x06secret\x18\x04 \x01(\tR\x06secret\"5\n" +
+	"\bWebhooks\x12)\n" +
+	"\bwebhooks\x18\x01 \x03(\v2\r.calc.WebhookR\bwebhooks\"'\n" +
+	"\rWebhookSecret\x12\x16\n" +
+	"\x06secret\x18\x01 \x01(\tR\x06secret\"M\n" +
+	"\x0fDeliveryRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\"\n" +
+	"\fexpressionId\x18\x02 \x01(\x05R\fexpressionId\"\x82\x02\n" +
+	"\bDelivery\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\"\n" +
+	"\fexpressionId\x18\x02 \x01(\x05R\fexpressionId\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x05R\battempts\x12\"\n" +
+	"\fresponseCode\x18\x06 \x01(\x05R\fresponseCode\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12$\n" +
+	"\rnextAttemptAt\x18\b \x01(\x03R\rnextAttemptAt\x12\x1c\n" +
+	"\tupdatedAt\x18\t \x01(\x03R\tupdatedAt\"<\n" +
+	"\n" +
+	"Deliveries\x12.\n" +
+	"\n" +
+	"deliveries\x18\x01 \x03(\v2\x0e.calc.DeliveryR\n" +
+	"deliveries2\xf9\t\n" +
 	"\vCalcService\x12\x1f\n" +
-	"\x04Calc\x12\r.calc.Request\x1a\b.calc.Id\x12-\n" +
+	"\x04Calc\x12\r.calc.Request\x1a\b.calc.Id\x122\n" +
+	"\tCalcBatch\x12\x12.calc.BatchRequest\x1a\x11.calc.BatchResult\x12-\n" +
 	"\x0eGetExpressions\x12\b.calc.Id\x1a\x11.calc.Expressions\x12+\n" +
 	"\rGetExpression\x12\b.calc.Id\x1a\x10.calc.Expression\x12\x1d\n" +
 	"\x05Login\x12\n" +
 	".calc.User\x1a\b.calc.Id\x12 \n" +
 	"\bRegister\x12\n" +
-	".calc.User\x1a\b.calc.IdB\bZ\x06.;calcb\x06proto3"
+	".calc.User\x1a\b.calc.Id\x12)\n" +
+	"\fGetVariables\x12\b.calc.Id\x1a\x0f.calc.Variables\x12-\n" +
+	"\vSetVariable\x12\x0e.calc.Variable\x1a\x0e.calc.Variable\x12-\n" +
+	"\x0eDeleteVariable\x12\x0e.calc.Variable\x1a\v.calc.Empty\x12)\n" +
+	"\fGetFunctions\x12\b.calc.Id\x1a\x0f.calc.Functions\x12-\n" +
+	"\vGetFunction\x12\x0e.calc.Function\x1a\x0e.calc.Function\x12-\n" +
+	"\vSetFunction\x12\x0e.calc.Function\x1a\x0e.calc.Function\x12-\n" +
+	"\x0eDeleteFunction\x12\x0e.calc.Function\x1a\v.calc.Empty\x12(\n" +
+	"\aGetTask\x12\x11.calc.TaskRequest\x1a\n" +
+	".calc.Task\x12-\n" +
+	"\fSubmitResult\x12\x10.calc.TaskResult\x1a\v.calc.Empty\x12$\n" +
+	"\bGetQueue\x12\v.calc.Empty\x1a\v.calc.Queue\x126\n" +
+	"\x10CancelExpression\x12\x10.calc.Expression\x1a\x10.calc.Expression\x12H\n" +
+	"\x0fWatchExpression\x12\x1c.calc.WatchExpressionRequest\x1a\x15.calc.ExpressionEvent0\x01\x125\n" +
+	"\x0eWaitExpression\x12\x11.calc.WaitRequest\x1a\x10.calc.Expression\x12?\n" +
+	"\x10WatchExpressions\x12\x12.calc.WatchRequest\x1a\x15.calc.ExpressionEvent0\x01\x12*\n" +
+	"\n" +
+	"AddWebhook\x12\r.calc.Webhook\x1a\r.calc.Webhook\x12'\n" +
+	"\vGetWebhooks\x12\b.calc.Id\x1a\x0e.calc.Webhooks\x12+\n" +
+	"\rDeleteWebhook\x12\r.calc.Webhook\x1a\v.calc.Empty\x124\n" +
+	"\x13RotateWebhookSecret\x12\b.calc.Id\x1a\x13.calc.WebhookSecret\x128\n" +
+	"\rGetDeliveries\x12\x15.calc.DeliveryRequest\x1a\x10.calc.Deliveries\x12\"\n" +
+	"\aGetPool\x12\v.calc.Empty\x1a\n" +
+	".calc.Pool\x12(\n" +
+	"\n" +
+	"ResizePool\x12\x0e.calc.PoolSize\x1a\n" +
+	".calc.PoolB\bZ\x06.;calcb\x06proto3"
 
 var (
 	file_proto_messages_proto_rawDescOnce sync.Once
@@ -336,31 +2184,110 @@ func file_proto_messages_proto_rawDescGZIP() []byte {
 	return file_proto_messages_proto_rawDescData
 }
 
-var file_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_proto_messages_proto_goTypes = []any{
-	(*Request)(nil),     // 0: calc.Request
-	(*Id)(nil),          // 1: calc.Id
-	(*Expression)(nil),  // 2: calc.Expression
-	(*Expressions)(nil), // 3: calc.Expressions
-	(*User)(nil),        // 4: calc.User
+	(*Request)(nil),                // 0: calc.Request
+	(*Id)(nil),                     // 1: calc.Id
+	(*Expression)(nil),             // 2: calc.Expression
+	(*Reference)(nil),              // 3: calc.Reference
+	(*SyntaxError)(nil),            // 4: calc.SyntaxError
+	(*Expressions)(nil),            // 5: calc.Expressions
+	(*BatchRequest)(nil),           // 6: calc.BatchRequest
+	(*BatchItem)(nil),              // 7: calc.BatchItem
+	(*BatchResult)(nil),            // 8: calc.BatchResult
+	(*User)(nil),                   // 9: calc.User
+	(*Variable)(nil),               // 10: calc.Variable
+	(*Variables)(nil),              // 11: calc.Variables
+	(*Function)(nil),               // 12: calc.Function
+	(*Functions)(nil),              // 13: calc.Functions
+	(*Empty)(nil),                  // 14: calc.Empty
+	(*Queue)(nil),                  // 15: calc.Queue
+	(*TaskRequest)(nil),            // 16: calc.TaskRequest
+	(*Task)(nil),                   // 17: calc.Task
+	(*TaskResult)(nil),             // 18: calc.TaskResult
+	(*Pool)(nil),                   // 19: calc.Pool
+	(*PoolSize)(nil),               // 20: calc.PoolSize
+	(*ExpressionEvent)(nil),        // 21: calc.ExpressionEvent
+	(*WatchExpressionRequest)(nil), // 22: calc.WatchExpressionRequest
+	(*WaitRequest)(nil),            // 23: calc.WaitRequest
+	(*WatchRequest)(nil),           // 24: calc.WatchRequest
+	(*Webhook)(nil),                // 25: calc.Webhook
+	(*Webhooks)(nil),               // 26: calc.Webhooks
+	(*WebhookSecret)(nil),          // 27: calc.WebhookSecret
+	(*DeliveryRequest)(nil),        // 28: calc.DeliveryRequest
+	(*Delivery)(nil),               // 29: calc.Delivery
+	(*Deliveries)(nil),             // 30: calc.Deliveries
 }
 var file_proto_messages_proto_depIdxs = []int32{
-	2, // 0: calc.Expressions.expressions:type_name -> calc.Expression
-	0, // 1: calc.CalcService.Calc:input_type -> calc.Request
-	1, // 2: calc.CalcService.GetExpressions:input_type -> calc.Id
-	1, // 3: calc.CalcService.GetExpression:input_type -> calc.Id
-	4, // 4: calc.CalcService.Login:input_type -> calc.User
-	4, // 5: calc.CalcService.Register:input_type -> calc.User
-	1, // 6: calc.CalcService.Calc:output_type -> calc.Id
-	3, // 7: calc.CalcService.GetExpressions:output_type -> calc.Expressions
-	2, // 8: calc.CalcService.GetExpression:output_type -> calc.Expression
-	1, // 9: calc.CalcService.Login:output_type -> calc.Id
-	1, // 10: calc.CalcService.Register:output_type -> calc.Id
-	6, // [6:11] is the sub-list for method output_type
-	1, // [1:6] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3,  // 0: calc.Expression.references:type_name -> calc.Reference
+	2,  // 1: calc.Expressions.expressions:type_name -> calc.Expression
+	0,  // 2: calc.BatchRequest.requests:type_name -> calc.Request
+	4,  // 3: calc.BatchItem.syntax:type_name -> calc.SyntaxError
+	7,  // 4: calc.BatchResult.items:type_name -> calc.BatchItem
+	10, // 5: calc.Variables.variables:type_name -> calc.Variable
+	12, // 6: calc.Functions.functions:type_name -> calc.Function
+	2,  // 7: calc.ExpressionEvent.expression:type_name -> calc.Expression
+	25, // 8: calc.Webhooks.webhooks:type_name -> calc.Webhook
+	29, // 9: calc.Deliveries.deliveries:type_name -> calc.Delivery
+	0,  // 10: calc.CalcService.Calc:input_type -> calc.Request
+	6,  // 11: calc.CalcService.CalcBatch:input_type -> calc.BatchRequest
+	1,  // 12: calc.CalcService.GetExpressions:input_type -> calc.Id
+	1,  // 13: calc.CalcService.GetExpression:input_type -> calc.Id
+	9,  // 14: calc.CalcService.Login:input_type -> calc.User
+	9,  // 15: calc.CalcService.Register:input_type -> calc.User
+	1,  // 16: calc.CalcService.GetVariables:input_type -> calc.Id
+	10, // 17: calc.CalcService.SetVariable:input_type -> calc.Variable
+	10, // 18: calc.CalcService.DeleteVariable:input_type -> calc.Variable
+	1,  // 19: calc.CalcService.GetFunctions:input_type -> calc.Id
+	12, // 20: calc.CalcService.GetFunction:input_type -> calc.Function
+	12, // 21: calc.CalcService.SetFunction:input_type -> calc.Function
+	12, // 22: calc.CalcService.DeleteFunction:input_type -> calc.Function
+	16, // 23: calc.CalcService.GetTask:input_type -> calc.TaskRequest
+	18, // 24: calc.CalcService.SubmitResult:input_type -> calc.TaskResult
+	14, // 25: calc.CalcService.GetQueue:input_type -> calc.Empty
+	2,  // 26: calc.CalcService.CancelExpression:input_type -> calc.Expression
+	22, // 27: calc.CalcService.WatchExpression:input_type -> calc.WatchExpressionRequest
+	23, // 28: calc.CalcService.WaitExpression:input_type -> calc.WaitRequest
+	24, // 29: calc.CalcService.WatchExpressions:input_type -> calc.WatchRequest
+	25, // 30: calc.CalcService.AddWebhook:input_type -> calc.Webhook
+	1,  // 31: calc.CalcService.GetWebhooks:input_type -> calc.Id
+	25, // 32: calc.CalcService.DeleteWebhook:input_type -> calc.Webhook
+	1,  // 33: calc.CalcService.RotateWebhookSecret:input_type -> calc.Id
+	28, // 34: calc.CalcService.GetDeliveries:input_type -> calc.DeliveryRequest
+	14, // 35: calc.CalcService.GetPool:input_type -> calc.Empty
+	20, // 36: calc.CalcService.ResizePool:input_type -> calc.PoolSize
+	1,  // 37: calc.CalcService.Calc:output_type -> calc.Id
+	8,  // 38: calc.CalcService.CalcBatch:output_type -> calc.BatchResult
+	5,  // 39: calc.CalcService.GetExpressions:output_type -> calc.Expressions
+	2,  // 40: calc.CalcService.GetExpression:output_type -> calc.Expression
+	1,  // 41: calc.CalcService.Login:output_type -> calc.Id
+	1,  // 42: calc.CalcService.Register:output_type -> calc.Id
+	11, // 43: calc.CalcService.GetVariables:output_type -> calc.Variables
+	10, // 44: calc.CalcService.SetVariable:output_type -> calc.Variable
+	14, // 45: calc.CalcService.DeleteVariable:output_type -> calc.Empty
+	13, // 46: calc.CalcService.GetFunctions:output_type -> calc.Functions
+	12, // 47: calc.CalcService.GetFunction:output_type -> calc.Function
+	12, // 48: calc.CalcService.SetFunction:output_type -> calc.Function
+	14, // 49: calc.CalcService.DeleteFunction:output_type -> calc.Empty
+	17, // 50: calc.CalcService.GetTask:output_type -> calc.Task
+	14, // 51: calc.CalcService.SubmitResult:output_type -> calc.Empty
+	15, // 52: calc.CalcService.GetQueue:output_type -> calc.Queue
+	2,  // 53: calc.CalcService.CancelExpression:output_type -> calc.Expression
+	21, // 54: calc.CalcService.WatchExpression:output_type -> calc.ExpressionEvent
+	2,  // 55: calc.CalcService.WaitExpression:output_type -> calc.Expression
+	21, // 56: calc.CalcService.WatchExpressions:output_type -> calc.ExpressionEvent
+	25, // 57: calc.CalcService.AddWebhook:output_type -> calc.Webhook
+	26, // 58: calc.CalcService.GetWebhooks:output_type -> calc.Webhooks
+	14, // 59: calc.CalcService.DeleteWebhook:output_type -> calc.Empty
+	27, // 60: calc.CalcService.RotateWebhookSecret:output_type -> calc.WebhookSecret
+	30, // 61: calc.CalcService.GetDeliveries:output_type -> calc.Deliveries
+	19, // 62: calc.CalcService.GetPool:output_type -> calc.Pool
+	19, // 63: calc.CalcService.ResizePool:output_type -> calc.Pool
+	37, // [37:64] is the sub-list for method output_type
+	10, // [10:37] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_proto_messages_proto_init() }
@@ -374,7 +2301,7 @@ func file_proto_messages_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_messages_proto_rawDesc), len(file_proto_messages_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,11 +19,33 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CalcService_Calc_FullMethodName           = "/calc.CalcService/Calc"
-	CalcService_GetExpressions_FullMethodName = "/calc.CalcService/GetExpressions"
-	CalcService_GetExpression_FullMethodName  = "/calc.CalcService/GetExpression"
-	CalcService_Login_FullMethodName          = "/calc.CalcService/Login"
-	CalcService_Register_FullMethodName       = "/calc.CalcService/Register"
+	CalcService_Calc_FullMethodName                = "/calc.CalcService/Calc"
+	CalcService_CalcBatch_FullMethodName           = "/calc.CalcService/CalcBatch"
+	CalcService_GetExpressions_FullMethodName      = "/calc.CalcService/GetExpressions"
+	CalcService_GetExpression_FullMethodName       = "/calc.CalcService/GetExpression"
+	CalcService_Login_FullMethodName               = "/calc.CalcService/Login"
+	CalcService_Register_FullMethodName            = "/calc.CalcService/Register"
+	CalcService_GetVariables_FullMethodName        = "/calc.CalcService/GetVariables"
+	CalcService_SetVariable_FullMethodName         = "/calc.CalcService/SetVariable"
+	CalcService_DeleteVariable_FullMethodName      = "/calc.CalcService/DeleteVariable"
+	CalcService_GetFunctions_FullMethodName        = "/calc.CalcService/GetFunctions"
+	CalcService_GetFunction_FullMethodName         = "/calc.CalcService/GetFunction"
+	CalcService_SetFunction_FullMethodName         = "/calc.CalcService/SetFunction"
+	CalcService_DeleteFunction_FullMethodName      = "/calc.CalcService/DeleteFunction"
+	CalcService_GetTask_FullMethodName             = "/calc.CalcService/GetTask"
+	CalcService_SubmitResult_FullMethodName        = "/calc.CalcService/SubmitResult"
+	CalcService_GetQueue_FullMethodName            = "/calc.CalcService/GetQueue"
+	CalcService_CancelExpression_FullMethodName    = "/calc.CalcService/CancelExpression"
+	CalcService_WatchExpression_FullMethodName     = "/calc.CalcService/WatchExpression"
+	CalcService_WaitExpression_FullMethodName      = "/calc.CalcService/WaitExpression"
+	CalcService_WatchExpressions_FullMethodName    = "/calc.CalcService/WatchExpressions"
+	CalcService_AddWebhook_FullMethodName          = "/calc.CalcService/AddWebhook"
+	CalcService_GetWebhooks_FullMethodName         = "/calc.CalcService/GetWebhooks"
+	CalcService_DeleteWebhook_FullMethodName       = "/calc.CalcService/DeleteWebhook"
+	CalcService_RotateWebhookSecret_FullMethodName = "/calc.CalcService/RotateWebhookSecret"
+	CalcService_GetDeliveries_FullMethodName       = "/calc.CalcService/GetDeliveries"
+	CalcService_GetPool_FullMethodName             = "/calc.CalcService/GetPool"
+	CalcService_ResizePool_FullMethodName          = "/calc.CalcService/ResizePool"
 )
 
 // CalcServiceClient is the client API for CalcService service.
@@ -34,10 +56,48 @@ const (
 type CalcServiceClient interface {
 	// методы, которые можно будет реализовать и использовать
 	Calc(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Id, error)
+	// CalcBatch принимает много выражений за один вызов; ошибка одного не мешает остальным
+	CalcBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResult, error)
 	GetExpressions(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Expressions, error)
 	GetExpression(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Expression, error)
 	Login(ctx context.Context, in *User, opts ...grpc.CallOption) (*Id, error)
 	Register(ctx context.Context, in *User, opts ...grpc.CallOption) (*Id, error)
+	GetVariables(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Variables, error)
+	SetVariable(ctx context.Context, in *Variable, opts ...grpc.CallOption) (*Variable, error)
+	DeleteVariable(ctx context.Context, in *Variable, opts ...grpc.CallOption) (*Empty, error)
+	GetFunctions(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Functions, error)
+	GetFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Function, error)
+	SetFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Function, error)
+	DeleteFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Empty, error)
+	// GetTask ждёт задачу для агента; если задач нет, возвращает NotFound
+	GetTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Task, error)
+	SubmitResult(ctx context.Context, in *TaskResult, opts ...grpc.CallOption) (*Empty, error)
+	GetQueue(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Queue, error)
+	// CancelExpression отменяет выражение пользователя userId и возвращает его со статусом Cancelled
+	CancelExpression(ctx context.Context, in *Expression, opts ...grpc.CallOption) (*Expression, error)
+	// WatchExpression отправляет текущее состояние выражения пользователя userId и затем каждое
+	// его изменение; поток закрывается после события done, error или cancelled
+	WatchExpression(ctx context.Context, in *WatchExpressionRequest, opts ...grpc.CallOption) (CalcService_WatchExpressionClient, error)
+	// WaitExpression ждёт, пока выражение пользователя userId завершится, но не дольше waitMs,
+	// и возвращает его состояние
+	WaitExpression(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*Expression, error)
+	// WatchExpressions отправляет изменения всех выражений пользователя, начиная с пропущенных
+	// после after; если они уже не сохранились, первым приходит событие reset
+	WatchExpressions(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CalcService_WatchExpressionsClient, error)
+	// AddWebhook регистрирует адрес для доставки вычисленных выражений; повторная регистрация
+	// того же адреса возвращает существующий webhook
+	AddWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error)
+	GetWebhooks(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Webhooks, error)
+	DeleteWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Empty, error)
+	// RotateWebhookSecret создаёт пользователю новый ключ подписи и возвращает его;
+	// больше ключ нигде не показывается
+	RotateWebhookSecret(ctx context.Context, in *Id, opts ...grpc.CallOption) (*WebhookSecret, error)
+	// GetDeliveries возвращает последние доставки пользователя, новые первыми
+	GetDeliveries(ctx context.Context, in *DeliveryRequest, opts ...grpc.CallOption) (*Deliveries, error)
+	GetPool(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Pool, error)
+	// ResizePool меняет число локальных вычислителей; при автоматическом изменении
+	// размер потом продолжает меняться от заданного
+	ResizePool(ctx context.Context, in *PoolSize, opts ...grpc.CallOption) (*Pool, error)
 }
 
 type calcServiceClient struct {
@@ -58,6 +118,16 @@ func (c *calcServiceClient) Calc(ctx context.Context, in *Request, opts ...grpc.
 	return out, nil
 }
 
+func (c *calcServiceClient) CalcBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResult)
+	err := c.cc.Invoke(ctx, CalcService_CalcBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *calcServiceClient) GetExpressions(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Expressions, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Expressions)
@@ -98,6 +168,262 @@ func (c *calcServiceClient) Register(ctx context.Context, in *User, opts ...grpc
 	return out, nil
 }
 
+func (c *calcServiceClient) GetVariables(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Variables, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Variables)
+	err := c.cc.Invoke(ctx, CalcService_GetVariables_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) SetVariable(ctx context.Context, in *Variable, opts ...grpc.CallOption) (*Variable, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Variable)
+	err := c.cc.Invoke(ctx, CalcService_SetVariable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) DeleteVariable(ctx context.Context, in *Variable, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CalcService_DeleteVariable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetFunctions(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Functions, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Functions)
+	err := c.cc.Invoke(ctx, CalcService_GetFunctions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Function, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Function)
+	err := c.cc.Invoke(ctx, CalcService_GetFunction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) SetFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Function, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Function)
+	err := c.cc.Invoke(ctx, CalcService_SetFunction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) DeleteFunction(ctx context.Context, in *Function, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CalcService_DeleteFunction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Task)
+	err := c.cc.Invoke(ctx, CalcService_GetTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) SubmitResult(ctx context.Context, in *TaskResult, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CalcService_SubmitResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetQueue(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Queue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Queue)
+	err := c.cc.Invoke(ctx, CalcService_GetQueue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) CancelExpression(ctx context.Context, in *Expression, opts ...grpc.CallOption) (*Expression, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Expression)
+	err := c.cc.Invoke(ctx, CalcService_CancelExpression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) WatchExpression(ctx context.Context, in *WatchExpressionRequest, opts ...grpc.CallOption) (CalcService_WatchExpressionClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalcService_ServiceDesc.Streams[0], CalcService_WatchExpression_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calcServiceWatchExpressionClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CalcService_WatchExpressionClient interface {
+	Recv() (*ExpressionEvent, error)
+	grpc.ClientStream
+}
+
+type calcServiceWatchExpressionClient struct {
+	grpc.ClientStream
+}
+
+func (x *calcServiceWatchExpressionClient) Recv() (*ExpressionEvent, error) {
+	m := new(ExpressionEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *calcServiceClient) WaitExpression(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*Expression, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Expression)
+	err := c.cc.Invoke(ctx, CalcService_WaitExpression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) WatchExpressions(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CalcService_WatchExpressionsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalcService_ServiceDesc.Streams[1], CalcService_WatchExpressions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calcServiceWatchExpressionsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CalcService_WatchExpressionsClient interface {
+	Recv() (*ExpressionEvent, error)
+	grpc.ClientStream
+}
+
+type calcServiceWatchExpressionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *calcServiceWatchExpressionsClient) Recv() (*ExpressionEvent, error) {
+	m := new(ExpressionEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *calcServiceClient) AddWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Webhook)
+	err := c.cc.Invoke(ctx, CalcService_AddWebhook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetWebhooks(ctx context.Context, in *Id, opts ...grpc.CallOption) (*Webhooks, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Webhooks)
+	err := c.cc.Invoke(ctx, CalcService_GetWebhooks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) DeleteWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CalcService_DeleteWebhook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) RotateWebhookSecret(ctx context.Context, in *Id, opts ...grpc.CallOption) (*WebhookSecret, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WebhookSecret)
+	err := c.cc.Invoke(ctx, CalcService_RotateWebhookSecret_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetDeliveries(ctx context.Context, in *DeliveryRequest, opts ...grpc.CallOption) (*Deliveries, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Deliveries)
+	err := c.cc.Invoke(ctx, CalcService_GetDeliveries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) GetPool(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Pool, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pool)
+	err := c.cc.Invoke(ctx, CalcService_GetPool_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) ResizePool(ctx context.Context, in *PoolSize, opts ...grpc.CallOption) (*Pool, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pool)
+	err := c.cc.Invoke(ctx, CalcService_ResizePool_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalcServiceServer is the server API for CalcService service.
 // All implementations must embed UnimplementedCalcServiceServer
 // for forward compatibility.
@@ -106,10 +432,48 @@ func (c *calcServiceClient) Register(ctx context.Context, in *User, opts ...grpc
 type CalcServiceServer interface {
 	// методы, которые можно будет реализовать и использовать
 	Calc(context.Context, *Request) (*Id, error)
+	// CalcBatch принимает много выражений за один вызов; ошибка одного не мешает остальным
+	CalcBatch(context.Context, *BatchRequest) (*BatchResult, error)
 	GetExpressions(context.Context, *Id) (*Expressions, error)
 	GetExpression(context.Context, *Id) (*Expression, error)
 	Login(context.Context, *User) (*Id, error)
 	Register(context.Context, *User) (*Id, error)
+	GetVariables(context.Context, *Id) (*Variables, error)
+	SetVariable(context.Context, *Variable) (*Variable, error)
+	DeleteVariable(context.Context, *Variable) (*Empty, error)
+	GetFunctions(context.Context, *Id) (*Functions, error)
+	GetFunction(context.Context, *Function) (*Function, error)
+	SetFunction(context.Context, *Function) (*Function, error)
+	DeleteFunction(context.Context, *Function) (*Empty, error)
+	// GetTask ждёт задачу для агента; если задач нет, возвращает NotFound
+	GetTask(context.Context, *TaskRequest) (*Task, error)
+	SubmitResult(context.Context, *TaskResult) (*Empty, error)
+	GetQueue(context.Context, *Empty) (*Queue, error)
+	// CancelExpression отменяет выражение пользователя userId и возвращает его со статусом Cancelled
+	CancelExpression(context.Context, *Expression) (*Expression, error)
+	// WatchExpression отправляет текущее состояние выражения пользователя userId и затем каждое
+	// его изменение; поток закрывается после события done, error или cancelled
+	WatchExpression(*WatchExpressionRequest, CalcService_WatchExpressionServer) error
+	// WaitExpression ждёт, пока выражение пользователя userId завершится, но не дольше waitMs,
+	// и возвращает его состояние
+	WaitExpression(context.Context, *WaitRequest) (*Expression, error)
+	// WatchExpressions отправляет изменения всех выражений пользователя, начиная с пропущенных
+	// после after; если они уже не сохранились, первым приходит событие reset
+	WatchExpressions(*WatchRequest, CalcService_WatchExpressionsServer) error
+	// AddWebhook регистрирует адрес для доставки вычисленных выражений; повторная регистрация
+	// того же адреса возвращает существующий webhook
+	AddWebhook(context.Context, *Webhook) (*Webhook, error)
+	GetWebhooks(context.Context, *Id) (*Webhooks, error)
+	DeleteWebhook(context.Context, *Webhook) (*Empty, error)
+	// RotateWebhookSecret создаёт пользователю новый ключ подписи и возвращает его;
+	// больше ключ нигде не показывается
+	RotateWebhookSecret(context.Context, *Id) (*WebhookSecret, error)
+	// GetDeliveries возвращает последние доставки пользователя, новые первыми
+	GetDeliveries(context.Context, *DeliveryRequest) (*Deliveries, error)
+	GetPool(context.Context, *Empty) (*Pool, error)
+	// ResizePool меняет число локальных вычислителей; при автоматическом изменении
+	// размер потом продолжает меняться от заданного
+	ResizePool(context.Context, *PoolSize) (*Pool, error)
 	mustEmbedUnimplementedCalcServiceServer()
 }
 
@@ -123,6 +487,9 @@ type UnimplementedCalcServiceServer struct{}
 func (UnimplementedCalcServiceServer) Calc(context.Context, *Request) (*Id, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Calc not implemented")
 }
+func (UnimplementedCalcServiceServer) CalcBatch(context.Context, *BatchRequest) (*BatchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalcBatch not implemented")
+}
 func (UnimplementedCalcServiceServer) GetExpressions(context.Context, *Id) (*Expressions, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetExpressions not implemented")
 }
@@ -135,6 +502,69 @@ func (UnimplementedCalcServiceServer) Login(context.Context, *User) (*Id, error)
 func (UnimplementedCalcServiceServer) Register(context.Context, *User) (*Id, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
 }
+func (UnimplementedCalcServiceServer) GetVariables(context.Context, *Id) (*Variables, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVariables not implemented")
+}
+func (UnimplementedCalcServiceServer) SetVariable(context.Context, *Variable) (*Variable, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVariable not implemented")
+}
+func (UnimplementedCalcServiceServer) DeleteVariable(context.Context, *Variable) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVariable not implemented")
+}
+func (UnimplementedCalcServiceServer) GetFunctions(context.Context, *Id) (*Functions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFunctions not implemented")
+}
+func (UnimplementedCalcServiceServer) GetFunction(context.Context, *Function) (*Function, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFunction not implemented")
+}
+func (UnimplementedCalcServiceServer) SetFunction(context.Context, *Function) (*Function, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFunction not implemented")
+}
+func (UnimplementedCalcServiceServer) DeleteFunction(context.Context, *Function) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteFunction not implemented")
+}
+func (UnimplementedCalcServiceServer) GetTask(context.Context, *TaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedCalcServiceServer) SubmitResult(context.Context, *TaskResult) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitResult not implemented")
+}
+func (UnimplementedCalcServiceServer) GetQueue(context.Context, *Empty) (*Queue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQueue not implemented")
+}
+func (UnimplementedCalcServiceServer) CancelExpression(context.Context, *Expression) (*Expression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelExpression not implemented")
+}
+func (UnimplementedCalcServiceServer) WatchExpression(*WatchExpressionRequest, CalcService_WatchExpressionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchExpression not implemented")
+}
+func (UnimplementedCalcServiceServer) WaitExpression(context.Context, *WaitRequest) (*Expression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitExpression not implemented")
+}
+func (UnimplementedCalcServiceServer) WatchExpressions(*WatchRequest, CalcService_WatchExpressionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchExpressions not implemented")
+}
+func (UnimplementedCalcServiceServer) AddWebhook(context.Context, *Webhook) (*Webhook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddWebhook not implemented")
+}
+func (UnimplementedCalcServiceServer) GetWebhooks(context.Context, *Id) (*Webhooks, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhooks not implemented")
+}
+func (UnimplementedCalcServiceServer) DeleteWebhook(context.Context, *Webhook) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedCalcServiceServer) RotateWebhookSecret(context.Context, *Id) (*WebhookSecret, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateWebhookSecret not implemented")
+}
+func (UnimplementedCalcServiceServer) GetDeliveries(context.Context, *DeliveryRequest) (*Deliveries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeliveries not implemented")
+}
+func (UnimplementedCalcServiceServer) GetPool(context.Context, *Empty) (*Pool, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPool not implemented")
+}
+func (UnimplementedCalcServiceServer) ResizePool(context.Context, *PoolSize) (*Pool, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResizePool not implemented")
+}
 func (UnimplementedCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {}
 func (UnimplementedCalcServiceServer) testEmbeddedByValue()                     {}
 
@@ -174,6 +604,24 @@ func _CalcService_Calc_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CalcService_CalcBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).CalcBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_CalcBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).CalcBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CalcService_GetExpressions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Id)
 	if err := dec(in); err != nil {
@@ -246,34 +694,509 @@ func _CalcService_Register_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
-// CalcService_ServiceDesc is the grpc.ServiceDesc for CalcService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var CalcService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "calc.CalcService",
-	HandlerType: (*CalcServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Calc",
-			Handler:    _CalcService_Calc_Handler,
-		},
-		{
-			MethodName: "GetExpressions",
-			Handler:    _CalcService_GetExpressions_Handler,
-		},
-		{
-			MethodName: "GetExpression",
-			Handler:    _CalcService_GetExpression_Handler,
-		},
-		{
-			MethodName: "Login",
-			Handler:    _CalcService_Login_Handler,
-		},
-		{
-			MethodName: "Register",
-			Handler:    _CalcService_Register_Handler,
+func _CalcService_GetVariables_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetVariables(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetVariables_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetVariables(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_SetVariable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Variable)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).SetVariable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_SetVariable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).SetVariable(ctx, req.(*Variable))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_DeleteVariable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Variable)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).DeleteVariable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_DeleteVariable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).DeleteVariable(ctx, req.(*Variable))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetFunctions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetFunctions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetFunctions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetFunctions(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetFunction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Function)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetFunction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetFunction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetFunction(ctx, req.(*Function))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_SetFunction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Function)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).SetFunction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_SetFunction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).SetFunction(ctx, req.(*Function))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_DeleteFunction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Function)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).DeleteFunction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_DeleteFunction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).DeleteFunction(ctx, req.(*Function))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetTask(ctx, req.(*TaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_SubmitResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).SubmitResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_SubmitResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).SubmitResult(ctx, req.(*TaskResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetQueue(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_CancelExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Expression)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).CancelExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_CancelExpression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).CancelExpression(ctx, req.(*Expression))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_WatchExpression_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchExpressionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CalcServiceServer).WatchExpression(m, &calcServiceWatchExpressionServer{ServerStream: stream})
+}
+
+type CalcService_WatchExpressionServer interface {
+	Send(*ExpressionEvent) error
+	grpc.ServerStream
+}
+
+type calcServiceWatchExpressionServer struct {
+	grpc.ServerStream
+}
+
+func (x *calcServiceWatchExpressionServer) Send(m *ExpressionEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CalcService_WaitExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).WaitExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_WaitExpression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).WaitExpression(ctx, req.(*WaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_WatchExpressions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CalcServiceServer).WatchExpressions(m, &calcServiceWatchExpressionsServer{ServerStream: stream})
+}
+
+type CalcService_WatchExpressionsServer interface {
+	Send(*ExpressionEvent) error
+	grpc.ServerStream
+}
+
+type calcServiceWatchExpressionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *calcServiceWatchExpressionsServer) Send(m *ExpressionEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CalcService_AddWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).AddWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_AddWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).AddWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetWebhooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetWebhooks(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_DeleteWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).DeleteWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_RotateWebhookSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).RotateWebhookSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_RotateWebhookSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).RotateWebhookSecret(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetDeliveries(ctx, req.(*DeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_GetPool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetPool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetPool_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetPool(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_ResizePool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PoolSize)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).ResizePool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_ResizePool_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).ResizePool(ctx, req.(*PoolSize))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CalcService_ServiceDesc is the grpc.ServiceDesc for CalcService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CalcService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calc.CalcService",
+	HandlerType: (*CalcServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Calc",
+			Handler:    _CalcService_Calc_Handler,
+		},
+		{
+			MethodName: "CalcBatch",
+			Handler:    _CalcService_CalcBatch_Handler,
+		},
+		{
+			MethodName: "GetExpressions",
+			Handler:    _CalcService_GetExpressions_Handler,
+		},
+		{
+			MethodName: "GetExpression",
+			Handler:    _CalcService_GetExpression_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _CalcService_Login_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _CalcService_Register_Handler,
+		},
+		{
+			MethodName: "GetVariables",
+			Handler:    _CalcService_GetVariables_Handler,
+		},
+		{
+			MethodName: "SetVariable",
+			Handler:    _CalcService_SetVariable_Handler,
+		},
+		{
+			MethodName: "DeleteVariable",
+			Handler:    _CalcService_DeleteVariable_Handler,
+		},
+		{
+			MethodName: "GetFunctions",
+			Handler:    _CalcService_GetFunctions_Handler,
+		},
+		{
+			MethodName: "GetFunction",
+			Handler:    _CalcService_GetFunction_Handler,
+		},
+		{
+			MethodName: "SetFunction",
+			Handler:    _CalcService_SetFunction_Handler,
+		},
+		{
+			MethodName: "DeleteFunction",
+			Handler:    _CalcService_DeleteFunction_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _CalcService_GetTask_Handler,
+		},
+		{
+			MethodName: "SubmitResult",
+			Handler:    _CalcService_SubmitResult_Handler,
+		},
+		{
+			MethodName: "GetQueue",
+			Handler:    _CalcService_GetQueue_Handler,
+		},
+		{
+			MethodName: "CancelExpression",
+			Handler:    _CalcService_CancelExpression_Handler,
+		},
+		{
+			MethodName: "WaitExpression",
+			Handler:    _CalcService_WaitExpression_Handler,
+		},
+		{
+			MethodName: "AddWebhook",
+			Handler:    _CalcService_AddWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhooks",
+			Handler:    _CalcService_GetWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _CalcService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "RotateWebhookSecret",
+			Handler:    _CalcService_RotateWebhookSecret_Handler,
+		},
+		{
+			MethodName: "GetDeliveries",
+			Handler:    _CalcService_GetDeliveries_Handler,
+		},
+		{
+			MethodName: "GetPool",
+			Handler:    _CalcService_GetPool_Handler,
+		},
+		{
+			MethodName: "ResizePool",
+			Handler:    _CalcService_ResizePool_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchExpression",
+			Handler:       _CalcService_WatchExpression_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchExpressions",
+			Handler:       _CalcService_WatchExpressions_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/messages.proto",
 }